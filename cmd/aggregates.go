@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"github.com/huyhvq/eurofxref/pkg/repository"
+	"github.com/spf13/cobra"
+	"log"
+)
+
+var rebuildAggregatesCmd = &cobra.Command{
+	Use:   "rebuild-aggregates",
+	Short: "Recompute the monthly rate aggregates",
+	Long:  `Recompute the monthly rate aggregates used by the analyze and period endpoints from the rates table.`,
+	RunE:  rebuildAggregatesExecute,
+}
+
+func init() {
+	rootCmd.AddCommand(rebuildAggregatesCmd)
+}
+
+func rebuildAggregatesExecute(cmd *cobra.Command, args []string) error {
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := repository.NewRate(db.DB()).RebuildAggregates(); err != nil {
+		return err
+	}
+	log.Println("rate aggregates rebuilt")
+	return nil
+}
@@ -5,9 +5,8 @@ import (
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/mysql"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
-	"github.com/huyhvq/eurofxref/pkg/database"
+	"github.com/huyhvq/eurofxref/pkg/repository"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 	"log"
 )
 
@@ -23,14 +22,7 @@ func init() {
 }
 
 func migrateExecute(cmd *cobra.Command, args []string) {
-	db, err := database.NewDB(database.MysqlCfg{
-		Username: viper.GetString("db_user"),
-		Password: viper.GetString("db_pass"),
-		Host:     viper.GetString("db_host"),
-		Port:     viper.GetString("db_port"),
-		Name:     viper.GetString("db_name"),
-		Driver:   viper.GetString("db_driver"),
-	})
+	db, err := openDB()
 	if err != nil {
 		panic(err)
 	}
@@ -48,11 +40,20 @@ func migrateExecute(cmd *cobra.Command, args []string) {
 		return
 	}
 
-	if err := m.Up(); err != nil {
+	if err := migrateUp(m, db.DB()); err != nil {
 		log.Println(err)
 	}
 }
 
+// migrateUp applies the pending migrations, then fills the monthly aggregates
+// when their table is still empty.
+func migrateUp(m *migrate.Migrate, db *sql.DB) error {
+	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
+		return err
+	}
+	return repository.NewRate(db).FillAggregates()
+}
+
 func NewMigrate(db *sql.DB) (*migrate.Migrate, error) {
 	driver, _ := mysql.WithInstance(db, &mysql.Config{})
 	m, err := migrate.NewWithDatabaseInstance(
@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"github.com/huyhvq/eurofxref/pkg/override"
+	"github.com/huyhvq/eurofxref/pkg/repository"
+	"github.com/spf13/cobra"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+var (
+	overrideCurrency string
+	overrideStart    string
+	overrideEnd      string
+	overrideRate     float64
+	overrideReason   string
+	overrideBy       string
+)
+
+var overrideCmd = &cobra.Command{
+	Use:   "override",
+	Short: "Manage manual rate overrides",
+	Long: `Add, list and revoke manual rate overrides. An active override replaces the
+provider rate of its currency for every date in its range.`,
+}
+
+var overrideAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Add an override",
+	RunE:  overrideAddExecute,
+}
+
+var overrideListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List overrides, newest first",
+	RunE:  overrideListExecute,
+}
+
+var overrideRevokeCmd = &cobra.Command{
+	Use:   "revoke <id>",
+	Short: "Revoke an override",
+	Args:  cobra.ExactArgs(1),
+	RunE:  overrideRevokeExecute,
+}
+
+func init() {
+	overrideAddCmd.Flags().StringVar(&overrideCurrency, "currency", "", "currency to override")
+	overrideAddCmd.Flags().StringVar(&overrideStart, "start", "", "first date of the override")
+	overrideAddCmd.Flags().StringVar(&overrideEnd, "end", "", "last date of the override (default start)")
+	overrideAddCmd.Flags().Float64Var(&overrideRate, "rate", 0, "units of currency per one EUR")
+	overrideAddCmd.Flags().StringVar(&overrideReason, "reason", "", "why the provider rate is replaced")
+	overrideAddCmd.Flags().StringVar(&overrideBy, "by", os.Getenv("USER"), "who adds the override")
+	overrideRevokeCmd.Flags().StringVar(&overrideBy, "by", os.Getenv("USER"), "who revokes the override")
+	overrideCmd.AddCommand(overrideAddCmd)
+	overrideCmd.AddCommand(overrideListCmd)
+	overrideCmd.AddCommand(overrideRevokeCmd)
+	rootCmd.AddCommand(overrideCmd)
+}
+
+func overrideAddExecute(cmd *cobra.Command, args []string) error {
+	o := model.RateOverride{
+		Currency:  overrideCurrency,
+		Start:     overrideStart,
+		End:       overrideEnd,
+		Rate:      overrideRate,
+		Reason:    overrideReason,
+		CreatedBy: overrideBy,
+		CreatedAt: time.Now().UTC(),
+	}
+	if o.End == "" {
+		o.End = o.Start
+	}
+	if err := override.Normalize(&o); err != nil {
+		return err
+	}
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if o.ID, err = repository.NewOverride(db.DB()).Insert(o); err != nil {
+		return err
+	}
+	printOverrides([]model.RateOverride{o})
+	return nil
+}
+
+func overrideListExecute(cmd *cobra.Command, args []string) error {
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	overrides, err := repository.NewOverride(db.DB()).GetAll()
+	if err != nil {
+		return err
+	}
+	printOverrides(overrides)
+	return nil
+}
+
+func overrideRevokeExecute(cmd *cobra.Command, args []string) error {
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return err
+	}
+	if overrideBy == "" {
+		return fmt.Errorf("%w: --by is required", override.ErrInvalidOverride)
+	}
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	repo := repository.NewOverride(db.DB())
+	if err := repo.Revoke(id, overrideBy, time.Now().UTC()); err != nil {
+		return err
+	}
+	o, err := repo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	printOverrides([]model.RateOverride{o})
+	return nil
+}
+
+func printOverrides(overrides []model.RateOverride) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tCURRENCY\tRANGE\tRATE\tCREATED\tBY\tREVOKED\tREASON")
+	for _, o := range overrides {
+		revoked := ""
+		if !o.RevokedAt.IsZero() {
+			revoked = o.RevokedAt.Format("2006-01-02 15:04:05") + " " + o.RevokedBy
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s..%s\t%g\t%s\t%s\t%s\t%s\n",
+			o.ID, o.Currency, o.Start, o.End, o.Rate, o.CreatedAt.Format("2006-01-02 15:04:05"),
+			o.CreatedBy, revoked, o.Reason)
+	}
+	w.Flush()
+}
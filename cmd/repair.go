@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"github.com/huyhvq/eurofxref/pkg/repository"
+	"github.com/huyhvq/eurofxref/pkg/syncer"
+	"github.com/spf13/cobra"
+	"time"
+)
+
+var (
+	repairStart  string
+	repairEnd    string
+	repairDryRun bool
+)
+
+var repairCmd = &cobra.Command{
+	Use:   "repair",
+	Short: "Find and backfill missing or partial dates",
+	Long: `Compare the stored dates with the TARGET calendar, report missing dates and
+dates with fewer currencies than their neighbours, and backfill them from the
+ECB history feed.`,
+	RunE: repairExecute,
+}
+
+func init() {
+	repairCmd.Flags().StringVar(&repairStart, "start", "", "first date to check (default first stored date)")
+	repairCmd.Flags().StringVar(&repairEnd, "end", "", "last date to check (default last stored date)")
+	repairCmd.Flags().BoolVar(&repairDryRun, "dry-run", false, "only report, do not backfill")
+	rootCmd.AddCommand(repairCmd)
+}
+
+func repairExecute(cmd *cobra.Command, args []string) error {
+	start, err := parseDateFlag(repairStart)
+	if err != nil {
+		return err
+	}
+	end, err := parseDateFlag(repairEnd)
+	if err != nil {
+		return err
+	}
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	sinks, err := loadAlertSinks()
+	if err != nil {
+		return err
+	}
+	pegs, err := loadPegs()
+	if err != nil {
+		return err
+	}
+	listeners, closeListeners := ingestListeners(db.DB(), sinks, pegs)
+	defer closeListeners()
+	sc := newSyncer(repository.NewRate(db.DB()), repository.NewSyncRun(db.DB()),
+		repository.NewWebhook(db.DB()), listeners...)
+	report, err := sc.Check(start, end)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("checked %s..%s, %d dates stored\n", report.Start, report.End, report.StoredDates)
+	for _, d := range report.MissingDates {
+		fmt.Printf("missing  %s\n", d)
+	}
+	for _, p := range report.PartialDates {
+		fmt.Printf("partial  %s  %d of %d currencies\n", p.Date, p.Currencies, p.Expected)
+	}
+	if !report.HasGaps() || repairDryRun {
+		return nil
+	}
+
+	gapStart, gapEnd := report.GapRange()
+	run, err := sc.Repair(syncer.TriggerCLI, gapStart, gapEnd)
+	if run.ID != 0 {
+		printSyncRuns([]model.SyncRun{run})
+	}
+	if err != nil {
+		return err
+	}
+	after, err := sc.Check(gapStart, gapEnd)
+	if err != nil {
+		return err
+	}
+	if after.HasGaps() {
+		fmt.Printf("%d missing and %d partial dates are not available from the provider\n",
+			len(after.MissingDates), len(after.PartialDates))
+	}
+	return nil
+}
+
+func parseDateFlag(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	return time.ParseInLocation("2006-01-02", v, time.UTC)
+}
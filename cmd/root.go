@@ -1,16 +1,32 @@
 package cmd
 
 import (
+	"database/sql"
 	"fmt"
+	"github.com/huyhvq/eurofxref/pkg/alert"
+	"github.com/huyhvq/eurofxref/pkg/currency"
 	"github.com/huyhvq/eurofxref/pkg/database"
+	"github.com/huyhvq/eurofxref/pkg/eventbus"
 	"github.com/huyhvq/eurofxref/pkg/handler"
 	"github.com/huyhvq/eurofxref/pkg/repository"
 	"github.com/huyhvq/eurofxref/pkg/server"
 	"github.com/huyhvq/eurofxref/pkg/service/ecb"
+	"github.com/huyhvq/eurofxref/pkg/spread"
+	"github.com/huyhvq/eurofxref/pkg/syncer"
+	"github.com/huyhvq/eurofxref/pkg/webhook"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"log"
 	"os"
+	"time"
+)
+
+const (
+	// webhookPollInterval is how often queued webhook deliveries are checked.
+	webhookPollInterval = 10 * time.Second
+	// streamBacklog is how many rate stream events are kept for clients
+	// resuming with Last-Event-ID.
+	streamBacklog = 100
 )
 
 var cfgFile string
@@ -53,15 +69,15 @@ func initConfig() {
 }
 
 func serve(cmd *cobra.Command, args []string) {
-	db, err := database.NewDB(database.MysqlCfg{
-		Username: viper.GetString("db_user"),
-		Password: viper.GetString("db_pass"),
-		Host:     viper.GetString("db_host"),
-		Port:     viper.GetString("db_port"),
-		Name:     viper.GetString("db_name"),
-		Driver:   viper.GetString("db_driver"),
-	})
-
+	pegs, err := loadPegs()
+	if err != nil {
+		panic(err)
+	}
+	spreads, err := loadSpreads()
+	if err != nil {
+		panic(err)
+	}
+	db, err := openDB()
 	if err != nil {
 		panic(err)
 	}
@@ -71,7 +87,7 @@ func serve(cmd *cobra.Command, args []string) {
 		log.Println("initial database migrate failed...")
 	}
 	if m != nil {
-		if err := m.Up(); err != nil {
+		if err := migrateUp(m, db.DB()); err != nil {
 			log.Println("database migrate failed...", err)
 		} else {
 			log.Println("database migrate successful...")
@@ -79,17 +95,129 @@ func serve(cmd *cobra.Command, args []string) {
 	}
 
 	r := repository.NewRate(db.DB())
-	e := ecb.NewService(&ecb.Config{
-		Endpoint: "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-hist-90d.xml",
-	})
-	s := server.NewHttpServer(handler.NewHandler(r), e)
+	sr := repository.NewSyncRun(db.DB())
+	or := repository.NewOverride(db.DB())
+	wr := repository.NewWebhook(db.DB())
+	ar := repository.NewAlert(db.DB())
+	sinks, err := loadAlertSinks()
+	if err != nil {
+		panic(err)
+	}
+	latest, err := r.GetLatestDate()
+	if err != nil {
+		panic(err)
+	}
+	bus := eventbus.New(streamBacklog, eventbus.ID(latest))
+	listeners, closeListeners := ingestListeners(db.DB(), sinks, pegs)
+	defer closeListeners()
+	sc := newSyncer(r, sr, wr, append(listeners, eventbus.Listener(bus, repository.NewOverriddenRate(r, or)))...)
+	s := server.NewHttpServer(handler.NewHandler(&handler.Config{
+		RateRepo:     repository.NewOverriddenRate(r, or),
+		SyncRunRepo:  sr,
+		BasketRepo:   repository.NewBasket(db.DB()),
+		OverrideRepo: or,
+		QuoteRepo:    repository.NewQuote(db.DB()),
+		WebhookRepo:  wr,
+		AlertRepo:    ar,
+		Syncer:       sc,
+		AdminToken:   viper.GetString("admin_token"),
+		Spreads:      spreads,
+		QuoteTTL:     viper.GetDuration("quote_ttl"),
+		AlertSinks:   sinks,
+		Bus:          bus,
+		Pegs:         pegs,
+	}))
 	log.Println("initial service...")
-	if err := s.Initial(r); err != nil {
+	if _, err := sc.Sync(syncer.TriggerStartup); err != nil {
 		panic(err)
 	}
+	if viper.GetBool("auto_repair") {
+		autoRepair(sc)
+	}
+	go webhook.NewDispatcher(wr, nil).Run(webhookPollInterval, nil)
 	log.Println("initial service done")
 	log.Println("starting service as port 8080...")
 	if err := s.Start(); err != nil {
 		log.Println("starting service failed, error:", err)
 	}
 }
+
+func openDB() (database.Connector, error) {
+	return database.NewDB(database.MysqlCfg{
+		Username: viper.GetString("db_user"),
+		Password: viper.GetString("db_pass"),
+		Host:     viper.GetString("db_host"),
+		Port:     viper.GetString("db_port"),
+		Name:     viper.GetString("db_name"),
+		Driver:   viper.GetString("db_driver"),
+	})
+}
+
+// loadPegs reads the pegged currencies of the derived_currencies config key.
+func loadPegs() (currency.Pegs, error) {
+	var derived []currency.Derived
+	if err := viper.UnmarshalKey("derived_currencies", &derived); err != nil {
+		return nil, err
+	}
+	return currency.NewPegs(derived)
+}
+
+// loadSpreads reads the bid/ask rules of the spreads config key.
+func loadSpreads() (spread.Rules, error) {
+	var rules []spread.Rule
+	if err := viper.UnmarshalKey("spreads", &rules); err != nil {
+		return nil, err
+	}
+	return spread.New(rules)
+}
+
+// ingestListeners returns what runs after every sync that changed rates:
+// evaluating alert rules on the rates as served, overrides included. The
+// returned func waits for the triggered alerts to reach their sinks.
+func ingestListeners(db *sql.DB, sinks map[string]alert.Sink, pegs currency.Pegs) ([]syncer.Listener, func()) {
+	rates := repository.NewOverriddenRate(repository.NewRate(db), repository.NewOverride(db))
+	e := alert.NewEvaluator(repository.NewAlert(db), rates, sinks, pegs)
+	go e.Run()
+	return []syncer.Listener{e.Listener()}, e.Close
+}
+
+// loadAlertSinks builds the sinks of the alert_sinks config key.
+func loadAlertSinks() (map[string]alert.Sink, error) {
+	var cfgs []alert.SinkConfig
+	if err := viper.UnmarshalKey("alert_sinks", &cfgs); err != nil {
+		return nil, err
+	}
+	return alert.NewSinks(cfgs)
+}
+
+// newSyncer returns the ECB syncer. Webhook deliveries of wr are queued with
+// the rates they announce.
+func newSyncer(r repository.RateRepository, sr repository.SyncRunRepository, wr repository.WebhookRepository,
+	listeners ...syncer.Listener) syncer.Syncer {
+	e := ecb.NewService(&ecb.Config{
+		Endpoint:        "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-hist-90d.xml",
+		HistoryEndpoint: "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-hist.xml",
+	})
+	return syncer.New(r, sr, e, webhook.Outbox(wr), listeners...)
+}
+
+// autoRepair backfills gaps in the stored history once per start. Failures are
+// logged only, a partial history must not keep the API down.
+func autoRepair(sc syncer.Syncer) {
+	report, err := sc.Check(time.Time{}, time.Time{})
+	if err != nil {
+		log.Println("consistency check failed...", err)
+		return
+	}
+	if !report.HasGaps() {
+		return
+	}
+	log.Printf("found %d missing and %d partial dates, repairing...", len(report.MissingDates), len(report.PartialDates))
+	start, end := report.GapRange()
+	run, err := sc.Repair(syncer.TriggerRepair, start, end)
+	if err != nil {
+		log.Println("repair failed...", err)
+		return
+	}
+	log.Printf("repair done, %d rates inserted, %d revised", run.RowsInserted, run.RowsRevised)
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"github.com/huyhvq/eurofxref/pkg/repository"
+	"github.com/huyhvq/eurofxref/pkg/syncer"
+	"github.com/spf13/cobra"
+	"os"
+	"text/tabwriter"
+)
+
+var syncStatusLimit int
+
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Inspect and trigger rate synchronisation",
+	Long:  `Inspect the sync run history and trigger a sync with the provider.`,
+}
+
+var syncStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "List recent sync runs",
+	Long:  `List recent sync runs, newest first.`,
+	RunE:  syncStatusExecute,
+}
+
+var syncRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Trigger a sync now",
+	Long:  `Fetch the provider feed, store new rates and record the run.`,
+	RunE:  syncRunExecute,
+}
+
+func init() {
+	syncStatusCmd.Flags().IntVar(&syncStatusLimit, "limit", 20, "number of runs to show")
+	syncCmd.AddCommand(syncStatusCmd)
+	syncCmd.AddCommand(syncRunCmd)
+	rootCmd.AddCommand(syncCmd)
+}
+
+func syncStatusExecute(cmd *cobra.Command, args []string) error {
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	runs, err := repository.NewSyncRun(db.DB()).GetRuns(syncStatusLimit)
+	if err != nil {
+		return err
+	}
+	printSyncRuns(runs)
+	return nil
+}
+
+func syncRunExecute(cmd *cobra.Command, args []string) error {
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	sinks, err := loadAlertSinks()
+	if err != nil {
+		return err
+	}
+	pegs, err := loadPegs()
+	if err != nil {
+		return err
+	}
+	listeners, closeListeners := ingestListeners(db.DB(), sinks, pegs)
+	defer closeListeners()
+	run, err := newSyncer(repository.NewRate(db.DB()), repository.NewSyncRun(db.DB()),
+		repository.NewWebhook(db.DB()), listeners...).Sync(syncer.TriggerCLI)
+	if run.ID != 0 {
+		printSyncRuns([]model.SyncRun{run})
+	}
+	return err
+}
+
+func printSyncRuns(runs []model.SyncRun) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTARTED\tTRIGGER\tSTATUS\tHTTP\tDATES\tRANGE\tINSERTED\tREVISED\tERROR")
+	for _, r := range runs {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%d\t%d\t%s..%s\t%d\t%d\t%s\n",
+			r.ID, r.StartedAt.Format("2006-01-02 15:04:05"), r.TriggeredBy, r.Status, r.HTTPStatus,
+			r.DatesFetched, r.FirstDate, r.LastDate, r.RowsInserted, r.RowsRevised, r.Error)
+	}
+	w.Flush()
+}
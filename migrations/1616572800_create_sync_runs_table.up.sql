@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS `sync_runs`
+(
+    `id`            integer PRIMARY KEY AUTO_INCREMENT,
+    `provider`      varchar(32)   NOT NULL,
+    `endpoint`      varchar(255)  NOT NULL DEFAULT '',
+    `triggered_by`  varchar(32)   NOT NULL,
+    `status`        varchar(16)   NOT NULL,
+    `http_status`   integer       NOT NULL DEFAULT 0,
+    `feed_hash`     varchar(64)   NOT NULL DEFAULT '',
+    `dates_fetched` integer       NOT NULL DEFAULT 0,
+    `first_date`    date          NULL,
+    `last_date`     date          NULL,
+    `rows_inserted` integer       NOT NULL DEFAULT 0,
+    `error`         varchar(1024) NOT NULL DEFAULT '',
+    `started_at`    datetime      NOT NULL,
+    `finished_at`   datetime      NULL,
+    INDEX `idx_sync_runs_started_at` (`started_at`)
+);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS `rate_revisions`;
\ No newline at end of file
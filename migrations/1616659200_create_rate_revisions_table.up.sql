@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS `rate_revisions`
+(
+    `id`            bigint PRIMARY KEY AUTO_INCREMENT,
+    `currency`      varchar(3)     NOT NULL,
+    `rate_date`     date           NOT NULL,
+    `rate`          decimal(10, 5) NOT NULL,
+    `source`        varchar(32)    NOT NULL,
+    `feed_hash`     varchar(64)    NOT NULL DEFAULT '',
+    `fetched_at`    datetime(6)    NOT NULL,
+    `superseded_at` datetime(6)    NULL,
+    INDEX `idx_rate_revisions_date_currency` (`rate_date`, `currency`)
+);
@@ -0,0 +1 @@
+DELETE FROM `rate_revisions` WHERE `feed_hash` = '';
\ No newline at end of file
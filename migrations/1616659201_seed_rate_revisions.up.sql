@@ -0,0 +1,4 @@
+INSERT INTO `rate_revisions` (`currency`, `rate_date`, `rate`, `source`, `feed_hash`, `fetched_at`)
+SELECT r.`currency`, r.`created_at`, r.`rate`, 'ecb', '', r.`created_at`
+FROM `rates` r
+         JOIN (SELECT MAX(`id`) AS `id` FROM `rates` GROUP BY `currency`, `created_at`) l ON l.`id` = r.`id`;
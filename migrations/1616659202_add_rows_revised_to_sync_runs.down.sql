@@ -0,0 +1 @@
+ALTER TABLE `sync_runs` DROP COLUMN `rows_revised`;
\ No newline at end of file
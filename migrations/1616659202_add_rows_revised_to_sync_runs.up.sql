@@ -0,0 +1 @@
+ALTER TABLE `sync_runs` ADD COLUMN `rows_revised` integer NOT NULL DEFAULT 0 AFTER `rows_inserted`;
\ No newline at end of file
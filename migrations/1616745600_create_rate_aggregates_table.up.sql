@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS `rate_aggregates`
+(
+    `currency`   varchar(3)      NOT NULL,
+    `month`      date            NOT NULL,
+    `count`      integer         NOT NULL,
+    `sum`        decimal(20, 5)  NOT NULL,
+    `sum_sq`     decimal(30, 10) NOT NULL,
+    `min`        decimal(10, 5)  NOT NULL,
+    `max`        decimal(10, 5)  NOT NULL,
+    `first_date` date            NOT NULL,
+    `first_rate` decimal(10, 5)  NOT NULL,
+    `last_date`  date            NOT NULL,
+    `last_rate`  decimal(10, 5)  NOT NULL,
+    PRIMARY KEY (`currency`, `month`)
+);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS `baskets`
+(
+    `id`         bigint PRIMARY KEY AUTO_INCREMENT,
+    `name`       varchar(64)    NOT NULL,
+    `kind`       varchar(16)    NOT NULL,
+    `base_date`  date           NULL,
+    `base_value` decimal(20, 6) NULL,
+    `created_at` datetime(6)    NOT NULL,
+    `updated_at` datetime(6)    NOT NULL,
+    UNIQUE INDEX `idx_baskets_name` (`name`)
+);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS `basket_components`;
\ No newline at end of file
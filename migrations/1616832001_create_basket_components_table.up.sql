@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS `basket_components`
+(
+    `basket_id` bigint          NOT NULL,
+    `currency`  varchar(3)      NOT NULL,
+    `weight`    decimal(20, 10) NULL,
+    `units`     decimal(30, 10) NOT NULL,
+    PRIMARY KEY (`basket_id`, `currency`)
+);
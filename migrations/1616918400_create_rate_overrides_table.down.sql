@@ -0,0 +1 @@
+DROP TABLE IF EXISTS `rate_overrides`;
\ No newline at end of file
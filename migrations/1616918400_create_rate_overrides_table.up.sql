@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS `rate_overrides`
+(
+    `id`         bigint PRIMARY KEY AUTO_INCREMENT,
+    `currency`   varchar(3)      NOT NULL,
+    `start_date` date            NOT NULL,
+    `end_date`   date            NOT NULL,
+    `rate`       decimal(20, 10) NOT NULL,
+    `reason`     varchar(255)    NOT NULL,
+    `created_by` varchar(64)     NOT NULL,
+    `created_at` datetime(6)     NOT NULL,
+    `revoked_by` varchar(64)     NOT NULL DEFAULT '',
+    `revoked_at` datetime(6)     NULL,
+    INDEX `idx_rate_overrides_dates` (`start_date`, `end_date`)
+);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS `quotes`
+(
+    `id`            varchar(32)     NOT NULL PRIMARY KEY,
+    `from_currency` varchar(3)      NOT NULL,
+    `to_currency`   varchar(3)      NOT NULL,
+    `amount`        decimal(20, 6)  NOT NULL,
+    `rate`          decimal(20, 10) NOT NULL,
+    `result`        decimal(20, 6)  NOT NULL,
+    `rate_date`     date            NOT NULL,
+    `profile`       varchar(64)     NOT NULL DEFAULT '',
+    `side`          varchar(3)      NOT NULL,
+    `created_at`    datetime(6)     NOT NULL,
+    `expires_at`    datetime(6)     NOT NULL,
+    `redeemed_at`   datetime(6)     NULL
+);
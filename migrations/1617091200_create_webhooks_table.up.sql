@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS `webhooks`
+(
+    `id`         bigint PRIMARY KEY AUTO_INCREMENT,
+    `url`        varchar(2048) NOT NULL,
+    `secret`     varchar(255)  NOT NULL,
+    `currencies` varchar(1024) NOT NULL DEFAULT '',
+    `created_at` datetime(6)   NOT NULL
+);
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS `webhook_deliveries`
+(
+    `id`               bigint PRIMARY KEY AUTO_INCREMENT,
+    `webhook_id`       bigint        NOT NULL,
+    `event`            varchar(64)   NOT NULL,
+    `sync_run_id`      bigint        NOT NULL,
+    `payload`          mediumtext    NOT NULL,
+    `status`           varchar(16)   NOT NULL,
+    `attempts`         int           NOT NULL DEFAULT 0,
+    `next_attempt_at`  datetime(6)   NOT NULL,
+    `last_status_code` int           NOT NULL DEFAULT 0,
+    `last_error`       varchar(1024) NOT NULL DEFAULT '',
+    `created_at`       datetime(6)   NOT NULL,
+    `delivered_at`     datetime(6)   NULL,
+    INDEX `idx_webhook_deliveries_due` (`status`, `next_attempt_at`),
+    INDEX `idx_webhook_deliveries_webhook` (`webhook_id`, `id`)
+);
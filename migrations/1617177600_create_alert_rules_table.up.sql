@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS `alert_rules`
+(
+    `id`                bigint PRIMARY KEY AUTO_INCREMENT,
+    `name`              varchar(255)    NOT NULL DEFAULT '',
+    `base`              varchar(3)      NOT NULL,
+    `quote`             varchar(3)      NOT NULL,
+    `condition`         varchar(16)     NOT NULL,
+    `threshold`         decimal(20, 10) NOT NULL,
+    `cooldown_seconds`  int             NOT NULL DEFAULT 0,
+    `sinks`             varchar(255)    NOT NULL DEFAULT '',
+    `created_at`        datetime(6)     NOT NULL,
+    `last_triggered_at` datetime(6)     NULL
+);
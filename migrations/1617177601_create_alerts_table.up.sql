@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS `alerts`
+(
+    `id`           bigint PRIMARY KEY AUTO_INCREMENT,
+    `rule_id`      bigint          NOT NULL,
+    `date`         date            NOT NULL,
+    `value`        decimal(20, 10) NOT NULL,
+    `previous`     decimal(20, 10) NULL,
+    `message`      varchar(255)    NOT NULL,
+    `error`        varchar(1024)   NOT NULL DEFAULT '',
+    `triggered_at` datetime(6)     NOT NULL,
+    INDEX `idx_alerts_rule` (`rule_id`, `id`)
+);
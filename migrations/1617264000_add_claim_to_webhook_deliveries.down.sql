@@ -0,0 +1 @@
+ALTER TABLE `webhook_deliveries` DROP INDEX `idx_webhook_deliveries_claim`, DROP COLUMN `claim`;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE `webhook_deliveries` ADD COLUMN `claim` varchar(32) NOT NULL DEFAULT '', ADD INDEX `idx_webhook_deliveries_claim` (`claim`);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS `change_sequence`;
\ No newline at end of file
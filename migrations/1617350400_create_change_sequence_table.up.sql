@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS `change_sequence`
+(
+    `id`  tinyint PRIMARY KEY,
+    `seq` bigint NOT NULL
+);
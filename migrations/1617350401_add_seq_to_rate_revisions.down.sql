@@ -0,0 +1 @@
+ALTER TABLE `rate_revisions` DROP INDEX `idx_rate_revisions_seq`, DROP COLUMN `seq`;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE `rate_revisions` ADD COLUMN `seq` bigint NULL, ADD UNIQUE INDEX `idx_rate_revisions_seq` (`seq`);
@@ -0,0 +1 @@
+UPDATE `rate_revisions` SET `seq` = NULL;
\ No newline at end of file
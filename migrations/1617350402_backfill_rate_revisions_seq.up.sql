@@ -0,0 +1 @@
+UPDATE `rate_revisions` SET `seq` = `id`;
@@ -0,0 +1 @@
+DELETE FROM `change_sequence`;
\ No newline at end of file
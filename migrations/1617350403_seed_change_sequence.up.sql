@@ -0,0 +1,3 @@
+INSERT INTO `change_sequence` (`id`, `seq`)
+SELECT 1, COALESCE(MAX(`seq`), 0)
+FROM `rate_revisions`;
@@ -0,0 +1,245 @@
+package alert
+
+import (
+	"errors"
+	"fmt"
+	"github.com/huyhvq/eurofxref/pkg/currency"
+	"github.com/huyhvq/eurofxref/pkg/fx"
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"github.com/huyhvq/eurofxref/pkg/repository"
+	"github.com/huyhvq/eurofxref/pkg/syncer"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidRule = errors.New("invalid alert rule")
+
+// Normalize upper-cases the pair of r and checks its condition, threshold,
+// cooldown and sinks against the configured sink names.
+func Normalize(r *model.AlertRule, sinks map[string]Sink) error {
+	r.Name = strings.TrimSpace(r.Name)
+	r.Base = strings.ToUpper(strings.TrimSpace(r.Base))
+	r.Quote = strings.ToUpper(strings.TrimSpace(r.Quote))
+	if len(r.Base) != 3 || len(r.Quote) != 3 || r.Base == r.Quote || len(r.Name) > 255 {
+		return ErrInvalidRule
+	}
+	switch r.Condition {
+	case model.AlertAbove, model.AlertBelow, model.AlertCrossesAbove, model.AlertCrossesBelow, model.AlertChangePct:
+	default:
+		return ErrInvalidRule
+	}
+	if r.Threshold <= 0 || r.Cooldown < 0 {
+		return ErrInvalidRule
+	}
+	for i, s := range r.Sinks {
+		r.Sinks[i] = strings.TrimSpace(s)
+		if _, ok := sinks[r.Sinks[i]]; !ok {
+			return fmt.Errorf("%w: unknown sink %q", ErrInvalidRule, s)
+		}
+	}
+	return nil
+}
+
+// Evaluate reports whether r fires for value, the rate on the ingested date,
+// and previous, the rate of the publication before it or zero when there is
+// none. The crossing and change conditions need a previous rate.
+func Evaluate(r model.AlertRule, value, previous float64) (string, bool) {
+	pair := r.Base + "/" + r.Quote
+	threshold := strconv.FormatFloat(r.Threshold, 'f', -1, 64)
+	rate := strconv.FormatFloat(value, 'f', -1, 64)
+	switch r.Condition {
+	case model.AlertAbove:
+		return fmt.Sprintf("%s at %s is above %s", pair, rate, threshold), value > r.Threshold
+	case model.AlertBelow:
+		return fmt.Sprintf("%s at %s is below %s", pair, rate, threshold), value < r.Threshold
+	case model.AlertCrossesAbove:
+		return fmt.Sprintf("%s crossed above %s at %s", pair, threshold, rate),
+			previous != 0 && previous <= r.Threshold && value > r.Threshold
+	case model.AlertCrossesBelow:
+		return fmt.Sprintf("%s crossed below %s at %s", pair, threshold, rate),
+			previous != 0 && previous >= r.Threshold && value < r.Threshold
+	case model.AlertChangePct:
+		if previous == 0 {
+			return "", false
+		}
+		pct := (value/previous - 1) * 100
+		return fmt.Sprintf("%s moved %+.2f%% to %s", pair, pct, rate), math.Abs(pct) > r.Threshold
+	}
+	return "", false
+}
+
+// sinkQueueSize is how many triggered alerts may wait for their sinks
+// before further ones are recorded as undelivered.
+const sinkQueueSize = 256
+
+// errQueueFull is recorded on an alert dropped because the sink queue is
+// full.
+var errQueueFull = errors.New("sink queue full")
+
+// delivery is a recorded alert waiting for the sinks of its rule.
+type delivery struct {
+	rule  model.AlertRule
+	alert model.Alert
+}
+
+// Evaluator checks the stored rules against newly ingested rates and
+// records the alerts that fire. Sinks are sent to by Run, off the syncer's
+// lock, and their failures recorded on the alert afterwards.
+type Evaluator struct {
+	alerts repository.AlertRepository
+	rates  repository.RateRepository
+	sinks  map[string]Sink
+	pegs   currency.Pegs
+	now    func() time.Time
+	queue  chan delivery
+	done   chan struct{}
+}
+
+func NewEvaluator(alerts repository.AlertRepository, rates repository.RateRepository, sinks map[string]Sink, pegs currency.Pegs) *Evaluator {
+	return &Evaluator{
+		alerts: alerts,
+		rates:  rates,
+		sinks:  sinks,
+		pegs:   pegs,
+		now:    func() time.Time { return time.Now().UTC() },
+		queue:  make(chan delivery, sinkQueueSize),
+		done:   make(chan struct{}),
+	}
+}
+
+// Listener evaluates the rules on every new publication date of a sync, in
+// order. Backfilled dates, such as those a repair fills in, and revisions of
+// stored dates are skipped. Failures are logged only.
+func (e *Evaluator) Listener() syncer.Listener {
+	return func(ev syncer.Event) {
+		for _, nd := range ev.NewDates {
+			d, err := time.ParseInLocation("2006-01-02", nd, time.UTC)
+			if err != nil {
+				continue
+			}
+			if _, err := e.Evaluate(d); err != nil {
+				log.Println("alert: evaluation failed:", err)
+			}
+		}
+	}
+}
+
+// Run sends the queued alerts to their sinks until Close is called and the
+// queue is drained.
+func (e *Evaluator) Run() {
+	defer close(e.done)
+	for d := range e.queue {
+		if msg := e.send(d.rule, d.alert); msg != "" {
+			if err := e.alerts.UpdateError(d.alert.ID, msg); err != nil {
+				log.Println("alert: recording sink failure failed:", err)
+			}
+		}
+	}
+}
+
+// Close stops accepting alerts and waits for Run to deliver the queued
+// ones.
+func (e *Evaluator) Close() {
+	close(e.queue)
+	<-e.done
+}
+
+// Evaluate checks every rule against the rates of date and the publication
+// before it, queues the alerts that fire for their sinks and returns them.
+func (e *Evaluator) Evaluate(date time.Time) ([]model.Alert, error) {
+	rules, err := e.alerts.GetRules()
+	if err != nil || len(rules) == 0 {
+		return nil, err
+	}
+	rates, err := e.rates.GetRatesByDate(date)
+	if err != nil || len(rates) == 0 {
+		return nil, err
+	}
+	d := date.Format("2006-01-02")
+	table := fx.NewTable(rates).WithFixed(d).WithPegged(e.pegs, d)
+	var prevTable fx.Table
+	prevDate, err := e.rates.GetDateOnOrBefore(date.AddDate(0, 0, -1))
+	if err != nil {
+		return nil, err
+	}
+	if !prevDate.IsZero() {
+		prevRates, err := e.rates.GetRatesByDate(prevDate)
+		if err != nil {
+			return nil, err
+		}
+		if len(prevRates) > 0 {
+			pd := prevDate.Format("2006-01-02")
+			prevTable = fx.NewTable(prevRates).WithFixed(pd).WithPegged(e.pegs, pd)
+		}
+	}
+
+	now := e.now()
+	fired := make([]model.Alert, 0)
+	for _, r := range rules {
+		if !r.LastTriggeredAt.IsZero() && now.Before(r.LastTriggeredAt.Add(r.Cooldown)) {
+			continue
+		}
+		value, err := table.Cross(r.Base, r.Quote)
+		if err != nil {
+			continue
+		}
+		var previous float64
+		if prevTable != nil {
+			previous, _ = prevTable.Cross(r.Base, r.Quote)
+		}
+		msg, ok := Evaluate(r, value, previous)
+		if !ok {
+			continue
+		}
+		a := model.Alert{RuleID: r.ID, Date: d, Value: value, Previous: previous, Message: msg, TriggeredAt: now}
+		if a.ID, err = e.alerts.Trigger(a); err != nil {
+			return fired, err
+		}
+		e.enqueue(r, a)
+		fired = append(fired, a)
+	}
+	return fired, nil
+}
+
+// enqueue hands a to Run without waiting. When the queue is full the alert
+// stays recorded with the failure.
+func (e *Evaluator) enqueue(r model.AlertRule, a model.Alert) {
+	select {
+	case e.queue <- delivery{rule: r, alert: a}:
+	default:
+		if err := e.alerts.UpdateError(a.ID, errQueueFull.Error()); err != nil {
+			log.Println("alert: recording sink failure failed:", err)
+		}
+	}
+}
+
+// send delivers a to the sinks of r and returns the failures, if any.
+func (e *Evaluator) send(r model.AlertRule, a model.Alert) string {
+	names := r.Sinks
+	if len(names) == 0 {
+		for name := range e.sinks {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+	var failed []string
+	for _, name := range names {
+		s, ok := e.sinks[name]
+		if !ok {
+			failed = append(failed, name+": unknown sink")
+			continue
+		}
+		if err := s.Send(r, a); err != nil {
+			failed = append(failed, name+": "+err.Error())
+		}
+	}
+	msg := strings.Join(failed, "; ")
+	if len(msg) > 1024 {
+		msg = msg[:1024]
+	}
+	return msg
+}
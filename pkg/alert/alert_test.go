@@ -0,0 +1,222 @@
+package alert
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"github.com/huyhvq/eurofxref/pkg/repository"
+	"github.com/huyhvq/eurofxref/pkg/syncer"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type mockRates struct {
+	repository.RateRepository
+	byDate map[string][]model.Rate
+}
+
+func (m *mockRates) GetRatesByDate(date time.Time) ([]model.Rate, error) {
+	return m.byDate[date.Format("2006-01-02")], nil
+}
+
+func (m *mockRates) GetDateOnOrBefore(date time.Time) (time.Time, error) {
+	for d := date; d.After(date.AddDate(0, 0, -10)); d = d.AddDate(0, 0, -1) {
+		if _, ok := m.byDate[d.Format("2006-01-02")]; ok {
+			return d, nil
+		}
+	}
+	return time.Time{}, nil
+}
+
+type mockAlerts struct {
+	repository.AlertRepository
+	mu        sync.Mutex
+	rules     []model.AlertRule
+	triggered []model.Alert
+}
+
+func (m *mockAlerts) GetRules() ([]model.AlertRule, error) {
+	return m.rules, nil
+}
+
+func (m *mockAlerts) Trigger(a model.Alert) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.triggered = append(m.triggered, a)
+	return int64(len(m.triggered)), nil
+}
+
+func (m *mockAlerts) UpdateError(id int64, msg string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.triggered[id-1].Error = msg
+	return nil
+}
+
+type mockSink struct {
+	sent []model.Alert
+	err  error
+}
+
+func (m *mockSink) Send(r model.AlertRule, a model.Alert) error {
+	m.sent = append(m.sent, a)
+	return m.err
+}
+
+func TestNormalize(t *testing.T) {
+	sinks := map[string]Sink{"log": logSink{}}
+	r := model.AlertRule{Base: "eur", Quote: " usd", Condition: model.AlertCrossesAbove, Threshold: 1.2,
+		Sinks: []string{"log"}}
+	assert.Nil(t, Normalize(&r, sinks))
+	assert.Equal(t, "EUR", r.Base)
+	assert.Equal(t, "USD", r.Quote)
+
+	for _, invalid := range []model.AlertRule{
+		{Base: "EUR", Quote: "EUR", Condition: model.AlertAbove, Threshold: 1},
+		{Base: "EUR", Quote: "USD", Condition: "crosses", Threshold: 1},
+		{Base: "EUR", Quote: "USD", Condition: model.AlertAbove},
+		{Base: "EUR", Quote: "USD", Condition: model.AlertAbove, Threshold: 1, Cooldown: -time.Second},
+		{Base: "EUR", Quote: "USD", Condition: model.AlertAbove, Threshold: 1, Sinks: []string{"file"}},
+	} {
+		assert.True(t, errors.Is(Normalize(&invalid, sinks), ErrInvalidRule), invalid.Condition)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	rule := func(c string, threshold float64) model.AlertRule {
+		return model.AlertRule{Base: "EUR", Quote: "USD", Condition: c, Threshold: threshold}
+	}
+	for _, c := range []struct {
+		rule            model.AlertRule
+		value, previous float64
+		fires           bool
+	}{
+		{rule(model.AlertAbove, 1.2), 1.21, 0, true},
+		{rule(model.AlertAbove, 1.2), 1.2, 0, false},
+		{rule(model.AlertBelow, 1.2), 1.19, 1.18, true},
+		{rule(model.AlertCrossesAbove, 1.2), 1.201, 1.199, true},
+		{rule(model.AlertCrossesAbove, 1.2), 1.202, 1.201, false},
+		{rule(model.AlertCrossesAbove, 1.2), 1.201, 0, false},
+		{rule(model.AlertCrossesBelow, 1.2), 1.199, 1.2, true},
+		{rule(model.AlertChangePct, 1), 1.189, 1.2, false},
+		{rule(model.AlertChangePct, 1), 1.187, 1.2, true},
+		{rule(model.AlertChangePct, 1), 1.2121, 1.2, true},
+		{rule(model.AlertChangePct, 1), 1.3, 0, false},
+	} {
+		_, fires := Evaluate(c.rule, c.value, c.previous)
+		assert.Equal(t, c.fires, fires, "%s %v %v", c.rule.Condition, c.value, c.previous)
+	}
+
+	msg, _ := Evaluate(rule(model.AlertCrossesAbove, 1.2), 1.2012, 1.1987)
+	assert.Equal(t, "EUR/USD crossed above 1.2 at 1.2012", msg)
+	msg, _ = Evaluate(model.AlertRule{Base: "EUR", Quote: "GBP", Condition: model.AlertChangePct, Threshold: 1}, 0.8686, 0.86)
+	assert.Equal(t, "EUR/GBP moved +1.00% to 0.8686", msg)
+}
+
+func TestEvaluator_Evaluate(t *testing.T) {
+	now := time.Date(2021, 3, 31, 16, 0, 0, 0, time.UTC)
+	rates := &mockRates{byDate: map[string][]model.Rate{
+		"2021-03-26": {{Time: "2021-03-26", Currency: "USD", Rate: 1.19}, {Time: "2021-03-26", Currency: "GBP", Rate: 0.86}},
+		"2021-03-29": {{Time: "2021-03-29", Currency: "USD", Rate: 1.21}, {Time: "2021-03-29", Currency: "GBP", Rate: 0.861}},
+	}}
+	alerts := &mockAlerts{rules: []model.AlertRule{
+		{ID: 1, Base: "EUR", Quote: "USD", Condition: model.AlertCrossesAbove, Threshold: 1.2},
+		{ID: 2, Base: "GBP", Quote: "USD", Condition: model.AlertChangePct, Threshold: 1, Sinks: []string{"b"}},
+		{ID: 3, Base: "EUR", Quote: "USD", Condition: model.AlertAbove, Threshold: 1.2, Cooldown: time.Hour,
+			LastTriggeredAt: now.Add(-time.Minute)},
+		{ID: 4, Base: "EUR", Quote: "JPY", Condition: model.AlertAbove, Threshold: 1},
+		{ID: 5, Base: "EUR", Quote: "GBP", Condition: model.AlertAbove, Threshold: 0.85, Sinks: []string{"a"}},
+	}}
+	a, b := &mockSink{}, &mockSink{err: errors.New("boom")}
+	e := NewEvaluator(alerts, rates, map[string]Sink{"a": a, "b": b}, nil)
+	e.now = func() time.Time { return now }
+
+	go e.Run()
+
+	// Revising a stored date alone evaluates nothing.
+	e.Listener()(syncer.Event{Revisions: []model.RateRevision{{Time: "2021-03-29", Currency: "USD"}}})
+	assert.Empty(t, alerts.triggered)
+
+	// A repair filling in old dates evaluates nothing either.
+	e.Listener()(syncer.Event{
+		Run:             model.SyncRun{TriggeredBy: syncer.TriggerRepair},
+		Revisions:       []model.RateRevision{{Time: "2021-03-29", Currency: "USD"}},
+		BackfilledDates: []string{"2021-03-29"},
+	})
+	assert.Empty(t, alerts.triggered)
+
+	e.Listener()(syncer.Event{
+		Revisions: []model.RateRevision{{Time: "2021-03-26", Currency: "USD"}, {Time: "2021-03-29", Currency: "USD"}},
+		NewDates:  []string{"2021-03-26", "2021-03-29"},
+	})
+	e.Close()
+	assert.Len(t, alerts.triggered, 4)
+	assert.Equal(t, []string{"2021-03-26", "2021-03-29", "2021-03-29", "2021-03-29"},
+		[]string{alerts.triggered[0].Date, alerts.triggered[1].Date, alerts.triggered[2].Date, alerts.triggered[3].Date})
+	assert.Equal(t, int64(5), alerts.triggered[0].RuleID)
+	assert.Equal(t, "", alerts.triggered[0].Error)
+	assert.Equal(t, model.Alert{RuleID: 1, Date: "2021-03-29", Value: 1.21, Previous: 1.19,
+		Message: "EUR/USD crossed above 1.2 at 1.21", Error: "b: boom", TriggeredAt: now}, alerts.triggered[1])
+	assert.Equal(t, int64(2), alerts.triggered[2].RuleID)
+	assert.Equal(t, "b: boom", alerts.triggered[2].Error)
+	assert.Len(t, a.sent, 3)
+	assert.Len(t, b.sent, 2)
+}
+
+func TestEvaluator_queueFull(t *testing.T) {
+	rates := &mockRates{byDate: map[string][]model.Rate{
+		"2021-03-29": {{Time: "2021-03-29", Currency: "USD", Rate: 1.21}},
+	}}
+	alerts := &mockAlerts{rules: []model.AlertRule{
+		{ID: 1, Base: "EUR", Quote: "USD", Condition: model.AlertAbove, Threshold: 1.2},
+	}}
+	sink := &mockSink{}
+	e := NewEvaluator(alerts, rates, map[string]Sink{"a": sink}, nil)
+	e.queue = make(chan delivery)
+
+	// Without Run nothing takes the alert; it is recorded as undelivered
+	// instead of blocking the sync.
+	fired, err := e.Evaluate(time.Date(2021, 3, 29, 0, 0, 0, 0, time.UTC))
+	assert.Nil(t, err)
+	assert.Len(t, fired, 1)
+	assert.Equal(t, errQueueFull.Error(), alerts.triggered[0].Error)
+	assert.Empty(t, sink.sent)
+}
+
+func TestNewSinks(t *testing.T) {
+	sinks, err := NewSinks(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]Sink{"log": logSink{}}, sinks)
+
+	path := filepath.Join(t.TempDir(), "alerts.jsonl")
+	sinks, err = NewSinks([]SinkConfig{{Type: "log"}, {Name: "audit", Type: "file", Path: path}})
+	assert.Nil(t, err)
+	assert.Len(t, sinks, 2)
+	r := model.AlertRule{ID: 1, Base: "EUR", Quote: "USD", Condition: model.AlertAbove, Threshold: 1.2}
+	assert.Nil(t, sinks["audit"].Send(r, model.Alert{RuleID: 1, Date: "2021-03-29", Value: 1.21, Message: "m"}))
+	assert.Nil(t, sinks["audit"].Send(r, model.Alert{RuleID: 1, Date: "2021-03-30", Value: 1.22, Message: "m"}))
+	b, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	assert.Len(t, lines, 2)
+	var p Payload
+	assert.Nil(t, json.Unmarshal([]byte(lines[1]), &p))
+	assert.Equal(t, "2021-03-30", p.Date)
+	assert.Equal(t, "EUR/USD", p.Pair)
+	assert.Equal(t, EventAlertTriggered, p.Event)
+
+	for _, invalid := range [][]SinkConfig{
+		{{Type: "file"}},
+		{{Type: "webhook"}},
+		{{Type: "email"}},
+		{{Type: "log"}, {Type: "log"}},
+	} {
+		_, err := NewSinks(invalid)
+		assert.NotNil(t, err)
+	}
+}
@@ -0,0 +1,163 @@
+package alert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"github.com/huyhvq/eurofxref/pkg/webhook"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	EventAlertTriggered = "alert.triggered"
+
+	SinkLog     = "log"
+	SinkFile    = "file"
+	SinkWebhook = "webhook"
+)
+
+// Sink delivers triggered alerts.
+type Sink interface {
+	Send(r model.AlertRule, a model.Alert) error
+}
+
+// SinkConfig configures a named sink. Path is used by file sinks, URL and
+// Secret by webhook sinks.
+type SinkConfig struct {
+	Name   string `mapstructure:"name"`
+	Type   string `mapstructure:"type"`
+	Path   string `mapstructure:"path"`
+	URL    string `mapstructure:"url"`
+	Secret string `mapstructure:"secret"`
+}
+
+// Payload is what sinks write for an alert.
+type Payload struct {
+	Event       string    `json:"event"`
+	RuleID      int64     `json:"rule_id"`
+	Name        string    `json:"name,omitempty"`
+	Pair        string    `json:"pair"`
+	Condition   string    `json:"condition"`
+	Threshold   float64   `json:"threshold"`
+	Date        string    `json:"date"`
+	Value       float64   `json:"value"`
+	Previous    float64   `json:"previous,omitempty"`
+	Message     string    `json:"message"`
+	TriggeredAt time.Time `json:"triggered_at"`
+}
+
+func NewPayload(r model.AlertRule, a model.Alert) Payload {
+	return Payload{
+		Event:       EventAlertTriggered,
+		RuleID:      r.ID,
+		Name:        r.Name,
+		Pair:        r.Base + "/" + r.Quote,
+		Condition:   r.Condition,
+		Threshold:   r.Threshold,
+		Date:        a.Date,
+		Value:       a.Value,
+		Previous:    a.Previous,
+		Message:     a.Message,
+		TriggeredAt: a.TriggeredAt,
+	}
+}
+
+// NewSinks builds the configured sinks by name. Without any configuration a
+// single log sink named "log" is used.
+func NewSinks(cfgs []SinkConfig) (map[string]Sink, error) {
+	if len(cfgs) == 0 {
+		return map[string]Sink{SinkLog: logSink{}}, nil
+	}
+	sinks := make(map[string]Sink, len(cfgs))
+	for _, c := range cfgs {
+		if c.Name == "" {
+			c.Name = c.Type
+		}
+		if _, ok := sinks[c.Name]; ok {
+			return nil, fmt.Errorf("alert sink %q defined twice", c.Name)
+		}
+		switch c.Type {
+		case SinkLog:
+			sinks[c.Name] = logSink{}
+		case SinkFile:
+			if c.Path == "" {
+				return nil, fmt.Errorf("alert sink %q needs a path", c.Name)
+			}
+			sinks[c.Name] = &fileSink{path: c.Path}
+		case SinkWebhook:
+			if c.URL == "" {
+				return nil, fmt.Errorf("alert sink %q needs a url", c.Name)
+			}
+			sinks[c.Name] = &webhookSink{url: c.URL, secret: c.Secret, client: &http.Client{Timeout: 10 * time.Second}}
+		default:
+			return nil, fmt.Errorf("alert sink %q has unknown type %q", c.Name, c.Type)
+		}
+	}
+	return sinks, nil
+}
+
+type logSink struct{}
+
+func (logSink) Send(r model.AlertRule, a model.Alert) error {
+	log.Printf("alert: rule %d on %s: %s", r.ID, a.Date, a.Message)
+	return nil
+}
+
+// fileSink appends one JSON line per alert.
+type fileSink struct {
+	mu   sync.Mutex
+	path string
+}
+
+func (s *fileSink) Send(r model.AlertRule, a model.Alert) error {
+	b, err := json.Marshal(NewPayload(r, a))
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// webhookSink posts the alert signed like webhook deliveries.
+type webhookSink struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+func (s *webhookSink) Send(r model.AlertRule, a model.Alert) error {
+	b, err := json.Marshal(NewPayload(r, a))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhook.EventHeader, EventAlertTriggered)
+	req.Header.Set(webhook.SignatureHeader, webhook.Sign(s.secret, b))
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+// Package basket values composite currency baskets from the reference rates.
+package basket
+
+import (
+	"errors"
+	"github.com/huyhvq/eurofxref/pkg/fx"
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const DefaultBaseValue = 100
+
+var (
+	ErrInvalidBasket = errors.New("invalid basket")
+	namePattern      = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+)
+
+// Normalize upper-cases the currencies of b and checks its definition. A
+// weights basket without a base value is worth DefaultBaseValue EUR on its
+// base date.
+func Normalize(b *model.Basket) error {
+	if !namePattern.MatchString(b.Name) || len(b.Components) == 0 {
+		return ErrInvalidBasket
+	}
+	switch b.Kind {
+	case model.BasketUnits:
+		b.BaseDate, b.BaseValue = "", 0
+	case model.BasketWeights:
+		if _, err := time.Parse("2006-01-02", b.BaseDate); err != nil || b.BaseValue < 0 {
+			return ErrInvalidBasket
+		}
+		if b.BaseValue == 0 {
+			b.BaseValue = DefaultBaseValue
+		}
+	default:
+		return ErrInvalidBasket
+	}
+	seen := make(map[string]struct{}, len(b.Components))
+	for i := range b.Components {
+		c := &b.Components[i]
+		c.Currency = strings.ToUpper(strings.TrimSpace(c.Currency))
+		if _, ok := seen[c.Currency]; ok || len(c.Currency) != 3 {
+			return ErrInvalidBasket
+		}
+		seen[c.Currency] = struct{}{}
+		if (b.Kind == model.BasketUnits && c.Units <= 0) || (b.Kind == model.BasketWeights && c.Weight <= 0) {
+			return ErrInvalidBasket
+		}
+	}
+	return nil
+}
+
+// Resolve fixes the units of a weights basket from t, the rates of its base
+// date, so that each currency makes up its share of the base value. Units
+// baskets are only checked against t.
+func Resolve(b *model.Basket, t fx.Table) error {
+	var total float64
+	for _, c := range b.Components {
+		if _, err := t.Leg(c.Currency); err != nil {
+			return err
+		}
+		total += c.Weight
+	}
+	if b.Kind != model.BasketWeights {
+		return nil
+	}
+	for i := range b.Components {
+		c := &b.Components[i]
+		c.Units = c.Weight / total * b.BaseValue * t[c.Currency]
+	}
+	return nil
+}
+
+// Value returns the value of b in base at the rates of t together with the
+// value of each component, in the order of the components.
+func Value(b model.Basket, t fx.Table, base string) (float64, []float64, error) {
+	var total float64
+	values := make([]float64, len(b.Components))
+	for i, c := range b.Components {
+		v, _, err := t.Convert(c.Units, c.Currency, base)
+		if err != nil {
+			return 0, nil, err
+		}
+		values[i] = v
+		total += v
+	}
+	return total, values, nil
+}
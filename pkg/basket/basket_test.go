@@ -0,0 +1,77 @@
+package basket
+
+import (
+	"github.com/huyhvq/eurofxref/pkg/fx"
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+var table = fx.Table{"EUR": 1, "USD": 1.25, "GBP": 0.8, "JPY": 125}
+
+func TestNormalize(t *testing.T) {
+	b := model.Basket{
+		Name:       "REVENUE",
+		Kind:       model.BasketWeights,
+		BaseDate:   "2021-01-04",
+		Components: []model.BasketComponent{{Currency: "usd", Weight: 3}, {Currency: " gbp", Weight: 1}},
+	}
+	assert.Nil(t, Normalize(&b))
+	assert.Equal(t, float64(DefaultBaseValue), b.BaseValue)
+	assert.Equal(t, "USD", b.Components[0].Currency)
+	assert.Equal(t, "GBP", b.Components[1].Currency)
+
+	u := model.Basket{Name: "SDR", Kind: model.BasketUnits, BaseDate: "2021-01-04", BaseValue: 5,
+		Components: []model.BasketComponent{{Currency: "USD", Units: 0.57813}}}
+	assert.Nil(t, Normalize(&u))
+	assert.Equal(t, "", u.BaseDate)
+	assert.Equal(t, 0.0, u.BaseValue)
+
+	for _, invalid := range []model.Basket{
+		{Name: "no spaces", Kind: model.BasketUnits, Components: []model.BasketComponent{{Currency: "USD", Units: 1}}},
+		{Name: "EMPTY", Kind: model.BasketUnits},
+		{Name: "KIND", Kind: "other", Components: []model.BasketComponent{{Currency: "USD", Units: 1}}},
+		{Name: "DATE", Kind: model.BasketWeights, Components: []model.BasketComponent{{Currency: "USD", Weight: 1}}},
+		{Name: "DUP", Kind: model.BasketUnits, Components: []model.BasketComponent{{Currency: "USD", Units: 1}, {Currency: "usd", Units: 1}}},
+		{Name: "ZERO", Kind: model.BasketUnits, Components: []model.BasketComponent{{Currency: "USD", Weight: 1}}},
+		{Name: "CODE", Kind: model.BasketUnits, Components: []model.BasketComponent{{Currency: "US", Units: 1}}},
+	} {
+		assert.Equal(t, ErrInvalidBasket, Normalize(&invalid), invalid.Name)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	b := model.Basket{
+		Kind:       model.BasketWeights,
+		BaseValue:  100,
+		Components: []model.BasketComponent{{Currency: "USD", Weight: 3}, {Currency: "GBP", Weight: 1}},
+	}
+	assert.Nil(t, Resolve(&b, table))
+	assert.InDelta(t, 93.75, b.Components[0].Units, 1e-9)
+	assert.InDelta(t, 20, b.Components[1].Units, 1e-9)
+
+	v, _, err := Value(b, table, "EUR")
+	assert.Nil(t, err)
+	assert.InDelta(t, 100, v, 1e-9)
+
+	b.Components = append(b.Components, model.BasketComponent{Currency: "XXX", Weight: 1})
+	assert.Equal(t, fx.ErrUnknownCurrency, Resolve(&b, table))
+}
+
+func TestValue(t *testing.T) {
+	b := model.Basket{
+		Kind:       model.BasketUnits,
+		Components: []model.BasketComponent{{Currency: "USD", Units: 1.25}, {Currency: "JPY", Units: 250}},
+	}
+	v, values, err := Value(b, table, "EUR")
+	assert.Nil(t, err)
+	assert.InDelta(t, 3, v, 1e-9)
+	assert.InDeltaSlice(t, []float64{1, 2}, values, 1e-9)
+
+	v, _, err = Value(b, table, "USD")
+	assert.Nil(t, err)
+	assert.InDelta(t, 3.75, v, 1e-9)
+
+	_, _, err = Value(b, table, "XXX")
+	assert.Equal(t, fx.ErrUnknownCurrency, err)
+}
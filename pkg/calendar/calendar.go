@@ -0,0 +1,121 @@
+// Package calendar models the TARGET closing days on which the ECB does not
+// publish reference rates.
+package calendar
+
+import (
+	"sort"
+	"time"
+)
+
+type Holiday struct {
+	Date time.Time
+	Name string
+}
+
+// Easter returns Easter Sunday of the given year in the Gregorian calendar
+// (anonymous Gregorian algorithm).
+func Easter(year int) time.Time {
+	a := year % 19
+	b := year / 100
+	c := year % 100
+	d := b / 4
+	e := b % 4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i := c / 4
+	k := c % 4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
+
+// Holidays returns the TARGET closing days of year in date order.
+func Holidays(year int) []Holiday {
+	easter := Easter(year)
+	hs := []Holiday{
+		{Date: date(year, time.January, 1), Name: "New Year's Day"},
+		{Date: easter.AddDate(0, 0, -2), Name: "Good Friday"},
+		{Date: easter.AddDate(0, 0, 1), Name: "Easter Monday"},
+		{Date: date(year, time.May, 1), Name: "Labour Day"},
+		{Date: date(year, time.December, 25), Name: "Christmas Day"},
+		{Date: date(year, time.December, 26), Name: "Christmas Holiday"},
+	}
+	sort.Slice(hs, func(i, j int) bool { return hs[i].Date.Before(hs[j].Date) })
+	return hs
+}
+
+// HolidayName returns the name of the TARGET closing day falling on d, if any.
+func HolidayName(d time.Time) (string, bool) {
+	d = Truncate(d)
+	for _, h := range Holidays(d.Year()) {
+		if h.Date.Equal(d) {
+			return h.Name, true
+		}
+	}
+	return "", false
+}
+
+func IsWeekend(d time.Time) bool {
+	wd := d.Weekday()
+	return wd == time.Saturday || wd == time.Sunday
+}
+
+// IsPublicationDay reports whether the ECB publishes reference rates on d.
+func IsPublicationDay(d time.Time) bool {
+	if IsWeekend(d) {
+		return false
+	}
+	_, ok := HolidayName(d)
+	return !ok
+}
+
+// Previous returns the last publication day strictly before d.
+func Previous(d time.Time) time.Time {
+	d = Truncate(d).AddDate(0, 0, -1)
+	for !IsPublicationDay(d) {
+		d = d.AddDate(0, 0, -1)
+	}
+	return d
+}
+
+// Next returns the first publication day strictly after d.
+func Next(d time.Time) time.Time {
+	d = Truncate(d).AddDate(0, 0, 1)
+	for !IsPublicationDay(d) {
+		d = d.AddDate(0, 0, 1)
+	}
+	return d
+}
+
+// OnOrBefore returns d if it is a publication day, otherwise the previous one.
+func OnOrBefore(d time.Time) time.Time {
+	d = Truncate(d)
+	if IsPublicationDay(d) {
+		return d
+	}
+	return Previous(d)
+}
+
+// PublicationDays returns every publication day between start and end
+// inclusive.
+func PublicationDays(start, end time.Time) []time.Time {
+	days := make([]time.Time, 0)
+	for d := Truncate(start); !d.After(Truncate(end)); d = d.AddDate(0, 0, 1) {
+		if IsPublicationDay(d) {
+			days = append(days, d)
+		}
+	}
+	return days
+}
+
+// Truncate strips the time of day, returning midnight UTC of d's date.
+func Truncate(d time.Time) time.Time {
+	return date(d.Year(), d.Month(), d.Day())
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
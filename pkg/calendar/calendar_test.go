@@ -0,0 +1,63 @@
+package calendar
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func d(s string) time.Time {
+	t, _ := time.ParseInLocation("2006-01-02", s, time.UTC)
+	return t
+}
+
+func TestEaster(t *testing.T) {
+	for year, expected := range map[int]string{
+		2000: "2000-04-23",
+		2008: "2008-03-23",
+		2019: "2019-04-21",
+		2021: "2021-04-04",
+		2024: "2024-03-31",
+		2038: "2038-04-25",
+	} {
+		assert.Equal(t, d(expected), Easter(year), "easter %d", year)
+	}
+}
+
+func TestHolidays(t *testing.T) {
+	hs := Holidays(2021)
+	dates := make([]time.Time, 0, len(hs))
+	for _, h := range hs {
+		dates = append(dates, h.Date)
+	}
+	assert.Equal(t, []time.Time{
+		d("2021-01-01"), d("2021-04-02"), d("2021-04-05"), d("2021-05-01"), d("2021-12-25"), d("2021-12-26"),
+	}, dates)
+	assert.Equal(t, "Good Friday", hs[1].Name)
+}
+
+func TestIsPublicationDay(t *testing.T) {
+	assert.True(t, IsPublicationDay(d("2021-03-25")))
+	assert.False(t, IsPublicationDay(d("2021-03-27")), "saturday")
+	assert.False(t, IsPublicationDay(d("2021-03-28")), "sunday")
+	assert.False(t, IsPublicationDay(d("2021-04-02")), "good friday")
+	assert.False(t, IsPublicationDay(d("2021-04-05")), "easter monday")
+	assert.False(t, IsPublicationDay(time.Date(2020, 12, 25, 15, 0, 0, 0, time.UTC)), "christmas")
+	name, ok := HolidayName(d("2020-05-01"))
+	assert.True(t, ok)
+	assert.Equal(t, "Labour Day", name)
+}
+
+func TestPreviousNext(t *testing.T) {
+	assert.Equal(t, d("2021-04-01"), Previous(d("2021-04-06")))
+	assert.Equal(t, d("2021-04-06"), Next(d("2021-04-01")))
+	assert.Equal(t, d("2020-12-31"), Previous(d("2021-01-04")))
+	assert.Equal(t, d("2021-03-26"), OnOrBefore(d("2021-03-28")))
+	assert.Equal(t, d("2021-03-25"), OnOrBefore(d("2021-03-25")))
+}
+
+func TestPublicationDays(t *testing.T) {
+	days := PublicationDays(d("2021-03-31"), d("2021-04-07"))
+	assert.Equal(t, []time.Time{d("2021-03-31"), d("2021-04-01"), d("2021-04-06"), d("2021-04-07")}, days)
+	assert.Equal(t, 0, len(PublicationDays(d("2021-04-07"), d("2021-04-06"))))
+}
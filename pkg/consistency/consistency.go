@@ -0,0 +1,101 @@
+// Package consistency compares the stored publication dates with the TARGET
+// calendar to find dates that were never stored or only partially stored.
+package consistency
+
+import (
+	"github.com/huyhvq/eurofxref/pkg/calendar"
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"time"
+)
+
+type Report struct {
+	Start        string        `json:"start"`
+	End          string        `json:"end"`
+	StoredDates  int           `json:"stored_dates"`
+	MissingDates []string      `json:"missing_dates"`
+	PartialDates []PartialDate `json:"partial_dates"`
+}
+
+// PartialDate is a stored date with fewer currencies than the stored dates
+// around it.
+type PartialDate struct {
+	Date       string `json:"date"`
+	Currencies int    `json:"currencies"`
+	Expected   int    `json:"expected"`
+}
+
+func (r Report) HasGaps() bool {
+	return len(r.MissingDates) > 0 || len(r.PartialDates) > 0
+}
+
+// Check builds the report for [start, end] from per-date currency counts
+// ordered by date. A publication day without any count is missing. A date is
+// partial when it has fewer currencies than both of its stored neighbours, so
+// a currency that stops being published does not flag every later date.
+func Check(counts []model.DateCount, start, end time.Time) Report {
+	r := Report{
+		Start:        start.Format("2006-01-02"),
+		End:          end.Format("2006-01-02"),
+		StoredDates:  len(counts),
+		MissingDates: make([]string, 0),
+		PartialDates: make([]PartialDate, 0),
+	}
+	stored := make(map[string]struct{}, len(counts))
+	for _, c := range counts {
+		stored[c.Time] = struct{}{}
+	}
+	for _, d := range calendar.PublicationDays(start, end) {
+		ds := d.Format("2006-01-02")
+		if _, ok := stored[ds]; !ok {
+			r.MissingDates = append(r.MissingDates, ds)
+		}
+	}
+	for i, c := range counts {
+		expected := 0
+		switch {
+		case i > 0 && i < len(counts)-1:
+			expected = min(counts[i-1].Count, counts[i+1].Count)
+		case i > 0:
+			expected = counts[i-1].Count
+		case i < len(counts)-1:
+			expected = counts[i+1].Count
+		}
+		if c.Count < expected {
+			r.PartialDates = append(r.PartialDates, PartialDate{
+				Date:       c.Time,
+				Currencies: c.Count,
+				Expected:   expected,
+			})
+		}
+	}
+	return r
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// GapRange returns the smallest range covering every missing and partial date.
+func (r Report) GapRange() (time.Time, time.Time) {
+	var start, end time.Time
+	dates := append([]string{}, r.MissingDates...)
+	for _, p := range r.PartialDates {
+		dates = append(dates, p.Date)
+	}
+	for _, ds := range dates {
+		d, err := time.ParseInLocation("2006-01-02", ds, time.UTC)
+		if err != nil {
+			continue
+		}
+		if start.IsZero() || d.Before(start) {
+			start = d
+		}
+		if d.After(end) {
+			end = d
+		}
+	}
+	return start, end
+}
@@ -0,0 +1,64 @@
+package consistency
+
+import (
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func d(s string) time.Time {
+	t, _ := time.ParseInLocation("2006-01-02", s, time.UTC)
+	return t
+}
+
+func TestCheck(t *testing.T) {
+	counts := []model.DateCount{
+		{Time: "2021-03-29", Count: 32},
+		{Time: "2021-03-30", Count: 32},
+		{Time: "2021-04-01", Count: 30},
+		{Time: "2021-04-06", Count: 32},
+		{Time: "2021-04-07", Count: 31},
+		{Time: "2021-04-08", Count: 31},
+	}
+	r := Check(counts, d("2021-03-29"), d("2021-04-08"))
+	assert.Equal(t, "2021-03-29", r.Start)
+	assert.Equal(t, "2021-04-08", r.End)
+	assert.Equal(t, 6, r.StoredDates)
+	assert.Equal(t, []string{"2021-03-31"}, r.MissingDates)
+	assert.Equal(t, []PartialDate{{Date: "2021-04-01", Currencies: 30, Expected: 32}}, r.PartialDates)
+	assert.True(t, r.HasGaps())
+}
+
+func TestCheck_Boundaries(t *testing.T) {
+	counts := []model.DateCount{
+		{Time: "2021-03-29", Count: 2},
+		{Time: "2021-03-30", Count: 32},
+		{Time: "2021-03-31", Count: 20},
+	}
+	r := Check(counts, d("2021-03-29"), d("2021-03-31"))
+	assert.Equal(t, 0, len(r.MissingDates))
+	assert.Equal(t, []PartialDate{
+		{Date: "2021-03-29", Currencies: 2, Expected: 32},
+		{Date: "2021-03-31", Currencies: 20, Expected: 32},
+	}, r.PartialDates)
+
+	r = Check([]model.DateCount{{Time: "2021-03-29", Count: 32}}, d("2021-03-27"), d("2021-03-29"))
+	assert.False(t, r.HasGaps())
+	assert.NotNil(t, r.MissingDates)
+	assert.NotNil(t, r.PartialDates)
+}
+
+func TestReport_GapRange(t *testing.T) {
+	r := Report{
+		MissingDates: []string{"2021-03-31", "2021-04-12"},
+		PartialDates: []PartialDate{{Date: "2021-03-02"}},
+	}
+	start, end := r.GapRange()
+	assert.Equal(t, d("2021-03-02"), start)
+	assert.Equal(t, d("2021-04-12"), end)
+
+	start, end = Report{}.GapRange()
+	assert.True(t, start.IsZero())
+	assert.True(t, end.IsZero())
+}
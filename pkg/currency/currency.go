@@ -0,0 +1,57 @@
+// Package currency holds the ISO 4217 details of every currency the ECB has
+// published reference rates for, the legacy currencies replaced by the euro
+// and the common pegged currencies, together with the fixed rates the
+// non-published ones are priced at.
+package currency
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed iso4217.csv
+var dataset string
+
+type Currency struct {
+	Code       string
+	Numeric    string
+	MinorUnits int
+	Name       string
+}
+
+var currencies = load(dataset)
+
+func load(data string) map[string]Currency {
+	records, err := csv.NewReader(strings.NewReader(data)).ReadAll()
+	if err != nil {
+		panic("currency: invalid dataset: " + err.Error())
+	}
+	cs := make(map[string]Currency, len(records))
+	for _, r := range records[1:] {
+		units, err := strconv.Atoi(r[2])
+		if err != nil {
+			panic("currency: invalid minor units for " + r[0])
+		}
+		cs[r[0]] = Currency{Code: r[0], Numeric: r[1], MinorUnits: units, Name: r[3]}
+	}
+	return cs
+}
+
+// Lookup returns the details of the currency with the given code.
+func Lookup(code string) (Currency, bool) {
+	c, ok := currencies[strings.ToUpper(code)]
+	return c, ok
+}
+
+// All returns every currency of the dataset ordered by code.
+func All() []Currency {
+	cs := make([]Currency, 0, len(currencies))
+	for _, c := range currencies {
+		cs = append(cs, c)
+	}
+	sort.Slice(cs, func(i, j int) bool { return cs[i].Code < cs[j].Code })
+	return cs
+}
@@ -0,0 +1,95 @@
+package currency
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	c, ok := Lookup("usd")
+	assert.True(t, ok)
+	assert.Equal(t, Currency{Code: "USD", Numeric: "840", MinorUnits: 2, Name: "US Dollar"}, c)
+
+	c, ok = Lookup("AUD")
+	assert.True(t, ok)
+	assert.Equal(t, "036", c.Numeric)
+
+	c, _ = Lookup("JPY")
+	assert.Equal(t, 0, c.MinorUnits)
+
+	_, ok = Lookup("XXX")
+	assert.False(t, ok)
+}
+
+func TestAll(t *testing.T) {
+	cs := All()
+	assert.Equal(t, "AED", cs[0].Code)
+	assert.Equal(t, "ZAR", cs[len(cs)-1].Code)
+	for _, c := range cs {
+		assert.Len(t, c.Code, 3)
+		assert.Len(t, c.Numeric, 3)
+		assert.NotEmpty(t, c.Name)
+	}
+}
+
+func TestFixed(t *testing.T) {
+	assert.Empty(t, Fixed("1998-12-31"))
+	assert.Len(t, Fixed("1999-01-01"), 11)
+	assert.Len(t, Fixed("1999-01-01T00:00:00Z"), 11)
+
+	codes := make(map[string]float64)
+	for _, l := range Fixed("2022-12-30") {
+		codes[l.Code] = l.Rate
+	}
+	assert.Equal(t, 1.95583, codes["DEM"])
+	assert.Equal(t, 3.4528, codes["LTL"])
+	assert.NotContains(t, codes, "HRK")
+
+	l, ok := LookupLegacy("HRK")
+	assert.True(t, ok)
+	assert.Equal(t, "2023-01-01", l.Adopted)
+	for _, l := range Fixed("2030-01-01") {
+		_, ok := Lookup(l.Code)
+		assert.True(t, ok, l.Code)
+	}
+}
+
+func TestNewPegs(t *testing.T) {
+	_, err := NewPegs([]Derived{{Code: "AED", Peg: "USD", Ratio: 0}})
+	assert.True(t, errors.Is(err, ErrInvalidDerived))
+	_, err = NewPegs([]Derived{{Code: "AED", Peg: "USD", Ratio: 3.6725, ValidFrom: "1997-13-01"}})
+	assert.True(t, errors.Is(err, ErrInvalidDerived))
+	_, err = NewPegs([]Derived{{Code: "AED", Peg: "USD", Ratio: 3.6725, ValidFrom: "2000-01-01", ValidTo: "1999-01-01"}})
+	assert.True(t, errors.Is(err, ErrInvalidDerived))
+
+	pegs, err := NewPegs([]Derived{
+		{Code: "xof", Peg: "eur", Ratio: 655.957},
+		{Code: "AED", Peg: "USD", Ratio: 3.6725, ValidFrom: "1997-11-01"},
+		{Code: "ABC", Peg: "USD", Ratio: 2, ValidTo: "2009-12-31"},
+		{Code: "ABC", Peg: "USD", Ratio: 3, ValidFrom: "2010-01-01"},
+	})
+	assert.Nil(t, err)
+	ps := pegs.On("2021-03-26")
+	assert.Len(t, ps, 3)
+	assert.Equal(t, Derived{Code: "ABC", Peg: "USD", Ratio: 3, ValidFrom: "2010-01-01"}, ps[0])
+	assert.Equal(t, "XOF", ps[2].Code)
+	assert.Equal(t, "EUR", ps[2].Peg)
+	assert.Len(t, pegs.On("1997-01-01"), 2)
+	assert.Len(t, pegs.All(), 4)
+	// Dates scanned from the database carry a time.
+	assert.Equal(t, 2.0, pegs.On("2009-12-31T00:00:00Z")[0].Ratio)
+	assert.Len(t, pegs.On("1997-11-01T00:00:00Z"), 3)
+
+	assert.True(t, pegs.IsDerived("AED", "2021-03-26"))
+	assert.True(t, pegs.IsDerived("AED", "1997-11-01T00:00:00Z"))
+	assert.False(t, pegs.IsDerived("AED", "1997-10-31"))
+	assert.True(t, pegs.IsDerived("DEM", "2021-03-26"))
+	assert.False(t, pegs.IsDerived("HRK", "2022-12-30"))
+	assert.True(t, pegs.IsDerived("HRK", "2023-01-01T00:00:00Z"))
+	assert.False(t, pegs.IsDerived("USD", "2021-03-26"))
+
+	var none Pegs
+	assert.Empty(t, none.On("2021-03-26"))
+	assert.True(t, none.IsDerived("DEM", "2021-03-26"))
+}
@@ -0,0 +1,108 @@
+package currency
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrInvalidDerived = errors.New("invalid derived currency")
+
+// Derived is a currency the ECB does not publish, quoted at a fixed ratio to
+// one it does, e.g. AED at 3.6725 per USD. ValidFrom and ValidTo bound the
+// peg inclusively; empty means unbounded.
+type Derived struct {
+	Code      string  `mapstructure:"code"`
+	Peg       string  `mapstructure:"peg"`
+	Ratio     float64 `mapstructure:"ratio"`
+	ValidFrom string  `mapstructure:"valid_from"`
+	ValidTo   string  `mapstructure:"valid_to"`
+}
+
+// Pegs holds the derived currencies by code. A code may be defined several
+// times with different validity ranges, e.g. after a re-peg. The zero value
+// holds none.
+type Pegs map[string][]Derived
+
+// NewPegs validates ds and indexes them by code.
+func NewPegs(ds []Derived) (Pegs, error) {
+	p := make(Pegs, len(ds))
+	for _, d := range ds {
+		d.Code = strings.ToUpper(strings.TrimSpace(d.Code))
+		d.Peg = strings.ToUpper(strings.TrimSpace(d.Peg))
+		if len(d.Code) != 3 || len(d.Peg) != 3 || d.Code == d.Peg || d.Ratio <= 0 {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidDerived, d.Code)
+		}
+		for _, v := range []string{d.ValidFrom, d.ValidTo} {
+			if _, err := time.Parse("2006-01-02", v); v != "" && err != nil {
+				return nil, fmt.Errorf("%w: %q", ErrInvalidDerived, d.Code)
+			}
+		}
+		if d.ValidFrom != "" && d.ValidTo != "" && d.ValidFrom > d.ValidTo {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidDerived, d.Code)
+		}
+		p[d.Code] = append(p[d.Code], d)
+	}
+	return p, nil
+}
+
+// day returns the 2006-01-02 part of date, which may carry a time as
+// dates scanned from the database do.
+func day(date string) string {
+	if len(date) > len("2006-01-02") {
+		return date[:len("2006-01-02")]
+	}
+	return date
+}
+
+func (d Derived) validOn(date string) bool {
+	date = day(date)
+	return (d.ValidFrom == "" || d.ValidFrom <= date) && (d.ValidTo == "" || date <= d.ValidTo)
+}
+
+// On returns the derived currencies valid on date ordered by code.
+func (p Pegs) On(date string) []Derived {
+	ds := make([]Derived, 0, len(p))
+	for _, defs := range p {
+		for _, d := range defs {
+			if d.validOn(date) {
+				ds = append(ds, d)
+				break
+			}
+		}
+	}
+	sort.Slice(ds, func(i, j int) bool { return ds[i].Code < ds[j].Code })
+	return ds
+}
+
+// All returns every definition ordered by code and start of validity.
+func (p Pegs) All() []Derived {
+	ds := make([]Derived, 0, len(p))
+	for _, defs := range p {
+		ds = append(ds, defs...)
+	}
+	sort.Slice(ds, func(i, j int) bool {
+		if ds[i].Code != ds[j].Code {
+			return ds[i].Code < ds[j].Code
+		}
+		return ds[i].ValidFrom < ds[j].ValidFrom
+	})
+	return ds
+}
+
+// IsDerived reports whether code is priced on date from a fixed rate rather
+// than an ECB reference rate, either as a legacy currency after its euro
+// adoption or as a peg of p.
+func (p Pegs) IsDerived(code, date string) bool {
+	if l, ok := legacy[code]; ok && l.Adopted <= day(date) {
+		return true
+	}
+	for _, d := range p[code] {
+		if d.validOn(date) {
+			return true
+		}
+	}
+	return false
+}
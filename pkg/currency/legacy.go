@@ -0,0 +1,54 @@
+package currency
+
+// Legacy is a national currency replaced by the euro at an irrevocably fixed
+// conversion rate.
+type Legacy struct {
+	Code    string
+	Rate    float64
+	Adopted string
+}
+
+// legacy holds the units of each national currency per one EUR as fixed by
+// the Council of the EU, and the first day the euro was legal tender.
+var legacy = map[string]Legacy{
+	"ATS": {Code: "ATS", Rate: 13.7603, Adopted: "1999-01-01"},
+	"BEF": {Code: "BEF", Rate: 40.3399, Adopted: "1999-01-01"},
+	"DEM": {Code: "DEM", Rate: 1.95583, Adopted: "1999-01-01"},
+	"ESP": {Code: "ESP", Rate: 166.386, Adopted: "1999-01-01"},
+	"FIM": {Code: "FIM", Rate: 5.94573, Adopted: "1999-01-01"},
+	"FRF": {Code: "FRF", Rate: 6.55957, Adopted: "1999-01-01"},
+	"IEP": {Code: "IEP", Rate: 0.787564, Adopted: "1999-01-01"},
+	"ITL": {Code: "ITL", Rate: 1936.27, Adopted: "1999-01-01"},
+	"LUF": {Code: "LUF", Rate: 40.3399, Adopted: "1999-01-01"},
+	"NLG": {Code: "NLG", Rate: 2.20371, Adopted: "1999-01-01"},
+	"PTE": {Code: "PTE", Rate: 200.482, Adopted: "1999-01-01"},
+	"GRD": {Code: "GRD", Rate: 340.75, Adopted: "2001-01-01"},
+	"SIT": {Code: "SIT", Rate: 239.64, Adopted: "2007-01-01"},
+	"CYP": {Code: "CYP", Rate: 0.585274, Adopted: "2008-01-01"},
+	"MTL": {Code: "MTL", Rate: 0.4293, Adopted: "2008-01-01"},
+	"SKK": {Code: "SKK", Rate: 30.126, Adopted: "2009-01-01"},
+	"EEK": {Code: "EEK", Rate: 15.6466, Adopted: "2011-01-01"},
+	"LVL": {Code: "LVL", Rate: 0.702804, Adopted: "2014-01-01"},
+	"LTL": {Code: "LTL", Rate: 3.4528, Adopted: "2015-01-01"},
+	"HRK": {Code: "HRK", Rate: 7.5345, Adopted: "2023-01-01"},
+	"BGN": {Code: "BGN", Rate: 1.95583, Adopted: "2026-01-01"},
+}
+
+// Fixed returns the legacy currencies already replaced by the euro on date,
+// formatted as 2006-01-02.
+func Fixed(date string) []Legacy {
+	date = day(date)
+	ls := make([]Legacy, 0, len(legacy))
+	for _, l := range legacy {
+		if l.Adopted <= date {
+			ls = append(ls, l)
+		}
+	}
+	return ls
+}
+
+// LookupLegacy returns the fixed conversion of a legacy currency.
+func LookupLegacy(code string) (Legacy, bool) {
+	l, ok := legacy[code]
+	return l, ok
+}
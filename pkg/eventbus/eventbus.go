@@ -0,0 +1,135 @@
+package eventbus
+
+import (
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"github.com/huyhvq/eurofxref/pkg/repository"
+	"github.com/huyhvq/eurofxref/pkg/syncer"
+	"log"
+	"sync"
+	"time"
+)
+
+// subscriberBuffer is how many events a subscriber may lag behind before it
+// is dropped.
+const subscriberBuffer = 16
+
+// Event announces the rates of a newly stored publication date. Its ID is the
+// date as YYYYMMDD, so IDs increase across restarts but are not consecutive.
+type Event struct {
+	ID          uint64
+	Date        string
+	Rates       []model.Rate
+	PublishedAt time.Time
+}
+
+// Bus fans events out to subscribers and keeps the latest ones so that a
+// reconnecting subscriber can catch up.
+type Bus struct {
+	mu      sync.Mutex
+	size    int
+	last    uint64
+	dropped uint64
+	history []Event
+	subs    map[chan Event]struct{}
+}
+
+// New returns a bus that keeps the last size events for replay. last is the
+// ID of the latest date stored before the bus started; events up to it are
+// not kept.
+func New(size int, last uint64) *Bus {
+	return &Bus{size: size, last: last, dropped: last, subs: make(map[chan Event]struct{})}
+}
+
+// ID returns the event ID of the publication date d, or zero for the zero
+// time.
+func ID(d time.Time) uint64 {
+	if d.IsZero() {
+		return 0
+	}
+	return uint64(d.Year()*10000 + int(d.Month())*100 + d.Day())
+}
+
+// Publish delivers the rates of date as event id to every subscriber. An id
+// not above the last one is not published, so that a client never skips an
+// event it has not seen; ok is false then. A subscriber that has fallen
+// behind is dropped by closing its channel.
+func (b *Bus) Publish(id uint64, date string, rates []model.Rate) (e Event, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if id <= b.last {
+		return Event{}, false
+	}
+	b.last = id
+	e = Event{ID: id, Date: date, Rates: rates, PublishedAt: time.Now().UTC()}
+	b.history = append(b.history, e)
+	if n := len(b.history) - b.size; n > 0 {
+		b.dropped = b.history[n-1].ID
+		b.history = b.history[n:]
+	}
+	for ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+	return e, true
+}
+
+// Subscribe returns the kept events after lastID and a channel of the events
+// published from now on. complete is false when events after lastID are no
+// longer kept, or lastID is above any ID issued. cancel must be called once
+// the subscriber is done.
+func (b *Bus) Subscribe(lastID uint64) (missed []Event, complete bool, events <-chan Event, cancel func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	complete = lastID >= b.dropped && lastID <= b.last
+	for _, e := range b.history {
+		if e.ID > lastID {
+			missed = append(missed, e)
+		}
+	}
+	ch := make(chan Event, subscriberBuffer)
+	b.subs[ch] = struct{}{}
+	cancel = func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subs[ch]; ok {
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+	return missed, complete, ch, cancel
+}
+
+// LastID returns the ID of the latest event, or the last ID given to New
+// before the first.
+func (b *Bus) LastID() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.last
+}
+
+// Listener publishes the rates of every new publication date a sync stores,
+// read back through rates so that overrides apply. Backfilled dates are not
+// streamed; a date at or before the last one published is logged and
+// skipped.
+func Listener(b *Bus, rates repository.RateRepository) syncer.Listener {
+	return func(ev syncer.Event) {
+		for _, d := range ev.NewDates {
+			t, err := time.ParseInLocation("2006-01-02", d, time.UTC)
+			if err != nil {
+				continue
+			}
+			rs, err := rates.GetRatesByDate(t)
+			if err != nil {
+				log.Println("eventbus: loading rates failed:", err)
+				continue
+			}
+			if _, ok := b.Publish(ID(t), d, rs); !ok {
+				log.Println("eventbus: skipping date not after the last one published:", d)
+			}
+		}
+	}
+}
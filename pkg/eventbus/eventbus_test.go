@@ -0,0 +1,135 @@
+package eventbus
+
+import (
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"github.com/huyhvq/eurofxref/pkg/repository"
+	"github.com/huyhvq/eurofxref/pkg/syncer"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type mockRates struct {
+	repository.RateRepository
+}
+
+func (mockRates) GetRatesByDate(date time.Time) ([]model.Rate, error) {
+	return []model.Rate{{Time: date.Format("2006-01-02"), Currency: "USD", Rate: 1.18}}, nil
+}
+
+func TestID(t *testing.T) {
+	assert.Equal(t, uint64(20210331), ID(time.Date(2021, 3, 31, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, uint64(0), ID(time.Time{}))
+}
+
+func TestBus_Subscribe(t *testing.T) {
+	b := New(2, 20210326)
+	missed, complete, events, cancel := b.Subscribe(20210326)
+	assert.Empty(t, missed)
+	assert.True(t, complete)
+
+	b.Publish(20210329, "2021-03-29", nil)
+	e := <-events
+	assert.Equal(t, uint64(20210329), e.ID)
+	assert.Equal(t, "2021-03-29", e.Date)
+	cancel()
+	cancel()
+	_, ok := <-events
+	assert.False(t, ok)
+
+	b.Publish(20210330, "2021-03-30", nil)
+	b.Publish(20210331, "2021-03-31", nil)
+	assert.Equal(t, uint64(20210331), b.LastID())
+
+	missed, complete, _, cancel = b.Subscribe(20210329)
+	defer cancel()
+	assert.True(t, complete)
+	assert.Equal(t, []uint64{20210330, 20210331}, ids(missed))
+
+	missed, complete, _, cancel = b.Subscribe(20210331)
+	defer cancel()
+	assert.True(t, complete)
+	assert.Empty(t, missed)
+
+	missed, complete, _, cancel = b.Subscribe(20210326)
+	defer cancel()
+	assert.False(t, complete)
+	assert.Equal(t, []uint64{20210330, 20210331}, ids(missed))
+
+	_, complete, _, cancel = b.Subscribe(20210401)
+	defer cancel()
+	assert.False(t, complete)
+}
+
+func TestBus_Subscribe_afterRestart(t *testing.T) {
+	b := New(2, 20210331)
+	assert.Equal(t, uint64(20210331), b.LastID())
+
+	_, complete, _, cancel := b.Subscribe(20210331)
+	defer cancel()
+	assert.True(t, complete)
+
+	// A date was stored while the client was away and before the bus
+	// started, so its event was never kept.
+	_, complete, _, cancel = b.Subscribe(20210330)
+	defer cancel()
+	assert.False(t, complete)
+}
+
+func TestBus_Publish_keepsIDsIncreasing(t *testing.T) {
+	b := New(2, 20210329)
+	_, ok := b.Publish(20210329, "2021-03-29", nil)
+	assert.False(t, ok)
+	_, ok = b.Publish(20210326, "2021-03-26", nil)
+	assert.False(t, ok)
+	assert.Equal(t, uint64(20210329), b.LastID())
+
+	e, ok := b.Publish(20210330, "2021-03-30", nil)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(20210330), e.ID)
+	missed, _, _, cancel := b.Subscribe(20210329)
+	defer cancel()
+	assert.Equal(t, []uint64{20210330}, ids(missed))
+}
+
+func TestBus_Publish_dropsSlowSubscriber(t *testing.T) {
+	b := New(1, 0)
+	_, _, events, cancel := b.Subscribe(0)
+	defer cancel()
+	for i := 0; i <= subscriberBuffer; i++ {
+		b.Publish(uint64(20210301+i), "2021-03-31", nil)
+	}
+	n := 0
+	for range events {
+		n++
+	}
+	assert.Equal(t, subscriberBuffer, n)
+}
+
+func TestListener(t *testing.T) {
+	b := New(10, 20210329)
+	Listener(b, mockRates{})(syncer.Event{
+		NewDates:        []string{"2021-03-30", "2021-03-31"},
+		BackfilledDates: []string{"2021-03-01"},
+	})
+	missed, _, _, cancel := b.Subscribe(0)
+	defer cancel()
+	assert.Equal(t, []uint64{20210330, 20210331}, ids(missed))
+	assert.Equal(t, "2021-03-31", missed[1].Date)
+	assert.Equal(t, []model.Rate{{Time: "2021-03-31", Currency: "USD", Rate: 1.18}}, missed[1].Rates)
+
+	// A repair backfilling old dates streams nothing.
+	Listener(b, mockRates{})(syncer.Event{
+		Run:             model.SyncRun{TriggeredBy: syncer.TriggerRepair},
+		BackfilledDates: []string{"2021-03-02"},
+	})
+	assert.Equal(t, uint64(20210331), b.LastID())
+}
+
+func ids(events []Event) []uint64 {
+	res := make([]uint64, 0, len(events))
+	for _, e := range events {
+		res = append(res, e.ID)
+	}
+	return res
+}
@@ -0,0 +1,342 @@
+// Package fx derives cross rates from the EUR reference rates.
+package fx
+
+import (
+	"errors"
+	"github.com/huyhvq/eurofxref/pkg/currency"
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"math"
+	"sort"
+	"strings"
+)
+
+const Base = "EUR"
+
+var ErrUnknownCurrency = errors.New("unknown currency")
+
+// Table holds the units of each currency per one EUR. EUR itself is always
+// present with a rate of 1.
+type Table map[string]float64
+
+// NewTable builds the table of the rates of one date.
+func NewTable(rates []model.Rate) Table {
+	t := make(Table, len(rates)+1)
+	t[Base] = 1
+	for _, r := range rates {
+		if r.Rate > 0 {
+			t[r.Currency] = r.Rate
+		}
+	}
+	return t
+}
+
+// WithPegged adds the derived currencies of pegs valid on date whose peg is
+// in the table. Published rates already in the table are kept.
+func (t Table) WithPegged(pegs currency.Pegs, date string) Table {
+	for _, d := range pegs.On(date) {
+		if _, ok := t[d.Code]; ok {
+			continue
+		}
+		if p, ok := t[d.Peg]; ok {
+			t[d.Code] = p * d.Ratio
+		}
+	}
+	return t
+}
+
+// WithFixed adds the irrevocable conversion rates of the legacy currencies
+// the euro had replaced by date. Market rates already in the table, such as
+// those published before adoption, are kept.
+func (t Table) WithFixed(date string) Table {
+	for _, l := range currency.Fixed(date) {
+		if _, ok := t[l.Code]; !ok {
+			t[l.Code] = l.Rate
+		}
+	}
+	return t
+}
+
+// Leg returns the EUR rate of currency.
+func (t Table) Leg(currency string) (float64, error) {
+	r, ok := t[strings.ToUpper(currency)]
+	if !ok {
+		return 0, ErrUnknownCurrency
+	}
+	return r, nil
+}
+
+// Cross returns how many units of quote one unit of base buys, triangulated
+// through EUR.
+func (t Table) Cross(base, quote string) (float64, error) {
+	b, err := t.Leg(base)
+	if err != nil {
+		return 0, err
+	}
+	q, err := t.Leg(quote)
+	if err != nil {
+		return 0, err
+	}
+	return q / b, nil
+}
+
+// Convert converts amount from one currency into another.
+func (t Table) Convert(amount float64, from, to string) (float64, float64, error) {
+	rate, err := t.Cross(from, to)
+	if err != nil {
+		return 0, 0, err
+	}
+	return amount * rate, rate, nil
+}
+
+// Rebase returns every rate in the table expressed against base.
+func (t Table) Rebase(base string) (map[string]float64, error) {
+	b, err := t.Leg(base)
+	if err != nil {
+		return nil, err
+	}
+	rs := make(map[string]float64, len(t))
+	for c, r := range t {
+		rs[c] = r / b
+	}
+	return rs, nil
+}
+
+// Currencies returns the currencies of the table in alphabetical order.
+func (t Table) Currencies() []string {
+	cs := make([]string, 0, len(t))
+	for c := range t {
+		cs = append(cs, c)
+	}
+	sort.Strings(cs)
+	return cs
+}
+
+// Matrix returns the cross rate of every pair of symbols, where m[i][j] is the
+// units of symbols[j] per one unit of symbols[i]. The diagonal is exactly 1
+// and m[j][i] is always the reciprocal of m[i][j] before rounding.
+func (t Table) Matrix(symbols []string) ([][]float64, error) {
+	m := make([][]float64, len(symbols))
+	for i := range symbols {
+		m[i] = make([]float64, len(symbols))
+	}
+	for i := range symbols {
+		m[i][i] = 1
+		for j := i + 1; j < len(symbols); j++ {
+			r, err := t.Cross(symbols[i], symbols[j])
+			if err != nil {
+				return nil, err
+			}
+			m[i][j] = r
+			m[j][i] = 1 / r
+		}
+		if _, err := t.Leg(symbols[i]); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// RoundMatrix rounds a matrix of Matrix in place. Of each mirrored pair the
+// entry of at least 1 is rounded and the other is rounded from its reciprocal,
+// so both come from the one displayed value and the smaller entry, which has
+// the fewest significant digits, is not rounded twice over.
+func RoundMatrix(m [][]float64, decimals int) {
+	for i := range m {
+		for j := i + 1; j < len(m); j++ {
+			big, small := &m[i][j], &m[j][i]
+			if *big < 1 {
+				big, small = small, big
+			}
+			*big = Round(*big, decimals)
+			*small = Round(1 / *big, decimals)
+		}
+	}
+}
+
+// Round rounds v to the given number of decimal places.
+func Round(v float64, decimals int) float64 {
+	p := math.Pow(10, float64(decimals))
+	return math.Round(v*p) / p
+}
+
+type Change struct {
+	StartRate float64
+	EndRate   float64
+	Change    float64
+	ChangePct float64
+}
+
+// Fluctuation compares two tables against base. Without symbols every
+// currency present in both tables is compared.
+func Fluctuation(start, end Table, base string, symbols []string) (map[string]Change, error) {
+	sr, err := start.Rebase(base)
+	if err != nil {
+		return nil, err
+	}
+	er, err := end.Rebase(base)
+	if err != nil {
+		return nil, err
+	}
+	if len(symbols) == 0 {
+		for _, c := range start.Currencies() {
+			if _, ok := er[c]; ok && c != strings.ToUpper(base) {
+				symbols = append(symbols, c)
+			}
+		}
+	}
+	changes := make(map[string]Change, len(symbols))
+	for _, c := range symbols {
+		s, ok := sr[c]
+		if !ok {
+			return nil, ErrUnknownCurrency
+		}
+		e, ok := er[c]
+		if !ok {
+			return nil, ErrUnknownCurrency
+		}
+		changes[c] = Change{
+			StartRate: s,
+			EndRate:   e,
+			Change:    e - s,
+			ChangePct: (e - s) / s * 100,
+		}
+	}
+	return changes, nil
+}
+
+type Point struct {
+	Date string
+	Rate float64
+}
+
+// TimeSeries turns EUR rates ordered by date into one series per symbol
+// expressed against base. Dates on which base or a symbol was not published
+// are left out of that series. Without symbols every published currency except
+// base is returned; legacy currencies are priced at their fixed rates only
+// when asked for. Derived currencies of pegs are priced from their peg.
+func TimeSeries(rates []model.Rate, base string, symbols []string, pegs currency.Pegs) (map[string][]Point, error) {
+	base = strings.ToUpper(base)
+	series := make(map[string][]Point)
+	for _, s := range symbols {
+		series[s] = make([]Point, 0)
+	}
+	seenBase := false
+	for i := 0; i < len(rates); {
+		j := i
+		for j < len(rates) && rates[j].Time == rates[i].Time {
+			j++
+		}
+		date := rates[i].Time
+		table := NewTable(rates[i:j])
+		if len(symbols) > 0 {
+			table.WithFixed(date)
+		}
+		table.WithPegged(pegs, date)
+		rs, err := table.Rebase(base)
+		i = j
+		if err != nil {
+			continue
+		}
+		seenBase = true
+		if len(symbols) == 0 {
+			for c, r := range rs {
+				if c != base {
+					series[c] = append(series[c], Point{Date: date, Rate: r})
+				}
+			}
+			continue
+		}
+		for _, s := range symbols {
+			if r, ok := rs[s]; ok {
+				series[s] = append(series[s], Point{Date: date, Rate: r})
+			}
+		}
+	}
+	if !seenBase && len(rates) > 0 {
+		return nil, ErrUnknownCurrency
+	}
+	return series, nil
+}
+
+// Values returns the rates of points.
+func Values(points []Point) []float64 {
+	vs := make([]float64, len(points))
+	for i, p := range points {
+		vs[i] = p.Rate
+	}
+	return vs
+}
+
+// Align keeps the dates on which every symbol has a point and returns them
+// with the values of each symbol on those dates, in the order of symbols.
+func Align(series map[string][]Point, symbols []string) ([]string, [][]float64) {
+	counts := make(map[string]int)
+	for _, s := range symbols {
+		for _, p := range series[s] {
+			counts[p.Date]++
+		}
+	}
+	dates := make([]string, 0)
+	for d, n := range counts {
+		if n == len(symbols) {
+			dates = append(dates, d)
+		}
+	}
+	sort.Strings(dates)
+	index := make(map[string]int, len(dates))
+	for i, d := range dates {
+		index[d] = i
+	}
+	values := make([][]float64, len(symbols))
+	for i, s := range symbols {
+		values[i] = make([]float64, len(dates))
+		for _, p := range series[s] {
+			if j, ok := index[p.Date]; ok {
+				values[i][j] = p.Rate
+			}
+		}
+	}
+	return dates, values
+}
+
+// History holds one Table per stored date, including fixed legacy rates and
+// the derived currencies, so that many conversions on different dates can be
+// served from a single range of rates.
+type History struct {
+	dates  []string
+	tables map[string]Table
+}
+
+// NewHistory indexes EUR rates ordered by date.
+func NewHistory(rates []model.Rate, pegs currency.Pegs) *History {
+	h := &History{
+		dates:  make([]string, 0),
+		tables: make(map[string]Table),
+	}
+	for i := 0; i < len(rates); {
+		j := i
+		for j < len(rates) && rates[j].Time == rates[i].Time {
+			j++
+		}
+		h.dates = append(h.dates, rates[i].Time)
+		h.tables[rates[i].Time] = NewTable(rates[i:j]).WithFixed(rates[i].Time).WithPegged(pegs, rates[i].Time)
+		i = j
+	}
+	return h
+}
+
+// OnOrBefore returns the table of the latest date not after date together
+// with that date. ok is false when every date is after date.
+func (h *History) OnOrBefore(date string) (Table, string, bool) {
+	i := sort.Search(len(h.dates), func(i int) bool { return h.dates[i] > date })
+	if i == 0 {
+		return nil, "", false
+	}
+	d := h.dates[i-1]
+	return h.tables[d], d, true
+}
+
+// Dates returns the dates of the history in ascending order.
+func (h *History) Dates() []string {
+	return h.dates
+}
@@ -0,0 +1,236 @@
+package fx
+
+import (
+	"github.com/huyhvq/eurofxref/pkg/currency"
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+var table = NewTable([]model.Rate{
+	{Currency: "USD", Rate: 1.25},
+	{Currency: "JPY", Rate: 125},
+	{Currency: "XXX", Rate: 0},
+})
+
+func TestNewTable(t *testing.T) {
+	assert.Equal(t, Table{"EUR": 1, "USD": 1.25, "JPY": 125}, table)
+}
+
+func TestTable_Cross(t *testing.T) {
+	r, err := table.Cross("USD", "JPY")
+	assert.Nil(t, err)
+	assert.InDelta(t, 100, r, 1e-9)
+
+	r, err = table.Cross("jpy", "EUR")
+	assert.Nil(t, err)
+	assert.InDelta(t, 0.008, r, 1e-12)
+
+	_, err = table.Cross("USD", "XXX")
+	assert.Equal(t, ErrUnknownCurrency, err)
+	_, err = table.Cross("GBP", "USD")
+	assert.Equal(t, ErrUnknownCurrency, err)
+}
+
+func TestTable_Convert(t *testing.T) {
+	amount, rate, err := table.Convert(10, "USD", "EUR")
+	assert.Nil(t, err)
+	assert.InDelta(t, 8, amount, 1e-9)
+	assert.InDelta(t, 0.8, rate, 1e-12)
+
+	_, _, err = table.Convert(10, "USD", "GBP")
+	assert.Equal(t, ErrUnknownCurrency, err)
+}
+
+func TestTable_Rebase(t *testing.T) {
+	rs, err := table.Rebase("USD")
+	assert.Nil(t, err)
+	assert.InDelta(t, 1, rs["USD"], 1e-12)
+	assert.InDelta(t, 0.8, rs["EUR"], 1e-12)
+	assert.InDelta(t, 100, rs["JPY"], 1e-9)
+
+	_, err = table.Rebase("GBP")
+	assert.Equal(t, ErrUnknownCurrency, err)
+}
+
+func TestTable_Currencies(t *testing.T) {
+	assert.Equal(t, []string{"EUR", "JPY", "USD"}, table.Currencies())
+}
+
+func TestTable_Matrix(t *testing.T) {
+	m, err := table.Matrix([]string{"EUR", "USD", "JPY"})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(m))
+	for i := range m {
+		assert.Equal(t, float64(1), m[i][i])
+		for j := i + 1; j < len(m); j++ {
+			assert.Equal(t, 1/m[i][j], m[j][i])
+		}
+	}
+	assert.InDelta(t, 1.25, m[0][1], 1e-12)
+	assert.InDelta(t, 100, m[1][2], 1e-9)
+
+	_, err = table.Matrix([]string{"USD", "GBP"})
+	assert.Equal(t, ErrUnknownCurrency, err)
+	_, err = table.Matrix([]string{"GBP"})
+	assert.Equal(t, ErrUnknownCurrency, err)
+}
+
+func TestRoundMatrix(t *testing.T) {
+	m := [][]float64{
+		{1, 1 / 0.0006251, 1.2},
+		{0.0006251, 1, 0.00075},
+		{1 / 1.2, 1 / 0.00075, 1},
+	}
+	RoundMatrix(m, 2)
+	assert.Equal(t, [][]float64{
+		{1, 1599.74, 1.2},
+		{0, 1, 0},
+		{0.83, 1333.33, 1},
+	}, m)
+
+	m = [][]float64{{1, 1.23456789}, {1 / 1.23456789, 1}}
+	RoundMatrix(m, 4)
+	assert.Equal(t, 1.2346, m[0][1])
+	assert.Equal(t, Round(1/1.2346, 4), m[1][0])
+}
+
+func TestRound(t *testing.T) {
+	assert.Equal(t, 1.2346, Round(1.23456, 4))
+	assert.Equal(t, float64(130), Round(129.91, 0))
+}
+
+func TestFluctuation(t *testing.T) {
+	end := NewTable([]model.Rate{
+		{Currency: "USD", Rate: 1.2},
+		{Currency: "JPY", Rate: 132},
+		{Currency: "GBP", Rate: 0.9},
+	})
+	cs, err := Fluctuation(table, end, "EUR", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(cs))
+	assert.InDelta(t, 1.25, cs["USD"].StartRate, 1e-12)
+	assert.InDelta(t, 1.2, cs["USD"].EndRate, 1e-12)
+	assert.InDelta(t, -0.05, cs["USD"].Change, 1e-12)
+	assert.InDelta(t, -4, cs["USD"].ChangePct, 1e-9)
+	assert.InDelta(t, 5.6, cs["JPY"].ChangePct, 1e-9)
+
+	cs, err = Fluctuation(table, end, "USD", []string{"JPY"})
+	assert.Nil(t, err)
+	assert.InDelta(t, 100, cs["JPY"].StartRate, 1e-9)
+	assert.InDelta(t, 110, cs["JPY"].EndRate, 1e-9)
+	assert.InDelta(t, 10, cs["JPY"].ChangePct, 1e-9)
+
+	_, err = Fluctuation(table, end, "USD", []string{"GBP"})
+	assert.Equal(t, ErrUnknownCurrency, err)
+	_, err = Fluctuation(table, end, "GBP", nil)
+	assert.Equal(t, ErrUnknownCurrency, err)
+}
+
+func TestTimeSeries(t *testing.T) {
+	rates := []model.Rate{
+		{Time: "2021-03-24", Currency: "JPY", Rate: 120},
+		{Time: "2021-03-24", Currency: "USD", Rate: 1.2},
+		{Time: "2021-03-25", Currency: "JPY", Rate: 125},
+		{Time: "2021-03-26", Currency: "JPY", Rate: 130},
+		{Time: "2021-03-26", Currency: "USD", Rate: 1.3},
+	}
+	series, err := TimeSeries(rates, "usd", nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(series))
+	assert.Equal(t, "2021-03-26", series["JPY"][1].Date)
+	assert.InDelta(t, 100, series["JPY"][0].Rate, 1e-9)
+	assert.InDelta(t, 100, series["JPY"][1].Rate, 1e-9)
+	assert.Equal(t, 2, len(series["EUR"]))
+	assert.InDelta(t, 1/1.3, series["EUR"][1].Rate, 1e-12)
+
+	series, err = TimeSeries(rates, "EUR", []string{"JPY", "GBP"}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []float64{120, 125, 130}, Values(series["JPY"]))
+	assert.Equal(t, 0, len(series["GBP"]))
+
+	_, err = TimeSeries(rates, "GBP", nil, nil)
+	assert.Equal(t, ErrUnknownCurrency, err)
+}
+
+func TestAlign(t *testing.T) {
+	series := map[string][]Point{
+		"USD": {{Date: "2021-03-24", Rate: 1.2}, {Date: "2021-03-25", Rate: 1.1}, {Date: "2021-03-26", Rate: 1.3}},
+		"JPY": {{Date: "2021-03-24", Rate: 120}, {Date: "2021-03-26", Rate: 130}},
+	}
+	dates, values := Align(series, []string{"JPY", "USD"})
+	assert.Equal(t, []string{"2021-03-24", "2021-03-26"}, dates)
+	assert.Equal(t, [][]float64{{120, 130}, {1.2, 1.3}}, values)
+
+	dates, values = Align(series, []string{"GBP"})
+	assert.Equal(t, 0, len(dates))
+	assert.Equal(t, [][]float64{{}}, values)
+}
+
+func TestHistory_OnOrBefore(t *testing.T) {
+	h := NewHistory([]model.Rate{
+		{Time: "2021-03-19", Currency: "USD", Rate: 1.19},
+		{Time: "2021-03-19", Currency: "GBP", Rate: 0.86},
+		{Time: "2021-03-22", Currency: "USD", Rate: 1.2},
+	}, nil)
+	assert.Equal(t, []string{"2021-03-19", "2021-03-22"}, h.Dates())
+	_, _, ok := h.OnOrBefore("2021-03-18")
+	assert.False(t, ok)
+
+	table, d, ok := h.OnOrBefore("2021-03-21")
+	assert.True(t, ok)
+	assert.Equal(t, "2021-03-19", d)
+	assert.Equal(t, 1.19, table["USD"])
+	assert.Equal(t, 0.86, table["GBP"])
+	assert.Equal(t, 1.95583, table["DEM"])
+
+	table, d, ok = h.OnOrBefore("2021-03-22")
+	assert.True(t, ok)
+	assert.Equal(t, "2021-03-22", d)
+	assert.Equal(t, 1.2, table["USD"])
+
+	_, d, _ = h.OnOrBefore("2030-01-01")
+	assert.Equal(t, "2021-03-22", d)
+}
+
+func TestTable_WithFixed(t *testing.T) {
+	table := Table{"EUR": 1, "HRK": 7.5365}.WithFixed("2022-12-30")
+	assert.Equal(t, 7.5365, table["HRK"])
+	assert.Equal(t, 30.126, table["SKK"])
+
+	table = Table{"EUR": 1}.WithFixed("2023-01-02")
+	assert.Equal(t, 7.5345, table["HRK"])
+
+	_, ok := Table{"EUR": 1}.WithFixed("1998-12-31")["DEM"]
+	assert.False(t, ok)
+
+	rate, err := Table{"EUR": 1}.WithFixed("2021-03-26").Cross("DEM", "FRF")
+	assert.Nil(t, err)
+	assert.InDelta(t, 6.55957/1.95583, rate, 1e-12)
+}
+
+func TestTable_WithPegged(t *testing.T) {
+	pegs, err := currency.NewPegs([]currency.Derived{
+		{Code: "AED", Peg: "USD", Ratio: 3.6725},
+		{Code: "XOF", Peg: "EUR", Ratio: 655.957, ValidFrom: "1999-01-01"},
+		{Code: "ABC", Peg: "DEM", Ratio: 2},
+	})
+	assert.Nil(t, err)
+
+	table := NewTable([]model.Rate{{Time: "2021-03-26", Currency: "USD", Rate: 1.18}}).WithPegged(pegs, "2021-03-26")
+	assert.InDelta(t, 1.18*3.6725, table["AED"], 1e-12)
+	assert.Equal(t, 655.957, table["XOF"])
+	_, ok := table["ABC"]
+	assert.False(t, ok)
+	assert.Equal(t, 2*1.95583, table.WithFixed("2021-03-26").WithPegged(pegs, "2021-03-26")["ABC"])
+
+	table = NewTable([]model.Rate{{Time: "1998-12-31", Currency: "GBP", Rate: 0.7}}).WithPegged(pegs, "1998-12-31")
+	_, ok = table["XOF"]
+	assert.False(t, ok)
+	_, ok = table["AED"]
+	assert.False(t, ok)
+
+	// Dates scanned from the database carry a time.
+	table = NewTable(nil).WithPegged(pegs, "1999-01-01T00:00:00Z")
+	assert.Equal(t, 655.957, table["XOF"])
+}
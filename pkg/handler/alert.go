@@ -0,0 +1,226 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/huyhvq/eurofxref/pkg/alert"
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"github.com/huyhvq/eurofxref/pkg/repository"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultAlertLimit = 50
+	maxAlertLimit     = 1000
+)
+
+type AlertRuleRequest struct {
+	Name      string   `json:"name,omitempty"`
+	Pair      string   `json:"pair"`
+	Condition string   `json:"condition"`
+	Threshold float64  `json:"threshold"`
+	Cooldown  string   `json:"cooldown,omitempty"`
+	Sinks     []string `json:"sinks,omitempty"`
+}
+
+type AlertRule struct {
+	ID              int64      `json:"id"`
+	Name            string     `json:"name,omitempty"`
+	Pair            string     `json:"pair"`
+	Condition       string     `json:"condition"`
+	Threshold       float64    `json:"threshold"`
+	Cooldown        string     `json:"cooldown"`
+	Sinks           []string   `json:"sinks"`
+	CreatedAt       time.Time  `json:"created_at"`
+	LastTriggeredAt *time.Time `json:"last_triggered_at,omitempty"`
+}
+
+type Alert struct {
+	ID          int64     `json:"id"`
+	RuleID      int64     `json:"rule_id"`
+	Date        string    `json:"date"`
+	Value       float64   `json:"value"`
+	Previous    float64   `json:"previous,omitempty"`
+	Message     string    `json:"message"`
+	Error       string    `json:"error,omitempty"`
+	TriggeredAt time.Time `json:"triggered_at"`
+}
+
+// AdminAlertRules serves /admin/alerts/rules: GET lists the rules, POST
+// creates one.
+func (h *handler) AdminAlertRules(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		errorRespond(w, http.StatusMethodNotAllowed, errInvalidMethod.Error())
+		return
+	}
+	if !h.authorized(r) {
+		errorRespond(w, http.StatusUnauthorized, errUnauthorized.Error())
+		return
+	}
+	if r.Method == http.MethodGet {
+		rules, err := h.alertRepo.GetRules()
+		if err != nil {
+			errorRespond(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		res := make([]*AlertRule, 0, len(rules))
+		for _, rule := range rules {
+			res = append(res, alertRuleTransform(rule))
+		}
+		jsonRespond(w, http.StatusOK, res)
+		return
+	}
+	var req AlertRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		errorRespond(w, http.StatusBadRequest, errInvalidRequest.Error())
+		return
+	}
+	pair := strings.Split(req.Pair, "/")
+	if len(pair) != 2 {
+		errorRespond(w, http.StatusBadRequest, alert.ErrInvalidRule.Error())
+		return
+	}
+	rule := model.AlertRule{
+		Name:      req.Name,
+		Base:      pair[0],
+		Quote:     pair[1],
+		Condition: req.Condition,
+		Threshold: req.Threshold,
+		Sinks:     req.Sinks,
+		CreatedAt: time.Now().UTC(),
+	}
+	if req.Cooldown != "" {
+		var err error
+		if rule.Cooldown, err = time.ParseDuration(req.Cooldown); err != nil {
+			errorRespond(w, http.StatusBadRequest, alert.ErrInvalidRule.Error())
+			return
+		}
+	}
+	if err := alert.Normalize(&rule, h.alertSinks); err != nil {
+		errorRespond(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	id, err := h.alertRepo.InsertRule(rule)
+	if err != nil {
+		errorRespond(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	rule.ID = id
+	jsonRespond(w, http.StatusCreated, alertRuleTransform(rule))
+}
+
+// AdminAlertRule serves /admin/alerts/rules/{id}: GET returns the rule,
+// DELETE removes it and keeps its alert history.
+func (h *handler) AdminAlertRule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodDelete {
+		errorRespond(w, http.StatusMethodNotAllowed, errInvalidMethod.Error())
+		return
+	}
+	if !h.authorized(r) {
+		errorRespond(w, http.StatusUnauthorized, errUnauthorized.Error())
+		return
+	}
+	id, err := strconv.ParseInt(r.URL.Path[len("/admin/alerts/rules/"):], 10, 64)
+	if err != nil {
+		errorRespond(w, http.StatusNotFound, errNotFound.Error())
+		return
+	}
+	if r.Method == http.MethodDelete {
+		err := h.alertRepo.DeleteRule(id)
+		if err == repository.ErrNotFound {
+			errorRespond(w, http.StatusNotFound, errNotFound.Error())
+			return
+		}
+		if err != nil {
+			errorRespond(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	rule, err := h.alertRepo.GetRule(id)
+	if err == repository.ErrNotFound {
+		errorRespond(w, http.StatusNotFound, errNotFound.Error())
+		return
+	}
+	if err != nil {
+		errorRespond(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	jsonRespond(w, http.StatusOK, alertRuleTransform(rule))
+}
+
+// AdminAlerts serves GET /admin/alerts?rule=&limit=, the triggered alerts
+// newest first.
+func (h *handler) AdminAlerts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		errorRespond(w, http.StatusMethodNotAllowed, errInvalidMethod.Error())
+		return
+	}
+	if !h.authorized(r) {
+		errorRespond(w, http.StatusUnauthorized, errUnauthorized.Error())
+		return
+	}
+	q := r.URL.Query()
+	var (
+		ruleID int64
+		limit  = defaultAlertLimit
+		err    error
+	)
+	if v := q.Get("rule"); v != "" {
+		if ruleID, err = strconv.ParseInt(v, 10, 64); err != nil || ruleID <= 0 {
+			err = errors.New("invalid rule")
+		}
+	}
+	if v := q.Get("limit"); v != "" && err == nil {
+		if limit, err = strconv.Atoi(v); err == nil && (limit <= 0 || limit > maxAlertLimit) {
+			err = errors.New("invalid limit")
+		}
+	}
+	if err != nil {
+		errorRespond(w, http.StatusBadRequest, errInvalidRequest.Error())
+		return
+	}
+	alerts, err := h.alertRepo.GetAlerts(ruleID, limit)
+	if err != nil {
+		errorRespond(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	res := make([]*Alert, 0, len(alerts))
+	for _, a := range alerts {
+		res = append(res, &Alert{
+			ID:          a.ID,
+			RuleID:      a.RuleID,
+			Date:        a.Date,
+			Value:       a.Value,
+			Previous:    a.Previous,
+			Message:     a.Message,
+			Error:       a.Error,
+			TriggeredAt: a.TriggeredAt,
+		})
+	}
+	jsonRespond(w, http.StatusOK, res)
+}
+
+func alertRuleTransform(rule model.AlertRule) *AlertRule {
+	res := &AlertRule{
+		ID:        rule.ID,
+		Name:      rule.Name,
+		Pair:      rule.Base + "/" + rule.Quote,
+		Condition: rule.Condition,
+		Threshold: rule.Threshold,
+		Cooldown:  rule.Cooldown.String(),
+		Sinks:     rule.Sinks,
+		CreatedAt: rule.CreatedAt,
+	}
+	if res.Sinks == nil {
+		res.Sinks = []string{}
+	}
+	if !rule.LastTriggeredAt.IsZero() {
+		res.LastTriggeredAt = &rule.LastTriggeredAt
+	}
+	return res
+}
@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"errors"
+	"github.com/huyhvq/eurofxref/pkg/alert"
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"github.com/huyhvq/eurofxref/pkg/repository"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeAlerts keeps alert rules and alerts in memory, numbered from 1.
+type fakeAlerts struct {
+	repository.AlertRepository
+	rules  []model.AlertRule
+	alerts []model.Alert
+	err    error
+}
+
+func (f *fakeAlerts) InsertRule(r model.AlertRule) (int64, error) {
+	r.ID = int64(len(f.rules) + 1)
+	f.rules = append(f.rules, r)
+	return r.ID, f.err
+}
+
+func (f *fakeAlerts) GetRules() ([]model.AlertRule, error) {
+	return f.rules, f.err
+}
+
+func (f *fakeAlerts) GetRule(id int64) (model.AlertRule, error) {
+	for _, r := range f.rules {
+		if r.ID == id {
+			return r, f.err
+		}
+	}
+	return model.AlertRule{}, repository.ErrNotFound
+}
+
+func (f *fakeAlerts) DeleteRule(id int64) error {
+	for i, r := range f.rules {
+		if r.ID == id {
+			f.rules = append(f.rules[:i], f.rules[i+1:]...)
+			return nil
+		}
+	}
+	return repository.ErrNotFound
+}
+
+func (f *fakeAlerts) GetAlerts(ruleID int64, limit int) ([]model.Alert, error) {
+	var res []model.Alert
+	for _, a := range f.alerts {
+		if (ruleID == 0 || a.RuleID == ruleID) && len(res) < limit {
+			res = append(res, a)
+		}
+	}
+	return res, f.err
+}
+
+func TestHandler_AdminAlerts(t *testing.T) {
+	now := time.Date(2021, 3, 29, 16, 0, 0, 0, time.UTC)
+	alerts := &fakeAlerts{alerts: []model.Alert{
+		{ID: 2, RuleID: 1, Date: "2021-03-29", Value: 1.21, Previous: 1.19, Message: "EUR/USD crossed above 1.2 at 1.21",
+			Error: "treasury: boom", TriggeredAt: now},
+		{ID: 1, RuleID: 2, Date: "2021-03-26", Value: 0.86, Message: "EUR/GBP at 0.86 is above 0.85", TriggeredAt: now},
+	}}
+	sinks, err := alert.NewSinks(nil)
+	assert.Nil(t, err)
+	h := newTestHandler(Config{AlertRepo: alerts, AlertSinks: sinks})
+
+	body := `{"pair": "eur/usd", "condition": "crosses_above", "threshold": 1.2, "cooldown": "24h", "sinks": ["log"]}`
+	w := do(h.AdminAlertRules, http.MethodPost, "/admin/alerts/rules", strings.NewReader(body), true)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var rule AlertRule
+	decode(t, w, &rule)
+	assert.Equal(t, int64(1), rule.ID)
+	assert.Equal(t, "EUR/USD", rule.Pair)
+	assert.Equal(t, "24h0m0s", rule.Cooldown)
+	assert.Equal(t, []string{"log"}, rule.Sinks)
+	assert.Nil(t, rule.LastTriggeredAt)
+
+	w = do(h.AdminAlertRules, http.MethodGet, "/admin/alerts/rules", nil, true)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var rules []AlertRule
+	decode(t, w, &rules)
+	assert.Len(t, rules, 1)
+
+	w = do(h.AdminAlertRule, http.MethodGet, "/admin/alerts/rules/1", nil, true)
+	assert.Equal(t, http.StatusOK, w.Code)
+	rule = AlertRule{}
+	decode(t, w, &rule)
+	assert.Equal(t, "crosses_above", rule.Condition)
+
+	w = do(h.AdminAlerts, http.MethodGet, "/admin/alerts?rule=1", nil, true)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var list []Alert
+	decode(t, w, &list)
+	assert.Equal(t, []Alert{{ID: 2, RuleID: 1, Date: "2021-03-29", Value: 1.21, Previous: 1.19,
+		Message: "EUR/USD crossed above 1.2 at 1.21", Error: "treasury: boom", TriggeredAt: now}}, list)
+
+	w = do(h.AdminAlerts, http.MethodGet, "/admin/alerts?limit=1", nil, true)
+	assert.Equal(t, http.StatusOK, w.Code)
+	list = nil
+	decode(t, w, &list)
+	assert.Len(t, list, 1)
+
+	w = do(h.AdminAlertRule, http.MethodDelete, "/admin/alerts/rules/1", nil, true)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	for _, tc := range []struct {
+		name   string
+		fn     http.HandlerFunc
+		method string
+		target string
+		body   string
+		admin  bool
+		code   int
+	}{
+		{"unauthorized rules", h.AdminAlertRules, http.MethodGet, "/admin/alerts/rules", "", false, http.StatusUnauthorized},
+		{"unauthorized rule", h.AdminAlertRule, http.MethodGet, "/admin/alerts/rules/1", "", false, http.StatusUnauthorized},
+		{"unauthorized alerts", h.AdminAlerts, http.MethodGet, "/admin/alerts", "", false, http.StatusUnauthorized},
+		{"malformed", h.AdminAlertRules, http.MethodPost, "/admin/alerts/rules", `{"pair": `, true, http.StatusBadRequest},
+		{"invalid pair", h.AdminAlertRules, http.MethodPost, "/admin/alerts/rules",
+			`{"pair": "EURUSD", "condition": "above", "threshold": 1}`, true, http.StatusBadRequest},
+		{"invalid cooldown", h.AdminAlertRules, http.MethodPost, "/admin/alerts/rules",
+			`{"pair": "EUR/USD", "condition": "above", "threshold": 1, "cooldown": "1 day"}`, true, http.StatusBadRequest},
+		{"unknown sink", h.AdminAlertRules, http.MethodPost, "/admin/alerts/rules",
+			`{"pair": "EUR/USD", "condition": "above", "threshold": 1, "sinks": ["pager"]}`, true, http.StatusBadRequest},
+		{"rules method", h.AdminAlertRules, http.MethodPut, "/admin/alerts/rules", "", true, http.StatusMethodNotAllowed},
+		{"deleted", h.AdminAlertRule, http.MethodGet, "/admin/alerts/rules/1", "", true, http.StatusNotFound},
+		{"delete twice", h.AdminAlertRule, http.MethodDelete, "/admin/alerts/rules/1", "", true, http.StatusNotFound},
+		{"invalid id", h.AdminAlertRule, http.MethodGet, "/admin/alerts/rules/x", "", true, http.StatusNotFound},
+		{"rule method", h.AdminAlertRule, http.MethodPut, "/admin/alerts/rules/1", "", true, http.StatusMethodNotAllowed},
+		{"invalid rule filter", h.AdminAlerts, http.MethodGet, "/admin/alerts?rule=0", "", true, http.StatusBadRequest},
+		{"invalid limit", h.AdminAlerts, http.MethodGet, "/admin/alerts?limit=1001", "", true, http.StatusBadRequest},
+		{"alerts method", h.AdminAlerts, http.MethodPost, "/admin/alerts", "", true, http.StatusMethodNotAllowed},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := do(tc.fn, tc.method, tc.target, strings.NewReader(tc.body), tc.admin)
+			assert.Equal(t, tc.code, w.Code)
+		})
+	}
+
+	h = newTestHandler(Config{AlertRepo: &fakeAlerts{err: errors.New("db down")}, AlertSinks: sinks})
+	w = do(h.AdminAlerts, http.MethodGet, "/admin/alerts", nil, true)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "db down", errorOf(t, w))
+}
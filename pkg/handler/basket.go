@@ -0,0 +1,401 @@
+package handler
+
+import (
+	"encoding/json"
+	"github.com/huyhvq/eurofxref/pkg/basket"
+	"github.com/huyhvq/eurofxref/pkg/fx"
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"github.com/huyhvq/eurofxref/pkg/repository"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+type Basket struct {
+	Name       string            `json:"name"`
+	Kind       string            `json:"kind"`
+	BaseDate   string            `json:"base_date,omitempty"`
+	BaseValue  float64           `json:"base_value,omitempty"`
+	Components []BasketComponent `json:"components"`
+	CreatedAt  *time.Time        `json:"created_at,omitempty"`
+	UpdatedAt  *time.Time        `json:"updated_at,omitempty"`
+}
+
+type BasketComponent struct {
+	Currency string  `json:"currency"`
+	Weight   float64 `json:"weight,omitempty"`
+	Units    float64 `json:"units"`
+}
+
+type BasketValue struct {
+	Name       string                 `json:"name"`
+	Base       string                 `json:"base"`
+	Date       string                 `json:"date"`
+	Value      float64                `json:"value"`
+	Components []BasketComponentValue `json:"components"`
+	Derived    []string               `json:"derived,omitempty"`
+	Overridden []string               `json:"overridden,omitempty"`
+}
+
+type BasketComponentValue struct {
+	Currency string  `json:"currency"`
+	Units    float64 `json:"units"`
+	Value    float64 `json:"value"`
+}
+
+type BasketSeries struct {
+	Name       string        `json:"name"`
+	Base       string        `json:"base"`
+	Start      string        `json:"start"`
+	End        string        `json:"end"`
+	Series     []BasketPoint `json:"series"`
+	Overridden []string      `json:"overridden,omitempty"`
+}
+
+type BasketPoint struct {
+	Date  string  `json:"date"`
+	Value float64 `json:"value"`
+}
+
+// AdminBaskets serves /admin/baskets: GET lists the baskets, POST creates a
+// basket or replaces the one with the same name.
+func (h *handler) AdminBaskets(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		errorRespond(w, http.StatusMethodNotAllowed, errInvalidMethod.Error())
+		return
+	}
+	if !h.authorized(r) {
+		errorRespond(w, http.StatusUnauthorized, errUnauthorized.Error())
+		return
+	}
+	if r.Method == http.MethodGet {
+		h.listBaskets(w)
+		return
+	}
+	var req Basket
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		errorRespond(w, http.StatusBadRequest, errInvalidRequest.Error())
+		return
+	}
+	b := basketModel(req)
+	if err := basket.Normalize(&b); err != nil {
+		errorRespond(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	date := "latest"
+	if b.Kind == model.BasketWeights {
+		date = b.BaseDate
+	}
+	rates, t, err := h.resolveRates(date)
+	if err != nil {
+		errorRespond(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if len(rates) == 0 {
+		errorRespond(w, http.StatusBadRequest, errNoRates.Error())
+		return
+	}
+	d := t.Format("2006-01-02")
+	if err := basket.Resolve(&b, fx.NewTable(rates).WithFixed(d).WithPegged(h.pegs, d)); err != nil {
+		errorRespond(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	b.CreatedAt = time.Now().UTC()
+	b.UpdatedAt = b.CreatedAt
+	if _, err := h.basketRepo.Save(b); err != nil {
+		errorRespond(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	saved, err := h.basketRepo.GetByName(b.Name)
+	if err != nil {
+		errorRespond(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	jsonRespond(w, http.StatusOK, basketTransform(saved))
+}
+
+// AdminBasket serves /admin/baskets/{name}: GET returns the basket, DELETE
+// removes it.
+func (h *handler) AdminBasket(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodDelete {
+		errorRespond(w, http.StatusMethodNotAllowed, errInvalidMethod.Error())
+		return
+	}
+	if !h.authorized(r) {
+		errorRespond(w, http.StatusUnauthorized, errUnauthorized.Error())
+		return
+	}
+	name := r.URL.Path[len("/admin/baskets/"):]
+	if r.Method == http.MethodGet {
+		b, ok := h.getBasket(w, name)
+		if ok {
+			jsonRespond(w, http.StatusOK, basketTransform(b))
+		}
+		return
+	}
+	err := h.basketRepo.Delete(name)
+	if err == repository.ErrNotFound {
+		errorRespond(w, http.StatusNotFound, errNotFound.Error())
+		return
+	}
+	if err != nil {
+		errorRespond(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *handler) GetBaskets(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		errorRespond(w, http.StatusMethodNotAllowed, errInvalidMethod.Error())
+		return
+	}
+	h.listBaskets(w)
+}
+
+// GetBasket serves /baskets/{name}?date=&base= with the value of the basket
+// on a date, and /baskets/{name}/series?start=&end=&base= with its value on
+// every publication date of the range.
+func (h *handler) GetBasket(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		errorRespond(w, http.StatusMethodNotAllowed, errInvalidMethod.Error())
+		return
+	}
+	parts := strings.Split(r.URL.Path[len("/baskets/"):], "/")
+	switch {
+	case len(parts) == 1:
+		h.basketValue(w, r, parts[0])
+	case len(parts) == 2 && parts[1] == "series":
+		h.basketSeries(w, r, parts[0])
+	default:
+		errorRespond(w, http.StatusNotFound, errInvalidRequest.Error())
+	}
+}
+
+func (h *handler) basketValue(w http.ResponseWriter, r *http.Request, name string) {
+	precision, err := parsePrecision(r)
+	if err != nil {
+		errorRespond(w, http.StatusBadRequest, errInvalidRequest.Error())
+		return
+	}
+	b, ok := h.getBasket(w, name)
+	if !ok {
+		return
+	}
+	date := r.URL.Query().Get("date")
+	if date == "" {
+		date = "latest"
+	}
+	rates, t, err := h.resolveRates(date)
+	if err == errInvalidRequest {
+		errorRespond(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		errorRespond(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if len(rates) == 0 {
+		errorRespond(w, http.StatusNotFound, errNoRates.Error())
+		return
+	}
+	d := t.Format("2006-01-02")
+	base := parseBase(r)
+	total, values, err := basket.Value(b, fx.NewTable(rates).WithFixed(d).WithPegged(h.pegs, d), base)
+	if err != nil {
+		errorRespond(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	res := &BasketValue{
+		Name:       b.Name,
+		Base:       base,
+		Date:       d,
+		Value:      fx.Round(total, precision),
+		Components: make([]BasketComponentValue, 0, len(b.Components)),
+	}
+	codes := []string{base}
+	for i, c := range b.Components {
+		res.Components = append(res.Components, BasketComponentValue{
+			Currency: c.Currency,
+			Units:    c.Units,
+			Value:    fx.Round(values[i], precision),
+		})
+		codes = append(codes, c.Currency)
+	}
+	res.Derived = h.derivedSymbols(codes, d)
+	res.Overridden = overriddenSymbols(rates, codes)
+	jsonRespond(w, http.StatusOK, res)
+}
+
+func (h *handler) basketSeries(w http.ResponseWriter, r *http.Request, name string) {
+	start, end, err := parseRange(r, true)
+	if err != nil {
+		errorRespond(w, http.StatusBadRequest, errInvalidRequest.Error())
+		return
+	}
+	precision, err := parsePrecision(r)
+	if err != nil {
+		errorRespond(w, http.StatusBadRequest, errInvalidRequest.Error())
+		return
+	}
+	b, ok := h.getBasket(w, name)
+	if !ok {
+		return
+	}
+	rates, err := h.rateRepo.GetRatesBetween(start, end)
+	if err != nil {
+		errorRespond(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	base := parseBase(r)
+	res := &BasketSeries{
+		Name:   b.Name,
+		Base:   base,
+		Start:  start.Format("2006-01-02"),
+		End:    end.Format("2006-01-02"),
+		Series: make([]BasketPoint, 0),
+	}
+	codes := []string{base}
+	for _, c := range b.Components {
+		codes = append(codes, c.Currency)
+	}
+	res.Overridden = overriddenSymbols(rates, codes)
+	history := fx.NewHistory(rates, h.pegs)
+	for _, d := range history.Dates() {
+		table, _, _ := history.OnOrBefore(d)
+		// Dates on which a component or base was not published are skipped.
+		if v, _, err := basket.Value(b, table, base); err == nil {
+			res.Series = append(res.Series, BasketPoint{Date: d, Value: fx.Round(v, precision)})
+		}
+	}
+	jsonRespond(w, http.StatusOK, res)
+}
+
+func (h *handler) listBaskets(w http.ResponseWriter) {
+	baskets, err := h.basketRepo.GetAll()
+	if err != nil {
+		errorRespond(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	bs := make([]*Basket, 0, len(baskets))
+	for _, b := range baskets {
+		bs = append(bs, basketTransform(b))
+	}
+	jsonRespond(w, http.StatusOK, bs)
+}
+
+// getBasket loads the basket called name, responding with an error when it
+// cannot.
+func (h *handler) getBasket(w http.ResponseWriter, name string) (model.Basket, bool) {
+	b, err := h.basketRepo.GetByName(name)
+	if err == repository.ErrNotFound {
+		errorRespond(w, http.StatusNotFound, errNotFound.Error())
+		return b, false
+	}
+	if err != nil {
+		errorRespond(w, http.StatusInternalServerError, err.Error())
+		return b, false
+	}
+	return b, true
+}
+
+// withBaskets adds the stored baskets to the rates of er when the request
+// asks for them with baskets=true.
+func (h *handler) withBaskets(r *http.Request, er *ExchangeRate) error {
+	if r.URL.Query().Get("baskets") != "true" || len(er.Rates) == 0 {
+		return nil
+	}
+	baskets, err := h.basketRepo.GetAll()
+	if err != nil {
+		return err
+	}
+	// The rates of er leave out EUR, which the baskets are priced in.
+	t := fx.Table{fx.Base: 1}
+	for c, rate := range er.Rates {
+		t[c] = rate
+	}
+	er.Baskets = addBaskets(t, baskets)
+	for _, c := range er.Baskets {
+		er.Rates[c] = t[c]
+	}
+	return nil
+}
+
+// addBaskets prices each basket at the rates of t and adds it to t under its
+// upper-cased name, in units per EUR like the currencies of t. Currencies win
+// over baskets of the same code and baskets that cannot be valued at t are
+// left out. It returns the codes added, sorted.
+func addBaskets(t fx.Table, baskets []model.Basket) []string {
+	rates := make(map[string]float64, len(baskets))
+	for _, b := range baskets {
+		code := strings.ToUpper(b.Name)
+		if _, err := t.Leg(code); err == nil {
+			continue
+		}
+		if _, ok := rates[code]; ok {
+			continue
+		}
+		if v, _, err := basket.Value(b, t, fx.Base); err == nil && v > 0 {
+			rates[code] = 1 / v
+		}
+	}
+	codes := make([]string, 0, len(rates))
+	for code, rate := range rates {
+		t[code] = rate
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// basketSymbols returns the codes among codes that are in baskets, or nil
+// when there are none.
+func basketSymbols(baskets, codes []string) []string {
+	var res []string
+	for _, c := range codes {
+		i := sort.SearchStrings(baskets, c)
+		if i < len(baskets) && baskets[i] == c {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+func basketModel(b Basket) model.Basket {
+	m := model.Basket{
+		Name:       b.Name,
+		Kind:       b.Kind,
+		BaseDate:   b.BaseDate,
+		BaseValue:  b.BaseValue,
+		Components: make([]model.BasketComponent, 0, len(b.Components)),
+	}
+	for _, c := range b.Components {
+		m.Components = append(m.Components, model.BasketComponent{
+			Currency: c.Currency,
+			Weight:   c.Weight,
+			Units:    c.Units,
+		})
+	}
+	return m
+}
+
+func basketTransform(b model.Basket) *Basket {
+	res := &Basket{
+		Name:       b.Name,
+		Kind:       b.Kind,
+		BaseDate:   b.BaseDate,
+		BaseValue:  b.BaseValue,
+		Components: make([]BasketComponent, 0, len(b.Components)),
+		CreatedAt:  &b.CreatedAt,
+		UpdatedAt:  &b.UpdatedAt,
+	}
+	for _, c := range b.Components {
+		res.Components = append(res.Components, BasketComponent{
+			Currency: c.Currency,
+			Weight:   c.Weight,
+			Units:    c.Units,
+		})
+	}
+	return res
+}
@@ -0,0 +1,200 @@
+package handler
+
+import (
+	"errors"
+	"github.com/huyhvq/eurofxref/pkg/fx"
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// testBaskets hold 1 USD and 1 GBP, and 100 JPY under the name of a currency.
+var testBaskets = []model.Basket{
+	{Name: "pair", Kind: model.BasketUnits, Components: []model.BasketComponent{
+		{Currency: "USD", Units: 1}, {Currency: "GBP", Units: 1},
+	}},
+	{Name: "USD", Kind: model.BasketUnits, Components: []model.BasketComponent{
+		{Currency: "JPY", Units: 100},
+	}},
+}
+
+func TestHandler_GetBasket(t *testing.T) {
+	h := newTestHandler(Config{BasketRepo: &fakeBaskets{baskets: testBaskets}})
+
+	w := do(h.GetBasket, http.MethodGet, "/baskets/pair?date=2021-03-27&base=USD", nil, false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var v BasketValue
+	decode(t, w, &v)
+	assert.Equal(t, "USD", v.Base)
+	assert.Equal(t, "2021-03-26", v.Date)
+	assert.Equal(t, fx.Round(1+1.1795/0.8556, 4), fx.Round(v.Value, 4))
+	assert.Len(t, v.Components, 2)
+
+	w = do(h.GetBasket, http.MethodGet, "/baskets/pair/series?start=2021-03-26&end=2021-03-29", nil, false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var s BasketSeries
+	decode(t, w, &s)
+	assert.Len(t, s.Series, 2)
+
+	for _, tc := range []struct {
+		target string
+		code   int
+	}{
+		{"/baskets/other", http.StatusNotFound},
+		{"/baskets/pair/values", http.StatusNotFound},
+		{"/baskets/pair?date=26-03-2021", http.StatusBadRequest},
+		{"/baskets/pair?base=XXX", http.StatusBadRequest},
+	} {
+		w = do(h.GetBasket, http.MethodGet, tc.target, nil, false)
+		assert.Equal(t, tc.code, w.Code, tc.target)
+	}
+
+	w = do(h.GetBaskets, http.MethodGet, "/baskets", nil, false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var list []Basket
+	decode(t, w, &list)
+	assert.Len(t, list, 2)
+}
+
+func TestHandler_basketCodes(t *testing.T) {
+	h := newTestHandler(Config{BasketRepo: &fakeBaskets{baskets: testBaskets}})
+	pair := 1 / (1/1.1795 + 1/0.8556)
+
+	w := do(h.GetRatesByDate, http.MethodGet, "/rates/2021-03-26?baskets=true", nil, false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var er ExchangeRate
+	decode(t, w, &er)
+	assert.InDelta(t, pair, er.Rates["PAIR"], 1e-12)
+	assert.Equal(t, 1.1795, er.Rates["USD"])
+	assert.Equal(t, []string{"PAIR"}, er.Baskets)
+
+	w = do(h.GetLatestRates, http.MethodGet, "/rates/latest", nil, false)
+	er = ExchangeRate{}
+	decode(t, w, &er)
+	assert.NotContains(t, er.Rates, "PAIR")
+	assert.Nil(t, er.Baskets)
+
+	w = do(h.GetRatesByDate, http.MethodGet, "/rates/2021-03-26/pair/usd", nil, false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var pr PairRate
+	decode(t, w, &pr)
+	assert.Equal(t, "PAIR", pr.Base)
+	assert.InDelta(t, 1.1795/pair, pr.Rate, 1e-12)
+	assert.Equal(t, []string{"PAIR"}, pr.Baskets)
+
+	w = do(h.Convert, http.MethodGet, "/convert?from=pair&to=EUR&amount=2&date=2021-03-26", nil, false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var res ConversionResult
+	decode(t, w, &res)
+	assert.InDelta(t, 2/pair, *res.Result, 1e-12)
+	assert.Equal(t, []string{"PAIR"}, res.Baskets)
+
+	body := `{"items": [
+		{"amount": 1, "from": "EUR", "to": "USD", "date": "2021-03-26"},
+		{"amount": 1, "from": "USD", "to": "PAIR", "date": "2021-03-26"},
+		{"amount": 1, "from": "PAIR", "to": "GBP"}
+	]}`
+	w = do(h.ConvertBatch, http.MethodPost, "/convert/batch", strings.NewReader(body), false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var batch ConversionBatchResult
+	decode(t, w, &batch)
+	assert.Nil(t, batch.Results[0].Baskets)
+	assert.InDelta(t, pair/1.1795, *batch.Results[1].Result, 1e-12)
+	assert.Equal(t, "2021-03-29", batch.Results[2].RateDate)
+	assert.InDelta(t, 0.8551/(1/(1/1.1765+1/0.8551)), *batch.Results[2].Result, 1e-12)
+
+	w = do(h.GetRatesByDate, http.MethodGet, "/rates/2021-03-26/pair/xxx", nil, false)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	failing := newTestHandler(Config{BasketRepo: &fakeBaskets{err: errors.New("db down")}})
+	for _, target := range []string{
+		"/rates/2021-03-26?baskets=true",
+		"/rates/2021-03-26/pair/usd",
+	} {
+		w = do(failing.GetRatesByDate, http.MethodGet, target, nil, false)
+		assert.Equal(t, http.StatusInternalServerError, w.Code, target)
+	}
+	w = do(failing.Convert, http.MethodGet, "/convert?from=pair&to=EUR&amount=2", nil, false)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	w = do(failing.Convert, http.MethodGet, "/convert?from=USD&to=EUR&amount=2", nil, false)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestHandler_AdminBaskets(t *testing.T) {
+	baskets := &fakeBaskets{}
+	h := newTestHandler(Config{BasketRepo: baskets})
+
+	body := `{"name": "duo", "kind": "weights", "base_date": "2021-03-26",
+		"components": [{"currency": "usd", "weight": 1}, {"currency": "GBP", "weight": 1}]}`
+	w := do(h.AdminBaskets, http.MethodPost, "/admin/baskets", strings.NewReader(body), true)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var b Basket
+	decode(t, w, &b)
+	assert.Equal(t, "duo", b.Name)
+	assert.Equal(t, float64(100), b.BaseValue)
+	assert.Len(t, b.Components, 2)
+	assert.Equal(t, "USD", b.Components[0].Currency)
+	assert.InDelta(t, 50*1.1795, b.Components[0].Units, 1e-9)
+	assert.InDelta(t, 50*0.8556, b.Components[1].Units, 1e-9)
+	assert.NotNil(t, b.CreatedAt)
+
+	// Posting the same name replaces the basket.
+	body = `{"name": "duo", "kind": "units", "components": [{"currency": "JPY", "units": 100}]}`
+	w = do(h.AdminBaskets, http.MethodPost, "/admin/baskets", strings.NewReader(body), true)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = do(h.AdminBaskets, http.MethodGet, "/admin/baskets", nil, true)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var list []Basket
+	decode(t, w, &list)
+	assert.Len(t, list, 1)
+	assert.Equal(t, model.BasketUnits, list[0].Kind)
+
+	w = do(h.AdminBasket, http.MethodGet, "/admin/baskets/duo", nil, true)
+	assert.Equal(t, http.StatusOK, w.Code)
+	b = Basket{}
+	decode(t, w, &b)
+	assert.Equal(t, []BasketComponent{{Currency: "JPY", Units: 100}}, b.Components)
+
+	w = do(h.AdminBasket, http.MethodDelete, "/admin/baskets/duo", nil, true)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	for _, tc := range []struct {
+		name   string
+		fn     http.HandlerFunc
+		method string
+		target string
+		body   string
+		admin  bool
+		code   int
+	}{
+		{"unauthorized", h.AdminBaskets, http.MethodGet, "/admin/baskets", "", false, http.StatusUnauthorized},
+		{"unauthorized basket", h.AdminBasket, http.MethodGet, "/admin/baskets/duo", "", false, http.StatusUnauthorized},
+		{"malformed", h.AdminBaskets, http.MethodPost, "/admin/baskets", `{"name": `, true, http.StatusBadRequest},
+		{"invalid name", h.AdminBaskets, http.MethodPost, "/admin/baskets",
+			`{"name": "a b", "kind": "units", "components": [{"currency": "USD", "units": 1}]}`, true, http.StatusBadRequest},
+		{"unknown currency", h.AdminBaskets, http.MethodPost, "/admin/baskets",
+			`{"name": "x", "kind": "units", "components": [{"currency": "XXX", "units": 1}]}`, true, http.StatusBadRequest},
+		{"before the first publication", h.AdminBaskets, http.MethodPost, "/admin/baskets",
+			`{"name": "x", "kind": "weights", "base_date": "2021-03-01", "components": [{"currency": "USD", "weight": 1}]}`,
+			true, http.StatusBadRequest},
+		{"method", h.AdminBaskets, http.MethodPut, "/admin/baskets", "", true, http.StatusMethodNotAllowed},
+		{"deleted", h.AdminBasket, http.MethodGet, "/admin/baskets/duo", "", true, http.StatusNotFound},
+		{"delete twice", h.AdminBasket, http.MethodDelete, "/admin/baskets/duo", "", true, http.StatusNotFound},
+		{"basket method", h.AdminBasket, http.MethodPut, "/admin/baskets/duo", "", true, http.StatusMethodNotAllowed},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := do(tc.fn, tc.method, tc.target, strings.NewReader(tc.body), tc.admin)
+			assert.Equal(t, tc.code, w.Code)
+		})
+	}
+
+	h = newTestHandler(Config{BasketRepo: &fakeBaskets{err: errors.New("db down")}})
+	w = do(h.AdminBaskets, http.MethodGet, "/admin/baskets", nil, true)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	body = `{"name": "x", "kind": "units", "components": [{"currency": "USD", "units": 1}]}`
+	w = do(h.AdminBaskets, http.MethodPost, "/admin/baskets", strings.NewReader(body), true)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
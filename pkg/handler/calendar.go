@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"github.com/huyhvq/eurofxref/pkg/calendar"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type CalendarYear struct {
+	Year            int               `json:"year"`
+	PublicationDays int               `json:"publication_days"`
+	Holidays        []CalendarHoliday `json:"holidays"`
+}
+
+type CalendarHoliday struct {
+	Date string `json:"date"`
+	Name string `json:"name"`
+}
+
+type CalendarDay struct {
+	Date                   string `json:"date"`
+	PublicationDay         bool   `json:"publication_day"`
+	Weekend                bool   `json:"weekend"`
+	Holiday                string `json:"holiday,omitempty"`
+	PreviousPublicationDay string `json:"previous_publication_day"`
+	NextPublicationDay     string `json:"next_publication_day"`
+}
+
+func (h *handler) GetCalendar(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		errorRespond(w, http.StatusMethodNotAllowed, errInvalidMethod.Error())
+		return
+	}
+	year := time.Now().UTC().Year()
+	if y := r.URL.Query().Get("year"); y != "" {
+		n, err := strconv.Atoi(y)
+		if err != nil || n < 1583 || n > 9999 {
+			errorRespond(w, http.StatusBadRequest, errInvalidRequest.Error())
+			return
+		}
+		year = n
+	}
+	hs := calendar.Holidays(year)
+	cy := &CalendarYear{
+		Year:            year,
+		PublicationDays: len(calendar.PublicationDays(time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(year, 12, 31, 0, 0, 0, 0, time.UTC))),
+		Holidays:        make([]CalendarHoliday, 0, len(hs)),
+	}
+	for _, hd := range hs {
+		cy.Holidays = append(cy.Holidays, CalendarHoliday{
+			Date: hd.Date.Format("2006-01-02"),
+			Name: hd.Name,
+		})
+	}
+	jsonRespond(w, http.StatusOK, cy)
+}
+
+func (h *handler) GetCalendarDay(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		errorRespond(w, http.StatusMethodNotAllowed, errInvalidMethod.Error())
+		return
+	}
+	t, err := time.ParseInLocation("2006-01-02", r.URL.Path[len("/calendar/"):], time.UTC)
+	if err != nil {
+		errorRespond(w, http.StatusNotFound, errInvalidRequest.Error())
+		return
+	}
+	name, _ := calendar.HolidayName(t)
+	jsonRespond(w, http.StatusOK, &CalendarDay{
+		Date:                   t.Format("2006-01-02"),
+		PublicationDay:         calendar.IsPublicationDay(t),
+		Weekend:                calendar.IsWeekend(t),
+		Holiday:                name,
+		PreviousPublicationDay: calendar.Previous(t).Format("2006-01-02"),
+		NextPublicationDay:     calendar.Next(t).Format("2006-01-02"),
+	})
+}
@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestHandler_GetCalendar(t *testing.T) {
+	h := newTestHandler(Config{})
+
+	w := do(h.GetCalendar, http.MethodGet, "/calendar?year=2021", nil, false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var cy CalendarYear
+	decode(t, w, &cy)
+	assert.Equal(t, 2021, cy.Year)
+	assert.Equal(t, 258, cy.PublicationDays)
+	assert.Equal(t, CalendarHoliday{Date: "2021-04-02", Name: "Good Friday"}, cy.Holidays[1])
+
+	for _, target := range []string{"/calendar?year=x", "/calendar?year=1500"} {
+		w = do(h.GetCalendar, http.MethodGet, target, nil, false)
+		assert.Equal(t, http.StatusBadRequest, w.Code, target)
+	}
+
+	w = do(h.GetCalendar, http.MethodPost, "/calendar", nil, false)
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+func TestHandler_GetCalendarDay(t *testing.T) {
+	h := newTestHandler(Config{})
+
+	w := do(h.GetCalendarDay, http.MethodGet, "/calendar/2021-04-05", nil, false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var day CalendarDay
+	decode(t, w, &day)
+	assert.Equal(t, CalendarDay{
+		Date:                   "2021-04-05",
+		Holiday:                "Easter Monday",
+		PreviousPublicationDay: "2021-04-01",
+		NextPublicationDay:     "2021-04-06",
+	}, day)
+
+	w = do(h.GetCalendarDay, http.MethodGet, "/calendar/2021-04-06", nil, false)
+	decode(t, w, &day)
+	assert.True(t, day.PublicationDay)
+
+	w = do(h.GetCalendarDay, http.MethodGet, "/calendar/tomorrow", nil, false)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"encoding/base64"
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultChangesLimit = 1000
+	maxChangesLimit     = 10000
+	cursorPrefix        = "v1:"
+)
+
+type RateChange struct {
+	Date       string     `json:"date"`
+	Currency   string     `json:"currency"`
+	Rate       float64    `json:"rate"`
+	Previous   *float64   `json:"previous_rate,omitempty"`
+	Kind       string     `json:"kind"`
+	Source     string     `json:"source"`
+	IngestedAt *time.Time `json:"ingested_at"`
+}
+
+type ChangeFeed struct {
+	Changes    []RateChange `json:"changes"`
+	NextCursor string       `json:"next_cursor"`
+	HasMore    bool         `json:"has_more"`
+}
+
+// GetChanges serves GET /changes?since=&limit=, the stored and revised rates
+// in commit order. Consumers pass the next_cursor of a page as since of the
+// next one; without since the feed starts at the first stored rate. Manual
+// overrides are not part of the feed. Rates stored before revisions were kept
+// have no known ingest time and a null ingested_at.
+func (h *handler) GetChanges(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		errorRespond(w, http.StatusMethodNotAllowed, errInvalidMethod.Error())
+		return
+	}
+	q := r.URL.Query()
+	since, err := decodeCursor(q.Get("since"))
+	if err != nil {
+		errorRespond(w, http.StatusBadRequest, errInvalidRequest.Error())
+		return
+	}
+	limit := defaultChangesLimit
+	if l := q.Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n <= 0 || n > maxChangesLimit {
+			errorRespond(w, http.StatusBadRequest, errInvalidRequest.Error())
+			return
+		}
+		limit = n
+	}
+	changes, err := h.rateRepo.GetChanges(since, limit+1)
+	if err != nil {
+		errorRespond(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	feed := &ChangeFeed{Changes: make([]RateChange, 0, len(changes)), NextCursor: encodeCursor(since)}
+	if len(changes) > limit {
+		feed.HasMore = true
+		changes = changes[:limit]
+	}
+	for _, c := range changes {
+		feed.Changes = append(feed.Changes, rateChangeTransform(c))
+		feed.NextCursor = encodeCursor(c.Seq)
+	}
+	jsonRespond(w, http.StatusOK, feed)
+}
+
+func rateChangeTransform(c model.RateChange) RateChange {
+	rc := RateChange{
+		Date:     c.Time,
+		Currency: c.Currency,
+		Rate:     c.Rate,
+		Kind:     "inserted",
+		Source:   c.Source,
+	}
+	if !c.Seeded {
+		fetchedAt := c.FetchedAt
+		rc.IngestedAt = &fetchedAt
+	}
+	if c.Revised {
+		previous := c.Previous
+		rc.Previous = &previous
+		rc.Kind = "revised"
+	}
+	return rc
+}
+
+// encodeCursor hides the change sequence number behind an opaque, versioned
+// token so the sequence can change without breaking consumers that only echo
+// it back.
+func encodeCursor(seq int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursorPrefix + strconv.FormatInt(seq, 10)))
+}
+
+// decodeCursor reads a cursor of encodeCursor. An empty cursor is the start of
+// the feed.
+func decodeCursor(cursor string) (int64, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(b), cursorPrefix) {
+		return 0, errInvalidRequest
+	}
+	seq, err := strconv.ParseInt(strings.TrimPrefix(string(b), cursorPrefix), 10, 64)
+	if err != nil || seq < 0 {
+		return 0, errInvalidRequest
+	}
+	return seq, nil
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"errors"
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"github.com/huyhvq/eurofxref/pkg/repository"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// fakeChanges serves the change feed from memory, in sequence order.
+type fakeChanges struct {
+	repository.RateRepository
+	changes []model.RateChange
+	err     error
+}
+
+func (f *fakeChanges) GetChanges(afterSeq int64, limit int) ([]model.RateChange, error) {
+	res := make([]model.RateChange, 0)
+	for _, c := range f.changes {
+		if c.Seq > afterSeq && len(res) < limit {
+			res = append(res, c)
+		}
+	}
+	return res, f.err
+}
+
+func TestHandler_GetChanges(t *testing.T) {
+	fetched := time.Date(2021, 3, 31, 16, 5, 0, 0, time.UTC)
+	h := newTestHandler(Config{RateRepo: &fakeChanges{changes: []model.RateChange{
+		{Seq: 3, Time: "2021-03-26", Currency: "USD", Rate: 1.1795, Source: "ecb", Seeded: true,
+			FetchedAt: time.Date(2021, 3, 26, 0, 0, 0, 0, time.UTC)},
+		{Seq: 7, Time: "2021-03-31", Currency: "USD", Rate: 1.1725, Source: "ecb", FetchedAt: fetched},
+		{Seq: 8, Time: "2021-03-30", Currency: "GBP", Rate: 0.85209, Previous: 0.8521, Revised: true, Source: "ecb",
+			FetchedAt: fetched},
+	}}})
+
+	w := do(h.GetChanges, http.MethodGet, "/changes?limit=2", nil, false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var feed ChangeFeed
+	decode(t, w, &feed)
+	assert.True(t, feed.HasMore)
+	assert.Equal(t, []RateChange{
+		{Date: "2021-03-26", Currency: "USD", Rate: 1.1795, Kind: "inserted", Source: "ecb"},
+		{Date: "2021-03-31", Currency: "USD", Rate: 1.1725, Kind: "inserted", Source: "ecb", IngestedAt: &fetched},
+	}, feed.Changes)
+	assert.Equal(t, encodeCursor(7), feed.NextCursor)
+	assert.Contains(t, w.Body.String(), `"ingested_at":null`)
+
+	w = do(h.GetChanges, http.MethodGet, "/changes?since="+feed.NextCursor, nil, false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	feed = ChangeFeed{}
+	decode(t, w, &feed)
+	assert.False(t, feed.HasMore)
+	previous := 0.8521
+	assert.Equal(t, []RateChange{{Date: "2021-03-30", Currency: "GBP", Rate: 0.85209, Previous: &previous,
+		Kind: "revised", Source: "ecb", IngestedAt: &fetched}}, feed.Changes)
+	assert.Equal(t, encodeCursor(8), feed.NextCursor)
+
+	// An exhausted feed hands back the cursor it was given.
+	w = do(h.GetChanges, http.MethodGet, "/changes?since="+feed.NextCursor, nil, false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	feed = ChangeFeed{}
+	decode(t, w, &feed)
+	assert.Empty(t, feed.Changes)
+	assert.False(t, feed.HasMore)
+	assert.Equal(t, encodeCursor(8), feed.NextCursor)
+
+	for _, target := range []string{
+		"/changes?since=42",
+		"/changes?since=" + encodeCursor(-1),
+		"/changes?limit=0",
+		"/changes?limit=10001",
+	} {
+		w = do(h.GetChanges, http.MethodGet, target, nil, false)
+		assert.Equal(t, http.StatusBadRequest, w.Code, target)
+		assert.Equal(t, errInvalidRequest.Error(), errorOf(t, w))
+	}
+
+	w = do(newTestHandler(Config{RateRepo: &fakeChanges{err: errors.New("db down")}}).GetChanges,
+		http.MethodGet, "/changes", nil, false)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	w = do(h.GetChanges, http.MethodPost, "/changes", nil, false)
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
@@ -0,0 +1,252 @@
+package handler
+
+import (
+	"encoding/json"
+	"github.com/huyhvq/eurofxref/pkg/calendar"
+	"github.com/huyhvq/eurofxref/pkg/fx"
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"github.com/huyhvq/eurofxref/pkg/spread"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const maxBatchItems = 10000
+
+type ConversionItem struct {
+	ID     string  `json:"id,omitempty"`
+	Amount float64 `json:"amount"`
+	From   string  `json:"from"`
+	To     string  `json:"to"`
+	Date   string  `json:"date,omitempty"`
+}
+
+type ConversionResult struct {
+	ID         string   `json:"id,omitempty"`
+	Amount     float64  `json:"amount"`
+	From       string   `json:"from"`
+	To         string   `json:"to"`
+	Date       string   `json:"date,omitempty"`
+	RateDate   string   `json:"rate_date,omitempty"`
+	Rate       *float64 `json:"rate,omitempty"`
+	Bid        *float64 `json:"bid,omitempty"`
+	Mid        *float64 `json:"mid,omitempty"`
+	Ask        *float64 `json:"ask,omitempty"`
+	Result     *float64 `json:"result,omitempty"`
+	Derived    []string `json:"derived,omitempty"`
+	Overridden []string `json:"overridden,omitempty"`
+	Baskets    []string `json:"baskets,omitempty"`
+	Error      string   `json:"error,omitempty"`
+
+	err error
+}
+
+type ConversionBatch struct {
+	Profile string           `json:"profile,omitempty"`
+	Items   []ConversionItem `json:"items"`
+}
+
+type ConversionBatchResult struct {
+	Results []ConversionResult `json:"results"`
+}
+
+// Convert serves GET /convert?from=&to=&amount=&date=&profile=. Without a
+// date the latest rates are used.
+func (h *handler) Convert(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		errorRespond(w, http.StatusMethodNotAllowed, errInvalidMethod.Error())
+		return
+	}
+	q := r.URL.Query()
+	amount, err := strconv.ParseFloat(q.Get("amount"), 64)
+	if err != nil || q.Get("from") == "" || q.Get("to") == "" || !h.spreads.HasProfile(q.Get("profile")) {
+		errorRespond(w, http.StatusBadRequest, errInvalidRequest.Error())
+		return
+	}
+	results, err := h.convertBatch([]ConversionItem{{
+		Amount: amount,
+		From:   q.Get("from"),
+		To:     q.Get("to"),
+		Date:   q.Get("date"),
+	}}, q.Get("profile"))
+	if err != nil {
+		errorRespond(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if res := results[0]; res.err != nil {
+		conversionError(w, res.err)
+	} else {
+		jsonRespond(w, http.StatusOK, res)
+	}
+}
+
+// ConvertBatch serves POST /convert/batch. Every item is converted at the
+// rates of its own date and results are returned in the order of the items.
+// An item that cannot be converted carries its error instead of failing the
+// whole batch.
+func (h *handler) ConvertBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		errorRespond(w, http.StatusMethodNotAllowed, errInvalidMethod.Error())
+		return
+	}
+	var batch ConversionBatch
+	if err := json.NewDecoder(r.Body).Decode(&batch); err != nil || len(batch.Items) > maxBatchItems ||
+		!h.spreads.HasProfile(batch.Profile) {
+		errorRespond(w, http.StatusBadRequest, errInvalidRequest.Error())
+		return
+	}
+	results, err := h.convertBatch(batch.Items, batch.Profile)
+	if err != nil {
+		errorRespond(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	jsonRespond(w, http.StatusOK, &ConversionBatchResult{Results: results})
+}
+
+// convertBatch converts items with the rates of the latest publication on or
+// before each item's date and quotes bid and ask with the spreads of profile.
+// The result is always converted at mid. All dates are served from a single
+// range query; only a failing query is returned as an error.
+func (h *handler) convertBatch(items []ConversionItem, profile string) ([]ConversionResult, error) {
+	results := make([]ConversionResult, len(items))
+	dates := make([]time.Time, len(items))
+	var (
+		start, end time.Time
+		latest     bool
+	)
+	for i, item := range items {
+		results[i] = ConversionResult{
+			ID:     item.ID,
+			Amount: item.Amount,
+			From:   strings.ToUpper(strings.TrimSpace(item.From)),
+			To:     strings.ToUpper(strings.TrimSpace(item.To)),
+			Date:   item.Date,
+		}
+		if item.Date == "" || item.Date == "latest" {
+			latest = true
+			continue
+		}
+		d, err := time.ParseInLocation("2006-01-02", item.Date, time.UTC)
+		if err != nil {
+			results[i].fail(errInvalidRequest)
+			continue
+		}
+		dates[i] = calendar.OnOrBefore(d)
+		if start.IsZero() || dates[i].Before(start) {
+			start = dates[i]
+		}
+		if dates[i].After(end) {
+			end = dates[i]
+		}
+	}
+
+	if latest {
+		d, err := h.rateRepo.GetLatestDate()
+		if err != nil {
+			return nil, err
+		}
+		if d.IsZero() {
+			d = calendar.OnOrBefore(time.Now().UTC())
+		}
+		for i, item := range items {
+			if item.Date == "" || item.Date == "latest" {
+				dates[i] = d
+			}
+		}
+		if start.IsZero() || d.Before(start) {
+			start = d
+		}
+		if d.After(end) {
+			end = d
+		}
+	}
+	history := fx.NewHistory(nil, nil)
+	overridden := make(map[string][]model.Rate)
+	if !end.IsZero() {
+		// The earliest date may have no publication of its own, so the range
+		// starts at the closest stored date on or before it.
+		first, err := h.rateRepo.GetDateOnOrBefore(start)
+		if err != nil {
+			return nil, err
+		}
+		if !first.IsZero() {
+			start = first
+		}
+		rates, err := h.rateRepo.GetRatesBetween(start, end)
+		if err != nil {
+			return nil, err
+		}
+		history = fx.NewHistory(rates, h.pegs)
+		for _, r := range rates {
+			if r.OverrideID != 0 {
+				overridden[r.Time] = append(overridden[r.Time], r)
+			}
+		}
+	}
+
+	// Baskets are only loaded once an item names a code the rates lack, and
+	// priced once per rate date.
+	var (
+		all    []model.Basket
+		loaded bool
+	)
+	baskets := make(map[string][]string)
+	for i := range results {
+		res := &results[i]
+		if res.err != nil {
+			continue
+		}
+		table, d, ok := history.OnOrBefore(dates[i].Format("2006-01-02"))
+		if !ok || dates[i].IsZero() {
+			res.fail(errNoRates)
+			continue
+		}
+		v, rate, err := table.Convert(res.Amount, res.From, res.To)
+		if _, priced := baskets[d]; err == fx.ErrUnknownCurrency && !priced {
+			if !loaded {
+				if all, err = h.basketRepo.GetAll(); err != nil {
+					return nil, err
+				}
+				loaded = true
+			}
+			baskets[d] = addBaskets(table, all)
+			v, rate, err = table.Convert(res.Amount, res.From, res.To)
+		}
+		if err != nil {
+			res.fail(err)
+			continue
+		}
+		q, err := h.spreads.Quote(profile, res.From, res.To, rate)
+		if err != nil {
+			res.fail(err)
+			continue
+		}
+		res.RateDate = d
+		res.Rate = &rate
+		res.Bid, res.Mid, res.Ask = &q.Bid, &q.Mid, &q.Ask
+		res.Result = &v
+		res.Derived = h.derivedSymbols([]string{res.From, res.To}, d)
+		res.Overridden = overriddenSymbols(overridden[d], []string{res.From, res.To})
+		res.Baskets = basketSymbols(baskets[d], []string{res.From, res.To})
+	}
+	return results, nil
+}
+
+// fail records err as the reason res could not be converted.
+func (res *ConversionResult) fail(err error) {
+	res.err, res.Error = err, err.Error()
+}
+
+// conversionError responds with the status of a single conversion that
+// failed with err.
+func conversionError(w http.ResponseWriter, err error) {
+	switch err {
+	case errInvalidRequest, fx.ErrUnknownCurrency, spread.ErrUnknownProfile:
+		errorRespond(w, http.StatusBadRequest, err.Error())
+	case errNoRates:
+		errorRespond(w, http.StatusNotFound, err.Error())
+	default:
+		errorRespond(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,228 @@
+package handler
+
+import (
+	"encoding/csv"
+	"github.com/huyhvq/eurofxref/pkg/fx"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxCSVBytes           = 32 << 20
+	defaultCSVPrecision   = 2
+	defaultAmountColumn   = "amount"
+	defaultCurrencyColumn = "currency"
+	defaultDateColumn     = "date"
+	csvChunkRows          = 1000
+)
+
+// csvConversion is how the rows of a CSV file are converted.
+type csvConversion struct {
+	to                              string
+	amountCol, currencyCol, dateCol int
+	precision                       int
+	profile                         string
+}
+
+// columns returns the names of the columns appended to every row. bid and
+// ask are only written for a client profile.
+func (c csvConversion) columns() []string {
+	if c.profile == "" {
+		return []string{"converted_amount", "rate", "rate_date", "error"}
+	}
+	return []string{"converted_amount", "rate", "bid", "ask", "rate_date", "error"}
+}
+
+// errorRow returns an output row of a file with n columns that carries only
+// msg in its error column.
+func (c csvConversion) errorRow(n int, msg string) []string {
+	row := make([]string, n+len(c.columns()))
+	row[len(row)-1] = msg
+	return row
+}
+
+// ConvertCSV serves POST /convert/csv. The body is a CSV file with a header
+// row, sent as is or as the "file" field of a multipart form. Every row is
+// converted from its currency column into the to currency at the rates of
+// its date column and written back with converted_amount, rate, rate_date
+// and error columns appended. Column names are mapped with the
+// amount_column, currency_column and date_column parameters; without a date
+// column the latest rates are used. With a client profile, passed as a
+// parameter or a form field, bid and ask columns follow the rate. Rows are
+// read, converted and written in chunks of csvChunkRows; a row that cannot be
+// parsed after the first chunk was written ends the output with a row
+// carrying only the error.
+func (h *handler) ConvertCSV(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		errorRespond(w, http.StatusMethodNotAllowed, errInvalidMethod.Error())
+		return
+	}
+	q := r.URL.Query()
+	to := strings.ToUpper(strings.TrimSpace(q.Get("to")))
+	if to == "" {
+		to = fx.Base
+	}
+	precision := defaultCSVPrecision
+	if q.Get("precision") != "" {
+		p, err := parsePrecision(r)
+		if err != nil {
+			errorRespond(w, http.StatusBadRequest, errInvalidRequest.Error())
+			return
+		}
+		precision = p
+	}
+	comma := ','
+	if d := q.Get("delimiter"); d != "" {
+		c, size := utf8.DecodeRuneInString(d)
+		if size != len(d) || c == '"' || c == '\r' || c == '\n' {
+			errorRespond(w, http.StatusBadRequest, errInvalidRequest.Error())
+			return
+		}
+		comma = c
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxCSVBytes)
+	var body io.Reader = r.Body
+	profile := q.Get("profile")
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		f, _, err := r.FormFile("file")
+		if err != nil {
+			errorRespond(w, http.StatusBadRequest, errInvalidRequest.Error())
+			return
+		}
+		defer f.Close()
+		body = f
+		if v := r.MultipartForm.Value["profile"]; len(v) > 0 {
+			profile = v[0]
+		}
+	}
+	profile = strings.TrimSpace(profile)
+	if !h.spreads.HasProfile(profile) {
+		errorRespond(w, http.StatusBadRequest, errInvalidRequest.Error())
+		return
+	}
+	cr := csv.NewReader(body)
+	cr.Comma = comma
+	header, err := cr.Read()
+	if err != nil {
+		errorRespond(w, http.StatusBadRequest, errInvalidRequest.Error())
+		return
+	}
+	c := csvConversion{
+		to:          to,
+		amountCol:   csvColumn(header, q.Get("amount_column"), defaultAmountColumn),
+		currencyCol: csvColumn(header, q.Get("currency_column"), defaultCurrencyColumn),
+		dateCol:     csvColumn(header, q.Get("date_column"), defaultDateColumn),
+		precision:   precision,
+		profile:     profile,
+	}
+	if c.amountCol < 0 || c.currencyCol < 0 || (c.dateCol < 0 && q.Get("date_column") != "") {
+		errorRespond(w, http.StatusBadRequest, errInvalidRequest.Error())
+		return
+	}
+
+	rows, readErr := readCSVRows(cr, csvChunkRows)
+	if readErr != nil && readErr != io.EOF {
+		errorRespond(w, http.StatusBadRequest, errInvalidRequest.Error())
+		return
+	}
+	out, err := h.convertCSVRows(rows, c)
+	if err != nil {
+		errorRespond(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.WriteHeader(http.StatusOK)
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+	cw.Write(append(header, c.columns()...))
+	for {
+		cw.WriteAll(out)
+		if readErr != nil {
+			if readErr != io.EOF {
+				cw.WriteAll([][]string{c.errorRow(len(header), errInvalidRequest.Error())})
+			}
+			return
+		}
+		rows, readErr = readCSVRows(cr, csvChunkRows)
+		if out, err = h.convertCSVRows(rows, c); err != nil {
+			cw.WriteAll([][]string{c.errorRow(len(header), err.Error())})
+			return
+		}
+	}
+}
+
+// readCSVRows reads up to n records. It returns io.EOF with the last records
+// of the file, and any other error with the records read before it.
+func readCSVRows(cr *csv.Reader, n int) ([][]string, error) {
+	rows := make([][]string, 0, n)
+	for len(rows) < n {
+		row, err := cr.Read()
+		if err != nil {
+			return rows, err
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// convertCSVRows converts rows as c describes and returns them with the
+// columns of c appended.
+func (h *handler) convertCSVRows(rows [][]string, c csvConversion) ([][]string, error) {
+	items := make([]ConversionItem, len(rows))
+	invalid := make([]bool, len(rows))
+	for i, row := range rows {
+		items[i] = ConversionItem{From: row[c.currencyCol], To: c.to}
+		if c.dateCol >= 0 {
+			items[i].Date = strings.TrimSpace(row[c.dateCol])
+		}
+		amount, err := strconv.ParseFloat(strings.TrimSpace(row[c.amountCol]), 64)
+		if err != nil {
+			invalid[i] = true
+			continue
+		}
+		items[i].Amount = amount
+	}
+	results, err := h.convertBatch(items, c.profile)
+	if err != nil {
+		return nil, err
+	}
+	out := make([][]string, len(rows))
+	for i, res := range results {
+		var amount, rate, bid, ask string
+		if invalid[i] {
+			res = ConversionResult{}
+			res.fail(errInvalidRequest)
+		}
+		if res.err == nil {
+			amount = strconv.FormatFloat(fx.Round(*res.Result, c.precision), 'f', c.precision, 64)
+			rate = strconv.FormatFloat(*res.Rate, 'f', -1, 64)
+			bid = strconv.FormatFloat(*res.Bid, 'f', -1, 64)
+			ask = strconv.FormatFloat(*res.Ask, 'f', -1, 64)
+		}
+		if c.profile == "" {
+			out[i] = append(rows[i], amount, rate, res.RateDate, res.Error)
+		} else {
+			out[i] = append(rows[i], amount, rate, bid, ask, res.RateDate, res.Error)
+		}
+	}
+	return out, nil
+}
+
+// csvColumn returns the index of the header column named name, or def when
+// name is empty, compared case-insensitively. It is -1 when there is none.
+func csvColumn(header []string, name, def string) int {
+	if name == "" {
+		name = def
+	}
+	for i, h := range header {
+		if strings.EqualFold(strings.TrimSpace(h), name) {
+			return i
+		}
+	}
+	return -1
+}
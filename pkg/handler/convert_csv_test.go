@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/csv"
+	"github.com/stretchr/testify/assert"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// rateOf formats the rate into EUR of a currency with EUR rate eur as the
+// rate column does.
+func rateOf(eur float64) string {
+	return strconv.FormatFloat(1/eur, 'f', -1, 64)
+}
+
+func TestHandler_ConvertCSV(t *testing.T) {
+	h := newTestHandler(Config{})
+
+	body := "ref;Amount;Currency;Date\na;11.795;USD;2021-03-26\nb;x;USD;2021-03-26\nc;1;XXX;2021-03-26\nd;2;JPY;\n"
+	w := do(h.ConvertCSV, http.MethodPost, "/convert/csv?delimiter=%3B&precision=3", strings.NewReader(body), false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+	cr := csv.NewReader(w.Body)
+	cr.Comma = ';'
+	records, err := cr.ReadAll()
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{
+		{"ref", "Amount", "Currency", "Date", "converted_amount", "rate", "rate_date", "error"},
+		{"a", "11.795", "USD", "2021-03-26", "10.000", rateOf(1.1795), "2021-03-26", ""},
+		{"b", "x", "USD", "2021-03-26", "", "", "", errInvalidRequest.Error()},
+		{"c", "1", "XXX", "2021-03-26", "", "", "", "unknown currency"},
+		{"d", "2", "JPY", "", "0.015", rateOf(129.61), "2021-03-29", ""},
+	}, records)
+
+	for _, tc := range []struct {
+		name   string
+		target string
+		body   string
+	}{
+		{"empty", "/convert/csv", ""},
+		{"missing column", "/convert/csv?currency_column=ccy", "amount,currency\n1,USD\n"},
+		{"missing date column", "/convert/csv?date_column=day", "amount,currency\n1,USD\n"},
+		{"malformed first chunk", "/convert/csv", "amount,currency\n1,USD,x\n"},
+		{"invalid delimiter", "/convert/csv?delimiter=%22", "amount,currency\n1,USD\n"},
+		{"invalid precision", "/convert/csv?precision=x", "amount,currency\n1,USD\n"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := do(h.ConvertCSV, http.MethodPost, tc.target, strings.NewReader(tc.body), false)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+
+	w = do(h.ConvertCSV, http.MethodGet, "/convert/csv", nil, false)
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+func TestHandler_ConvertCSV_Chunks(t *testing.T) {
+	h := newTestHandler(Config{})
+	row := "1.1765,USD\n"
+	body := "amount,currency\n" + strings.Repeat(row, csvChunkRows*2+1)
+	w := do(h.ConvertCSV, http.MethodPost, "/convert/csv", strings.NewReader(body), false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	records, err := csv.NewReader(w.Body).ReadAll()
+	assert.Nil(t, err)
+	assert.Len(t, records, csvChunkRows*2+2)
+	assert.Equal(t, []string{"1.1765", "USD", "1.00", rateOf(1.1765), "2021-03-29", ""}, records[len(records)-1])
+
+	// A malformed row after the first chunk ends the output with an error row.
+	body = "amount,currency\n" + strings.Repeat(row, csvChunkRows+1) + "1,USD,x\n" + row
+	w = do(h.ConvertCSV, http.MethodPost, "/convert/csv", strings.NewReader(body), false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	records, err = csv.NewReader(w.Body).ReadAll()
+	assert.Nil(t, err)
+	assert.Len(t, records, csvChunkRows+3)
+	assert.Equal(t, []string{"", "", "", "", "", errInvalidRequest.Error()}, records[len(records)-1])
+}
+
+func TestHandler_ConvertCSV_Multipart(t *testing.T) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	fw, err := mw.CreateFormFile("file", "amounts.csv")
+	assert.Nil(t, err)
+	fw.Write([]byte("amount,currency,date\n0.8556,GBP,2021-03-26\n"))
+	mw.Close()
+
+	r := httptest.NewRequest(http.MethodPost, "/convert/csv", &buf)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	newTestHandler(Config{}).ConvertCSV(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	records, err := csv.NewReader(w.Body).ReadAll()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"0.8556", "GBP", "2021-03-26", "1.00", rateOf(0.8556), "2021-03-26", ""}, records[1])
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/huyhvq/eurofxref/pkg/fx"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestHandler_Convert(t *testing.T) {
+	h := newTestHandler(Config{})
+
+	w := do(h.Convert, http.MethodGet, "/convert?from=usd&to=gbp&amount=100&date=2021-03-27", nil, false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var res ConversionResult
+	decode(t, w, &res)
+	assert.Equal(t, "USD", res.From)
+	assert.Equal(t, "GBP", res.To)
+	assert.Equal(t, "2021-03-26", res.RateDate)
+	assert.InDelta(t, 0.8556/1.1795, *res.Rate, 1e-12)
+	assert.InDelta(t, 100*0.8556/1.1795, *res.Result, 1e-9)
+	assert.Equal(t, *res.Rate, *res.Bid)
+	assert.Equal(t, *res.Rate, *res.Ask)
+
+	w = do(h.Convert, http.MethodGet, "/convert?from=EUR&to=JPY&amount=2", nil, false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	res = ConversionResult{}
+	decode(t, w, &res)
+	assert.Equal(t, "2021-03-29", res.RateDate)
+	assert.Equal(t, 259.22, *res.Result)
+
+	for _, tc := range []struct {
+		name   string
+		target string
+		code   int
+		err    string
+	}{
+		{"missing amount", "/convert?from=EUR&to=USD", http.StatusBadRequest, errInvalidRequest.Error()},
+		{"missing currency", "/convert?from=EUR&amount=1", http.StatusBadRequest, errInvalidRequest.Error()},
+		{"unknown profile", "/convert?from=EUR&to=USD&amount=1&profile=retail", http.StatusBadRequest, errInvalidRequest.Error()},
+		{"invalid date", "/convert?from=EUR&to=USD&amount=1&date=26-03-2021", http.StatusBadRequest, errInvalidRequest.Error()},
+		{"unknown currency", "/convert?from=EUR&to=XXX&amount=1", http.StatusBadRequest, fx.ErrUnknownCurrency.Error()},
+		{"before the first publication", "/convert?from=EUR&to=USD&amount=1&date=2021-03-01", http.StatusNotFound, errNoRates.Error()},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := do(h.Convert, http.MethodGet, tc.target, nil, false)
+			assert.Equal(t, tc.code, w.Code)
+			assert.Equal(t, tc.err, errorOf(t, w))
+		})
+	}
+
+	w = do(newTestHandler(Config{RateRepo: &fakeRates{err: errors.New("db down")}}).Convert,
+		http.MethodGet, "/convert?from=EUR&to=USD&amount=1", nil, false)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	w = do(h.Convert, http.MethodPost, "/convert", nil, false)
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+func TestHandler_ConvertBatch(t *testing.T) {
+	h := newTestHandler(Config{})
+
+	body := `{"items": [
+		{"id": "a", "amount": 10, "from": "EUR", "to": "USD", "date": "2021-03-26"},
+		{"id": "b", "amount": 10, "from": "EUR", "to": "USD"},
+		{"id": "c", "amount": 10, "from": "EUR", "to": "XXX", "date": "2021-03-26"},
+		{"id": "d", "amount": 10, "from": "EUR", "to": "USD", "date": "yesterday"},
+		{"id": "e", "amount": 10, "from": "EUR", "to": "USD", "date": "2021-03-01"}
+	]}`
+	w := do(h.ConvertBatch, http.MethodPost, "/convert/batch", strings.NewReader(body), false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var batch ConversionBatchResult
+	decode(t, w, &batch)
+	assert.Len(t, batch.Results, 5)
+	assert.Equal(t, "a", batch.Results[0].ID)
+	assert.Equal(t, 11.795, *batch.Results[0].Result)
+	assert.Equal(t, "2021-03-29", batch.Results[1].RateDate)
+	assert.Equal(t, fx.ErrUnknownCurrency.Error(), batch.Results[2].Error)
+	assert.Nil(t, batch.Results[2].Result)
+	assert.Equal(t, errInvalidRequest.Error(), batch.Results[3].Error)
+	assert.Equal(t, errNoRates.Error(), batch.Results[4].Error)
+
+	items := strings.Repeat(`{"amount": 1, "from": "EUR", "to": "USD"},`, maxBatchItems)
+	for _, body := range []string{
+		`{"items": `,
+		`{"profile": "retail", "items": []}`,
+		fmt.Sprintf(`{"items": [%s{"amount": 1, "from": "EUR", "to": "USD"}]}`, items),
+	} {
+		w = do(h.ConvertBatch, http.MethodPost, "/convert/batch", strings.NewReader(body), false)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	}
+
+	w = do(newTestHandler(Config{RateRepo: &fakeRates{err: errors.New("db down")}}).ConvertBatch,
+		http.MethodPost, "/convert/batch", strings.NewReader(body), false)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	w = do(h.ConvertBatch, http.MethodGet, "/convert/batch", nil, false)
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"github.com/huyhvq/eurofxref/pkg/fx"
+	"github.com/huyhvq/eurofxref/pkg/indicators"
+	"github.com/huyhvq/eurofxref/pkg/stats"
+	"net/http"
+	"sort"
+)
+
+type ExchangeRateCorrelation struct {
+	Base         string       `json:"base"`
+	Start        string       `json:"start"`
+	End          string       `json:"end"`
+	Method       string       `json:"method"`
+	Observations int          `json:"observations"`
+	Symbols      []string     `json:"symbols"`
+	Matrix       [][]*float64 `json:"matrix"`
+	Derived      []string     `json:"derived,omitempty"`
+	Overridden   []string     `json:"overridden,omitempty"`
+}
+
+// GetRatesCorrelation correlates the daily log returns of every pair of
+// symbols over the publication dates they have in common.
+func (h *handler) GetRatesCorrelation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		errorRespond(w, http.StatusMethodNotAllowed, errInvalidMethod.Error())
+		return
+	}
+	start, end, err := parseRange(r, true)
+	if err != nil {
+		errorRespond(w, http.StatusBadRequest, errInvalidRequest.Error())
+		return
+	}
+	method := r.URL.Query().Get("method")
+	correlate := stats.Pearson
+	switch method {
+	case "", "pearson":
+		method = "pearson"
+	case "spearman":
+		correlate = stats.Spearman
+	default:
+		errorRespond(w, http.StatusBadRequest, errInvalidRequest.Error())
+		return
+	}
+
+	base := parseBase(r)
+	symbols := parseSymbols(r)
+	series, overridden, err := h.timeSeries(base, symbols, start, end, 0)
+	if err == fx.ErrUnknownCurrency {
+		errorRespond(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		errorRespond(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if len(symbols) == 0 {
+		for c := range series {
+			symbols = append(symbols, c)
+		}
+		sort.Strings(symbols)
+	}
+
+	dates, values := fx.Align(series, symbols)
+	returns := make([][]float64, len(values))
+	for i, vs := range values {
+		if len(vs) > 1 {
+			returns[i] = indicators.LogReturns(vs)[1:]
+		}
+	}
+	m := make([][]*float64, len(symbols))
+	for i := range symbols {
+		m[i] = make([]*float64, len(symbols))
+	}
+	for i := range symbols {
+		for j := i; j < len(symbols); j++ {
+			c := optional(correlate(returns[i], returns[j]))
+			if i == j && c != nil {
+				one := float64(1)
+				c = &one
+			}
+			m[i][j], m[j][i] = c, c
+		}
+	}
+	observations := 0
+	if len(dates) > 1 {
+		observations = len(dates) - 1
+	}
+	jsonRespond(w, http.StatusOK, &ExchangeRateCorrelation{
+		Base:         base,
+		Start:        start.Format("2006-01-02"),
+		End:          end.Format("2006-01-02"),
+		Method:       method,
+		Observations: observations,
+		Symbols:      symbols,
+		Matrix:       m,
+		Derived:      h.derivedSymbols(symbols, end.Format("2006-01-02")),
+		Overridden:   overridden,
+	})
+}
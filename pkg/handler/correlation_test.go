@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"github.com/huyhvq/eurofxref/pkg/fx"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestHandler_GetRatesCorrelation(t *testing.T) {
+	h := newTestHandler(Config{RateRepo: &fakeRates{rates: weekRates}})
+
+	w := do(h.GetRatesCorrelation, http.MethodGet, "/rates/correlation?start=2021-03-22&end=2021-03-26&symbols=usd,gbp", nil, false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var c ExchangeRateCorrelation
+	decode(t, w, &c)
+	assert.Equal(t, "pearson", c.Method)
+	assert.Equal(t, 4, c.Observations)
+	assert.Equal(t, []string{"USD", "GBP"}, c.Symbols)
+	assert.Len(t, c.Matrix, 2)
+	assert.Equal(t, float64(1), *c.Matrix[0][0])
+	assert.Equal(t, *c.Matrix[0][1], *c.Matrix[1][0])
+
+	w = do(h.GetRatesCorrelation, http.MethodGet, "/rates/correlation?start=2021-03-22&end=2021-03-26&method=spearman", nil, false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	c = ExchangeRateCorrelation{}
+	decode(t, w, &c)
+	assert.Equal(t, "spearman", c.Method)
+	assert.Equal(t, []string{"GBP", "USD"}, c.Symbols)
+
+	w = do(h.GetRatesCorrelation, http.MethodGet, "/rates/correlation?start=2021-03-22&end=2021-03-26&symbols=USD,XXX", nil, false)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, fx.ErrUnknownCurrency.Error(), errorOf(t, w))
+
+	for _, target := range []string{
+		"/rates/correlation?start=2021-03-22",
+		"/rates/correlation?start=2021-03-22&end=2021-03-26&method=kendall",
+		"/rates/correlation?start=2021-03-22&end=2021-03-26&base=XXX",
+	} {
+		w = do(h.GetRatesCorrelation, http.MethodGet, target, nil, false)
+		assert.Equal(t, http.StatusBadRequest, w.Code, target)
+	}
+
+	w = do(h.GetRatesCorrelation, http.MethodPost, "/rates/correlation", nil, false)
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"github.com/huyhvq/eurofxref/pkg/currency"
+	"github.com/huyhvq/eurofxref/pkg/fx"
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"net/http"
+	"sort"
+)
+
+type CurrencyList struct {
+	Currencies []CurrencyInfo `json:"currencies"`
+}
+
+type CurrencyInfo struct {
+	Code        string  `json:"code"`
+	Name        string  `json:"name,omitempty"`
+	NumericCode string  `json:"numeric_code,omitempty"`
+	MinorUnits  *int    `json:"minor_units,omitempty"`
+	FirstDate   string  `json:"first_date"`
+	LastDate    string  `json:"last_date"`
+	Published   bool    `json:"published"`
+	Derived     bool    `json:"derived"`
+	Peg         string  `json:"peg,omitempty"`
+	Ratio       float64 `json:"ratio,omitempty"`
+}
+
+// GetCurrencies lists every currency with stored rates, EUR itself and the
+// derived currencies with their ISO 4217 details. A currency counts as still
+// published when it has a rate on the latest stored date.
+func (h *handler) GetCurrencies(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		errorRespond(w, http.StatusMethodNotAllowed, errInvalidMethod.Error())
+		return
+	}
+	ranges, err := h.rateRepo.GetCurrencyRanges()
+	if err != nil {
+		errorRespond(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	jsonRespond(w, http.StatusOK, h.currencyListTransform(ranges))
+}
+
+func (h *handler) currencyListTransform(ranges []model.CurrencyRange) *CurrencyList {
+	var first, last string
+	for _, cr := range ranges {
+		if first == "" || cr.First < first {
+			first = cr.First
+		}
+		if cr.Last > last {
+			last = cr.Last
+		}
+	}
+	list := &CurrencyList{Currencies: make([]CurrencyInfo, 0, len(ranges)+1)}
+	if len(ranges) > 0 {
+		ranges = append(ranges, model.CurrencyRange{Currency: fx.Base, First: first, Last: last})
+	}
+	pegs := make(map[string]currency.Derived)
+	for _, cr := range h.peggedRanges(ranges) {
+		pegs[cr.Currency] = cr.peg
+		ranges = append(ranges, cr.CurrencyRange)
+	}
+	for _, cr := range ranges {
+		info := CurrencyInfo{
+			Code:      cr.Currency,
+			FirstDate: cr.First,
+			LastDate:  cr.Last,
+			Published: cr.Last == last,
+			Derived:   h.pegs.IsDerived(cr.Currency, last),
+		}
+		if d, ok := pegs[cr.Currency]; ok {
+			info.Derived = true
+			info.Peg = d.Peg
+			info.Ratio = d.Ratio
+		}
+		if c, ok := currency.Lookup(cr.Currency); ok {
+			units := c.MinorUnits
+			info.Name = c.Name
+			info.NumericCode = c.Numeric
+			info.MinorUnits = &units
+		}
+		list.Currencies = append(list.Currencies, info)
+	}
+	sort.Slice(list.Currencies, func(i, j int) bool {
+		return list.Currencies[i].Code < list.Currencies[j].Code
+	})
+	return list
+}
+
+type peggedRange struct {
+	model.CurrencyRange
+	peg currency.Derived
+}
+
+// peggedRanges returns the range each derived currency not published itself
+// can be priced over: the validity of its definitions clipped to the range of
+// its peg. The peg reported is the latest definition.
+func (h *handler) peggedRanges(ranges []model.CurrencyRange) []peggedRange {
+	byCode := make(map[string]model.CurrencyRange, len(ranges))
+	for _, cr := range ranges {
+		byCode[cr.Currency] = cr
+	}
+	pegged := make([]peggedRange, 0)
+	index := make(map[string]int)
+	for _, d := range h.pegs.All() {
+		if _, ok := byCode[d.Code]; ok {
+			continue
+		}
+		p, ok := byCode[d.Peg]
+		if !ok {
+			continue
+		}
+		first, last := p.First, p.Last
+		if d.ValidFrom > first {
+			first = d.ValidFrom
+		}
+		if d.ValidTo != "" && d.ValidTo < last {
+			last = d.ValidTo
+		}
+		if first > last {
+			continue
+		}
+		i, ok := index[d.Code]
+		if !ok {
+			index[d.Code] = len(pegged)
+			pegged = append(pegged, peggedRange{
+				CurrencyRange: model.CurrencyRange{Currency: d.Code, First: first, Last: last},
+				peg:           d,
+			})
+			continue
+		}
+		if first < pegged[i].First {
+			pegged[i].First = first
+		}
+		if last >= pegged[i].Last {
+			pegged[i].Last = last
+			pegged[i].peg = d
+		}
+	}
+	return pegged
+}
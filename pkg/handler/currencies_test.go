@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"errors"
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestHandler_GetCurrencies(t *testing.T) {
+	rates := append([]model.Rate{{Time: "2021-03-25", Currency: "RUB", Rate: 88.16}}, testRates...)
+	h := newTestHandler(Config{RateRepo: &fakeRates{rates: rates}})
+
+	w := do(h.GetCurrencies, http.MethodGet, "/currencies", nil, false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var list CurrencyList
+	decode(t, w, &list)
+	codes := make([]string, len(list.Currencies))
+	for i, c := range list.Currencies {
+		codes[i] = c.Code
+	}
+	assert.Equal(t, []string{"EUR", "GBP", "JPY", "RUB", "USD"}, codes)
+
+	eur := list.Currencies[0]
+	assert.Equal(t, "Euro", eur.Name)
+	assert.Equal(t, "2021-03-25", eur.FirstDate)
+	assert.Equal(t, "2021-03-29", eur.LastDate)
+	assert.True(t, eur.Published)
+
+	jpy := list.Currencies[2]
+	assert.Equal(t, "392", jpy.NumericCode)
+	assert.Equal(t, 0, *jpy.MinorUnits)
+	assert.Equal(t, "2021-03-26", jpy.FirstDate)
+
+	rub := list.Currencies[3]
+	assert.Equal(t, "2021-03-25", rub.LastDate)
+	assert.False(t, rub.Published)
+
+	w = do(newTestHandler(Config{RateRepo: &fakeRates{rates: []model.Rate{}}}).GetCurrencies,
+		http.MethodGet, "/currencies", nil, false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	list = CurrencyList{}
+	decode(t, w, &list)
+	assert.Empty(t, list.Currencies)
+
+	w = do(newTestHandler(Config{RateRepo: &fakeRates{err: errors.New("db down")}}).GetCurrencies,
+		http.MethodGet, "/currencies", nil, false)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	w = do(h.GetCurrencies, http.MethodPost, "/currencies", nil, false)
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
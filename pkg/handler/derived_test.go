@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"github.com/huyhvq/eurofxref/pkg/currency"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestHandler_Pegs(t *testing.T) {
+	pegs, err := currency.NewPegs([]currency.Derived{
+		{Code: "AED", Peg: "USD", Ratio: 3.6725},
+		{Code: "XYZ", Peg: "USD", Ratio: 2, ValidFrom: "2021-03-29"},
+	})
+	assert.Nil(t, err)
+	h := newTestHandler(Config{Pegs: pegs})
+
+	w := do(h.GetRatesByDate, http.MethodGet, "/rates/2021-03-26", nil, false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var er ExchangeRate
+	decode(t, w, &er)
+	assert.InDelta(t, 1.1795*3.6725, er.Rates["AED"], 1e-12)
+	assert.NotContains(t, er.Rates, "XYZ")
+	assert.Equal(t, []string{"AED"}, er.Derived)
+
+	w = do(h.GetLatestRates, http.MethodGet, "/rates/latest", nil, false)
+	er = ExchangeRate{}
+	decode(t, w, &er)
+	assert.Equal(t, 2*1.1765, er.Rates["XYZ"])
+	assert.Equal(t, []string{"AED", "XYZ"}, er.Derived)
+
+	w = do(h.Convert, http.MethodGet, "/convert?from=AED&to=USD&amount=3.6725&date=2021-03-26", nil, false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var res ConversionResult
+	decode(t, w, &res)
+	assert.InDelta(t, 1, *res.Result, 1e-12)
+
+	w = do(h.GetCurrencies, http.MethodGet, "/currencies", nil, false)
+	var list CurrencyList
+	decode(t, w, &list)
+	byCode := make(map[string]CurrencyInfo)
+	for _, c := range list.Currencies {
+		byCode[c.Code] = c
+	}
+	assert.Equal(t, "USD", byCode["AED"].Peg)
+	assert.True(t, byCode["AED"].Derived)
+	assert.Equal(t, "2021-03-29", byCode["XYZ"].FirstDate)
+
+	// Without pegs nothing is derived.
+	w = do(newTestHandler(Config{}).GetRatesByDate, http.MethodGet, "/rates/2021-03-26", nil, false)
+	er = ExchangeRate{}
+	decode(t, w, &er)
+	assert.NotContains(t, er.Rates, "AED")
+	assert.Empty(t, er.Derived)
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"github.com/huyhvq/eurofxref/pkg/calendar"
+	"github.com/huyhvq/eurofxref/pkg/fx"
+	"net/http"
+	"strings"
+)
+
+type ExchangeRateFluctuation struct {
+	Base       string                     `json:"base"`
+	StartDate  string                     `json:"start_date"`
+	EndDate    string                     `json:"end_date"`
+	Rates      map[string]RateFluctuation `json:"rates"`
+	Derived    []string                   `json:"derived,omitempty"`
+	Overridden []string                   `json:"overridden,omitempty"`
+}
+
+type RateFluctuation struct {
+	StartRate float64 `json:"start_rate"`
+	EndRate   float64 `json:"end_rate"`
+	Change    float64 `json:"change"`
+	ChangePct float64 `json:"change_pct"`
+}
+
+func (h *handler) GetRatesFluctuation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		errorRespond(w, http.StatusMethodNotAllowed, errInvalidMethod.Error())
+		return
+	}
+	start, end, err := parseRange(r, true)
+	if err != nil {
+		errorRespond(w, http.StatusBadRequest, errInvalidRequest.Error())
+		return
+	}
+	sr, st, err := h.ratesOnOrBefore(calendar.OnOrBefore(start))
+	if err != nil {
+		errorRespond(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	er, et, err := h.ratesOnOrBefore(calendar.OnOrBefore(end))
+	if err != nil {
+		errorRespond(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if len(sr) == 0 || len(er) == 0 {
+		errorRespond(w, http.StatusNotFound, errNoRates.Error())
+		return
+	}
+	base := parseBase(r)
+	symbols := parseSymbols(r)
+	startTable, endTable := fx.NewTable(sr), fx.NewTable(er)
+	if len(symbols) > 0 {
+		startTable.WithFixed(st.Format("2006-01-02"))
+		endTable.WithFixed(et.Format("2006-01-02"))
+	}
+	startTable.WithPegged(h.pegs, st.Format("2006-01-02"))
+	endTable.WithPegged(h.pegs, et.Format("2006-01-02"))
+	changes, err := fx.Fluctuation(startTable, endTable, base, symbols)
+	if err != nil {
+		errorRespond(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	rs := make(map[string]RateFluctuation, len(changes))
+	codes := make([]string, 0, len(changes))
+	for c, ch := range changes {
+		codes = append(codes, c)
+		rs[c] = RateFluctuation{
+			StartRate: ch.StartRate,
+			EndRate:   ch.EndRate,
+			Change:    ch.Change,
+			ChangePct: ch.ChangePct,
+		}
+	}
+	jsonRespond(w, http.StatusOK, &ExchangeRateFluctuation{
+		Base:       base,
+		StartDate:  st.Format("2006-01-02"),
+		EndDate:    et.Format("2006-01-02"),
+		Rates:      rs,
+		Derived:    h.derivedSymbols(codes, et.Format("2006-01-02")),
+		Overridden: overriddenSymbols(append(sr, er...), codes),
+	})
+}
+
+// parseBase reads the base query parameter, defaulting to EUR.
+func parseBase(r *http.Request) string {
+	if b := strings.TrimSpace(r.URL.Query().Get("base")); b != "" {
+		return strings.ToUpper(b)
+	}
+	return fx.Base
+}
+
+// parseBases reads a comma-separated list of bases, dropping repeats. It
+// defaults to the euro.
+func parseBases(r *http.Request) []string {
+	bases := make([]string, 0)
+	seen := make(map[string]struct{})
+	for _, b := range strings.Split(r.URL.Query().Get("base"), ",") {
+		b = strings.ToUpper(strings.TrimSpace(b))
+		if _, ok := seen[b]; ok || b == "" {
+			continue
+		}
+		seen[b] = struct{}{}
+		bases = append(bases, b)
+	}
+	if len(bases) == 0 {
+		return []string{fx.Base}
+	}
+	return bases
+}
@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestHandler_GetRatesFluctuation(t *testing.T) {
+	h := newTestHandler(Config{})
+
+	w := do(h.GetRatesFluctuation, http.MethodGet, "/rates/fluctuation?start=2021-03-27&end=2021-03-29&symbols=usd,jpy", nil, false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var f ExchangeRateFluctuation
+	decode(t, w, &f)
+	assert.Equal(t, "EUR", f.Base)
+	assert.Equal(t, "2021-03-26", f.StartDate)
+	assert.Equal(t, "2021-03-29", f.EndDate)
+	assert.Len(t, f.Rates, 2)
+	usd := f.Rates["USD"]
+	assert.Equal(t, 1.1795, usd.StartRate)
+	assert.Equal(t, 1.1765, usd.EndRate)
+	assert.InDelta(t, -0.003, usd.Change, 1e-12)
+	assert.InDelta(t, -0.003/1.1795*100, usd.ChangePct, 1e-9)
+
+	w = do(h.GetRatesFluctuation, http.MethodGet, "/rates/fluctuation?start=2021-03-26&end=2021-03-29&base=gbp", nil, false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	f = ExchangeRateFluctuation{}
+	decode(t, w, &f)
+	assert.Equal(t, "GBP", f.Base)
+	assert.Len(t, f.Rates, 3)
+	assert.InDelta(t, 1/0.8556, f.Rates["EUR"].StartRate, 1e-12)
+
+	for _, tc := range []struct {
+		target string
+		code   int
+	}{
+		{"/rates/fluctuation?start=2021-03-26", http.StatusBadRequest},
+		{"/rates/fluctuation?start=2021-03-29&end=2021-03-26", http.StatusBadRequest},
+		{"/rates/fluctuation?start=2021-03-26&end=2021-03-29&symbols=XXX", http.StatusBadRequest},
+		{"/rates/fluctuation?start=2021-03-01&end=2021-03-29", http.StatusNotFound},
+	} {
+		w = do(h.GetRatesFluctuation, http.MethodGet, tc.target, nil, false)
+		assert.Equal(t, tc.code, w.Code, tc.target)
+	}
+
+	w = do(h.GetRatesFluctuation, http.MethodPost, "/rates/fluctuation", nil, false)
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
@@ -1,11 +1,22 @@
 package handler
 
 import (
+	"crypto/subtle"
 	"encoding/json"
 	"errors"
+	"github.com/huyhvq/eurofxref/pkg/alert"
+	"github.com/huyhvq/eurofxref/pkg/calendar"
+	"github.com/huyhvq/eurofxref/pkg/currency"
+	"github.com/huyhvq/eurofxref/pkg/eventbus"
+	"github.com/huyhvq/eurofxref/pkg/fx"
 	"github.com/huyhvq/eurofxref/pkg/model"
+	"github.com/huyhvq/eurofxref/pkg/override"
 	"github.com/huyhvq/eurofxref/pkg/repository"
+	"github.com/huyhvq/eurofxref/pkg/spread"
+	"github.com/huyhvq/eurofxref/pkg/syncer"
 	"net/http"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -13,35 +24,124 @@ type HttpServerHandler interface {
 	GetLatestRates(w http.ResponseWriter, r *http.Request)
 	GetRatesByDate(w http.ResponseWriter, r *http.Request)
 	GetRatesAnalyze(w http.ResponseWriter, r *http.Request)
+	GetRatesFluctuation(w http.ResponseWriter, r *http.Request)
+	GetRatesPeriods(w http.ResponseWriter, r *http.Request)
+	GetRatesIndicators(w http.ResponseWriter, r *http.Request)
+	GetRatesCorrelation(w http.ResponseWriter, r *http.Request)
+	GetPortfolioVaR(w http.ResponseWriter, r *http.Request)
+	Convert(w http.ResponseWriter, r *http.Request)
+	ConvertBatch(w http.ResponseWriter, r *http.Request)
+	ConvertCSV(w http.ResponseWriter, r *http.Request)
+	GetCurrencies(w http.ResponseWriter, r *http.Request)
+	GetBaskets(w http.ResponseWriter, r *http.Request)
+	GetBasket(w http.ResponseWriter, r *http.Request)
+	TriggerSync(w http.ResponseWriter, r *http.Request)
+	GetSyncRuns(w http.ResponseWriter, r *http.Request)
+	GetSyncRun(w http.ResponseWriter, r *http.Request)
+	GetConsistency(w http.ResponseWriter, r *http.Request)
+	Repair(w http.ResponseWriter, r *http.Request)
+	AdminBaskets(w http.ResponseWriter, r *http.Request)
+	AdminBasket(w http.ResponseWriter, r *http.Request)
+	AdminOverrides(w http.ResponseWriter, r *http.Request)
+	AdminOverride(w http.ResponseWriter, r *http.Request)
+	CreateQuote(w http.ResponseWriter, r *http.Request)
+	Quote(w http.ResponseWriter, r *http.Request)
+	AdminWebhooks(w http.ResponseWriter, r *http.Request)
+	AdminWebhook(w http.ResponseWriter, r *http.Request)
+	AdminAlertRules(w http.ResponseWriter, r *http.Request)
+	AdminAlertRule(w http.ResponseWriter, r *http.Request)
+	AdminAlerts(w http.ResponseWriter, r *http.Request)
+	StreamRates(w http.ResponseWriter, r *http.Request)
+	GetChanges(w http.ResponseWriter, r *http.Request)
+	GetCalendar(w http.ResponseWriter, r *http.Request)
+	GetCalendarDay(w http.ResponseWriter, r *http.Request)
+}
+
+type Config struct {
+	RateRepo     repository.RateRepository
+	SyncRunRepo  repository.SyncRunRepository
+	BasketRepo   repository.BasketRepository
+	OverrideRepo repository.OverrideRepository
+	QuoteRepo    repository.QuoteRepository
+	WebhookRepo  repository.WebhookRepository
+	AlertRepo    repository.AlertRepository
+	Syncer       syncer.Syncer
+	AdminToken   string
+	Spreads      spread.Rules
+	QuoteTTL     time.Duration
+	AlertSinks   map[string]alert.Sink
+	Bus          *eventbus.Bus
+	Pegs         currency.Pegs
 }
 
 type handler struct {
-	rateRepo repository.RateRepository
+	rateRepo     repository.RateRepository
+	syncRunRepo  repository.SyncRunRepository
+	basketRepo   repository.BasketRepository
+	overrideRepo repository.OverrideRepository
+	quoteRepo    repository.QuoteRepository
+	webhookRepo  repository.WebhookRepository
+	alertRepo    repository.AlertRepository
+	syncer       syncer.Syncer
+	adminToken   string
+	spreads      spread.Rules
+	quoteTTL     time.Duration
+	alertSinks   map[string]alert.Sink
+	bus          *eventbus.Bus
+	pegs         currency.Pegs
 }
 
 type ExchangeRate struct {
-	Base  string             `json:"base"`
-	Rates map[string]float64 `json:"rates"`
+	Base       string             `json:"base"`
+	Date       string             `json:"date,omitempty"`
+	Rates      map[string]float64 `json:"rates"`
+	AsKnownAt  string             `json:"as_known_at,omitempty"`
+	Derived    []string           `json:"derived,omitempty"`
+	Overridden []string           `json:"overridden,omitempty"`
+	Baskets    []string           `json:"baskets,omitempty"`
+	// OverridesExcluded is set when the rates are the provider rates, manual
+	// overrides not applied.
+	OverridesExcluded bool `json:"overrides_excluded,omitempty"`
 }
 
 type ExchangeRateAnalyze struct {
-	Base         string                 `json:"base"`
-	RatesAnalyze map[string]RateAnalyze `json:"rates_analyze"`
+	Base              string                 `json:"base"`
+	RatesAnalyze      map[string]RateAnalyze `json:"rates_analyze"`
+	OverridesExcluded bool                   `json:"overrides_excluded"`
 }
 
 type RateAnalyze struct {
-	Min float64 `json:"min"`
-	Max float64 `json:"max"`
-	Avg float64 `json:"avg"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	Avg    float64 `json:"avg"`
+	StdDev float64 `json:"std_dev"`
 }
 
 var (
 	errInvalidMethod  = errors.New("invalid method in request")
 	errInvalidRequest = errors.New("invalid request")
+	errUnauthorized   = errors.New("unauthorized")
+	errNotFound       = errors.New("not found")
+	errNoRates        = errors.New("no rates available for date")
 )
 
-func NewHandler(r repository.RateRepository) HttpServerHandler {
-	return &handler{rateRepo: r}
+func NewHandler(cfg *Config) HttpServerHandler {
+	return &handler{
+		rateRepo:     cfg.RateRepo,
+		syncRunRepo:  cfg.SyncRunRepo,
+		basketRepo:   cfg.BasketRepo,
+		overrideRepo: cfg.OverrideRepo,
+		quoteRepo:    cfg.QuoteRepo,
+		webhookRepo:  cfg.WebhookRepo,
+		alertRepo:    cfg.AlertRepo,
+		syncer:       cfg.Syncer,
+		adminToken:   cfg.AdminToken,
+		spreads:      cfg.Spreads,
+		quoteTTL:     cfg.QuoteTTL,
+		alertSinks:   cfg.AlertSinks,
+		bus:          cfg.Bus,
+		pegs:         cfg.Pegs,
+	}
 }
 
 func (h *handler) GetLatestRates(w http.ResponseWriter, r *http.Request) {
@@ -55,7 +155,15 @@ func (h *handler) GetLatestRates(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	jsonRespond(w, http.StatusOK, exchangeRateTransform(rates))
+	er := h.exchangeRateTransform(rates)
+	if len(rates) > 0 {
+		h.withLegacy(r, er, rates[0].Time)
+	}
+	if err := h.withBaskets(r, er); err != nil {
+		errorRespond(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	jsonRespond(w, http.StatusOK, er)
 	return
 }
 
@@ -64,20 +172,117 @@ func (h *handler) GetRatesByDate(w http.ResponseWriter, r *http.Request) {
 		errorRespond(w, http.StatusMethodNotAllowed, errInvalidMethod.Error())
 		return
 	}
-	date := r.URL.Path[len("/rates/"):]
+	parts := strings.Split(r.URL.Path[len("/rates/"):], "/")
+	switch len(parts) {
+	case 1:
+		h.ratesByDate(w, r, parts[0])
+	case 2:
+		if parts[1] != "matrix" {
+			errorRespond(w, http.StatusNotFound, errInvalidRequest.Error())
+			return
+		}
+		h.rateMatrix(w, r, parts[0])
+	case 3:
+		h.pairRate(w, r, parts[0], parts[1], parts[2])
+	default:
+		errorRespond(w, http.StatusNotFound, errInvalidRequest.Error())
+	}
+}
+
+// ratesByDate serves /rates/{date}. Rates are those stored for date itself,
+// possibly none; with fallback=true a date without a publication resolves to
+// the closest earlier one instead, and to 404 when there is none.
+func (h *handler) ratesByDate(w http.ResponseWriter, r *http.Request, date string) {
 	t, err := time.ParseInLocation("2006-01-02", date, time.UTC)
 	if err != nil {
 		errorRespond(w, http.StatusNotFound, errInvalidRequest.Error())
 		return
 	}
-	rates, err := h.rateRepo.GetRatesByDate(t)
+	fallback := r.URL.Query().Get("fallback") == "true"
+	if fallback {
+		t = calendar.OnOrBefore(t)
+	}
+	if ka := r.URL.Query().Get("as_known_at"); ka != "" {
+		knownAt, err := time.Parse(time.RFC3339, ka)
+		if err != nil {
+			errorRespond(w, http.StatusBadRequest, errInvalidRequest.Error())
+			return
+		}
+		rates, err := h.rateRepo.GetRatesByDateAsOf(t, knownAt.UTC())
+		if err != nil {
+			errorRespond(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		er := h.exchangeRateTransform(rates)
+		er.Date = t.Format("2006-01-02")
+		er.AsKnownAt = knownAt.UTC().Format(time.RFC3339)
+		er.OverridesExcluded = true
+		h.withLegacy(r, er, er.Date)
+		if err := h.withBaskets(r, er); err != nil {
+			errorRespond(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		jsonRespond(w, http.StatusOK, er)
+		return
+	}
+	var rates []model.Rate
+	if fallback {
+		rates, t, err = h.ratesOnOrBefore(t)
+	} else {
+		rates, err = h.rateRepo.GetRatesByDate(t)
+	}
 	if err != nil {
 		errorRespond(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	if fallback && len(rates) == 0 {
+		errorRespond(w, http.StatusNotFound, errNoRates.Error())
+		return
+	}
 
-	jsonRespond(w, http.StatusOK, exchangeRateTransform(rates))
-	return
+	er := h.exchangeRateTransform(rates)
+	er.Date = t.Format("2006-01-02")
+	h.withLegacy(r, er, er.Date)
+	if err := h.withBaskets(r, er); err != nil {
+		errorRespond(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	jsonRespond(w, http.StatusOK, er)
+}
+
+// resolveRates returns the rates for a date path segment, either a date or
+// "latest", together with the date the rates were published on. Dates
+// without a publication fall back to the closest earlier one.
+func (h *handler) resolveRates(date string) ([]model.Rate, time.Time, error) {
+	if date == "latest" {
+		t, err := h.rateRepo.GetLatestDate()
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		rates, err := h.rateRepo.GetRatesByDate(t)
+		return rates, t, err
+	}
+	t, err := time.ParseInLocation("2006-01-02", date, time.UTC)
+	if err != nil {
+		return nil, time.Time{}, errInvalidRequest
+	}
+	return h.ratesOnOrBefore(calendar.OnOrBefore(t))
+}
+
+// ratesOnOrBefore returns the rates of date, falling back to the closest
+// earlier stored date when date itself has none, together with the date the
+// rates belong to.
+func (h *handler) ratesOnOrBefore(date time.Time) ([]model.Rate, time.Time, error) {
+	rates, err := h.rateRepo.GetRatesByDate(date)
+	if err != nil || len(rates) > 0 {
+		return rates, date, err
+	}
+	d, err := h.rateRepo.GetDateOnOrBefore(date)
+	if err != nil || d.IsZero() {
+		return rates, date, err
+	}
+	rates, err = h.rateRepo.GetRatesByDate(d)
+	return rates, d, err
 }
 
 func (h *handler) GetRatesAnalyze(w http.ResponseWriter, r *http.Request) {
@@ -95,29 +300,79 @@ func (h *handler) GetRatesAnalyze(w http.ResponseWriter, r *http.Request) {
 	return
 }
 
-func exchangeRateTransform(rates []model.Rate) *ExchangeRate {
-	rs := make(map[string]float64, len(rates))
-	for _, rate := range rates {
-		rs[rate.Currency] = rate.Rate
+func (h *handler) exchangeRateTransform(rates []model.Rate) *ExchangeRate {
+	rs := fx.NewTable(rates)
+	if len(rates) > 0 {
+		rs.WithPegged(h.pegs, rates[0].Time)
 	}
-	return &ExchangeRate{
+	delete(rs, fx.Base)
+	er := &ExchangeRate{
 		Base:  "EUR",
 		Rates: rs,
 	}
+	if len(rates) > 0 {
+		er.Derived = h.derivedSymbols(rs.Currencies(), rates[0].Time)
+	}
+	er.Overridden = overriddenSymbols(rates, nil)
+	return er
+}
+
+// withLegacy adds the fixed rates of the legacy currencies the euro had
+// replaced by date when the request asks for them with legacy=true.
+func (h *handler) withLegacy(r *http.Request, er *ExchangeRate, date string) {
+	if r.URL.Query().Get("legacy") != "true" {
+		return
+	}
+	rs := fx.Table(er.Rates).WithFixed(date).WithPegged(h.pegs, date)
+	er.Derived = h.derivedSymbols(rs.Currencies(), date)
+}
+
+// overriddenSymbols returns the codes among codes, or every code when codes is
+// nil, whose rate in rates was set by a manual override, or nil when there
+// are none.
+func overriddenSymbols(rates []model.Rate, codes []string) []string {
+	var overridden []string
+	for _, c := range override.Overridden(rates) {
+		for _, code := range codes {
+			if code == c {
+				overridden = append(overridden, c)
+				break
+			}
+		}
+		if codes == nil {
+			overridden = append(overridden, c)
+		}
+	}
+	return overridden
+}
+
+// derivedSymbols returns the codes among codes that are priced on date from a
+// fixed rate instead of an ECB reference rate, or nil when there are none.
+func (h *handler) derivedSymbols(codes []string, date string) []string {
+	var derived []string
+	for _, c := range codes {
+		if h.pegs.IsDerived(c, date) {
+			derived = append(derived, c)
+		}
+	}
+	sort.Strings(derived)
+	return derived
 }
 
 func exchangeRateAnalyzeTransform(rates []model.RateAnalyze) *ExchangeRateAnalyze {
 	r := make(map[string]RateAnalyze, len(rates))
 	for _, rate := range rates {
 		r[rate.Currency] = RateAnalyze{
-			Min: rate.Min,
-			Max: rate.Max,
-			Avg: rate.Avg,
+			Min:    rate.Min,
+			Max:    rate.Max,
+			Avg:    rate.Avg,
+			StdDev: rate.StdDev,
 		}
 	}
 	return &ExchangeRateAnalyze{
-		Base:         "EUR",
-		RatesAnalyze: r,
+		Base:              "EUR",
+		RatesAnalyze:      r,
+		OverridesExcluded: true,
 	}
 }
 
@@ -131,3 +386,59 @@ func jsonRespond(w http.ResponseWriter, code int, payload interface{}) {
 func errorRespond(w http.ResponseWriter, code int, message string) {
 	jsonRespond(w, code, map[string]string{"error": message})
 }
+
+// authorized reports whether the request carries the admin token with the
+// Bearer scheme. Admin endpoints are disabled entirely when no token is
+// configured.
+func (h *handler) authorized(r *http.Request) bool {
+	if h.adminToken == "" {
+		return false
+	}
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return false
+	}
+	token := auth[len("Bearer "):]
+	return subtle.ConstantTimeCompare([]byte(token), []byte(h.adminToken)) == 1
+}
+
+// parseSymbols reads the comma separated symbols query parameter, upper-cased
+// and without duplicates.
+func parseSymbols(r *http.Request) []string {
+	symbols := make([]string, 0)
+	seen := make(map[string]struct{})
+	for _, s := range strings.Split(r.URL.Query().Get("symbols"), ",") {
+		s = strings.ToUpper(strings.TrimSpace(s))
+		if _, ok := seen[s]; ok || s == "" {
+			continue
+		}
+		seen[s] = struct{}{}
+		symbols = append(symbols, s)
+	}
+	return symbols
+}
+
+// parseRange reads the start and end query parameters. Missing parameters are
+// returned as the zero time unless required is set.
+func parseRange(r *http.Request, required bool) (time.Time, time.Time, error) {
+	var (
+		t   [2]time.Time
+		err error
+	)
+	for i, name := range []string{"start", "end"} {
+		v := r.URL.Query().Get(name)
+		if v == "" {
+			if required {
+				return time.Time{}, time.Time{}, errInvalidRequest
+			}
+			continue
+		}
+		if t[i], err = time.ParseInLocation("2006-01-02", v, time.UTC); err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+	}
+	if !t[0].IsZero() && !t[1].IsZero() && t[0].After(t[1]) {
+		return time.Time{}, time.Time{}, errInvalidRequest
+	}
+	return t[0], t[1], nil
+}
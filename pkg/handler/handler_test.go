@@ -1 +1,413 @@
 package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/huyhvq/eurofxref/pkg/consistency"
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"github.com/huyhvq/eurofxref/pkg/period"
+	"github.com/huyhvq/eurofxref/pkg/repository"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+)
+
+const testToken = "secret"
+
+// fakeRates serves rates from memory. Methods a test does not need panic
+// through the embedded nil interface. known are the rates served for any
+// as_known_at read, which records the instant asked for in knownAt, periods
+// are served for any period read and analyze for any analysis.
+type fakeRates struct {
+	repository.RateRepository
+	rates   []model.Rate
+	known   []model.Rate
+	knownAt time.Time
+	periods []model.RatePeriod
+	analyze []model.RateAnalyze
+	err     error
+}
+
+func (f *fakeRates) GetLatestDate() (time.Time, error) {
+	if f.err != nil || len(f.rates) == 0 {
+		return time.Time{}, f.err
+	}
+	dates := f.dates()
+	return time.ParseInLocation("2006-01-02", dates[len(dates)-1], time.UTC)
+}
+
+func (f *fakeRates) GetDateOnOrBefore(date time.Time) (time.Time, error) {
+	if f.err != nil {
+		return time.Time{}, f.err
+	}
+	var d time.Time
+	for _, s := range f.dates() {
+		if s <= date.Format("2006-01-02") {
+			d, _ = time.ParseInLocation("2006-01-02", s, time.UTC)
+		}
+	}
+	return d, nil
+}
+
+func (f *fakeRates) GetLatestRates() ([]model.Rate, error) {
+	d, err := f.GetLatestDate()
+	if err != nil {
+		return nil, err
+	}
+	return f.GetRatesByDate(d)
+}
+
+func (f *fakeRates) GetRatesByDate(date time.Time) ([]model.Rate, error) {
+	return f.GetRatesBetween(date, date)
+}
+
+func (f *fakeRates) GetRatesByDateAsOf(date, knownAt time.Time) ([]model.Rate, error) {
+	f.knownAt = knownAt
+	return f.known, f.err
+}
+
+func (f *fakeRates) GetRatesByPeriod(g period.Granularity, start, end time.Time) ([]model.RatePeriod, error) {
+	return f.periods, f.err
+}
+
+func (f *fakeRates) GetRatesAnalyze() ([]model.RateAnalyze, error) {
+	return f.analyze, f.err
+}
+
+func (f *fakeRates) GetRatesBetween(start, end time.Time) ([]model.Rate, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	rates := make([]model.Rate, 0)
+	for _, r := range f.rates {
+		if r.Time >= start.Format("2006-01-02") && r.Time <= end.Format("2006-01-02") {
+			rates = append(rates, r)
+		}
+	}
+	return rates, nil
+}
+
+func (f *fakeRates) GetCurrencyRanges() ([]model.CurrencyRange, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	byCode := make(map[string]*model.CurrencyRange)
+	codes := make([]string, 0)
+	for _, r := range f.rates {
+		cr, ok := byCode[r.Currency]
+		if !ok {
+			cr = &model.CurrencyRange{Currency: r.Currency, First: r.Time, Last: r.Time}
+			byCode[r.Currency] = cr
+			codes = append(codes, r.Currency)
+		}
+		if r.Time < cr.First {
+			cr.First = r.Time
+		}
+		if r.Time > cr.Last {
+			cr.Last = r.Time
+		}
+	}
+	sort.Strings(codes)
+	ranges := make([]model.CurrencyRange, len(codes))
+	for i, c := range codes {
+		ranges[i] = *byCode[c]
+	}
+	return ranges, nil
+}
+
+// dates returns the stored dates in ascending order.
+func (f *fakeRates) dates() []string {
+	seen := make(map[string]struct{})
+	dates := make([]string, 0)
+	for _, r := range f.rates {
+		if _, ok := seen[r.Time]; !ok {
+			seen[r.Time] = struct{}{}
+			dates = append(dates, r.Time)
+		}
+	}
+	sort.Strings(dates)
+	return dates
+}
+
+// testRates are the rates of two publications, a Friday and the Monday
+// after.
+var testRates = []model.Rate{
+	{Time: "2021-03-26", Currency: "GBP", Rate: 0.8556},
+	{Time: "2021-03-26", Currency: "JPY", Rate: 129.2},
+	{Time: "2021-03-26", Currency: "USD", Rate: 1.1795},
+	{Time: "2021-03-29", Currency: "GBP", Rate: 0.8551},
+	{Time: "2021-03-29", Currency: "JPY", Rate: 129.61},
+	{Time: "2021-03-29", Currency: "USD", Rate: 1.1765},
+}
+
+type fakeSyncRuns struct {
+	repository.SyncRunRepository
+	runs []model.SyncRun
+	err  error
+}
+
+func (f *fakeSyncRuns) GetRuns(limit int) ([]model.SyncRun, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if limit < len(f.runs) {
+		return f.runs[:limit], nil
+	}
+	return f.runs, nil
+}
+
+func (f *fakeSyncRuns) GetRunByID(id int64) (model.SyncRun, error) {
+	if f.err != nil {
+		return model.SyncRun{}, f.err
+	}
+	for _, run := range f.runs {
+		if run.ID == id {
+			return run, nil
+		}
+	}
+	return model.SyncRun{}, repository.ErrNotFound
+}
+
+type fakeSyncer struct {
+	run         model.SyncRun
+	err         error
+	report      consistency.Report
+	triggeredBy string
+	start, end  time.Time
+}
+
+func (f *fakeSyncer) Sync(triggeredBy string) (model.SyncRun, error) {
+	f.triggeredBy = triggeredBy
+	return f.run, f.err
+}
+
+func (f *fakeSyncer) Repair(triggeredBy string, start, end time.Time) (model.SyncRun, error) {
+	f.triggeredBy, f.start, f.end = triggeredBy, start, end
+	return f.run, f.err
+}
+
+func (f *fakeSyncer) Check(start, end time.Time) (consistency.Report, error) {
+	f.start, f.end = start, end
+	return f.report, f.err
+}
+
+// fakeBaskets serves baskets from memory.
+type fakeBaskets struct {
+	repository.BasketRepository
+	baskets []model.Basket
+	err     error
+}
+
+func (f *fakeBaskets) GetAll() ([]model.Basket, error) {
+	return f.baskets, f.err
+}
+
+func (f *fakeBaskets) GetByName(name string) (model.Basket, error) {
+	if f.err != nil {
+		return model.Basket{}, f.err
+	}
+	for _, b := range f.baskets {
+		if b.Name == name {
+			return b, nil
+		}
+	}
+	return model.Basket{}, repository.ErrNotFound
+}
+
+func (f *fakeBaskets) Save(b model.Basket) (int64, error) {
+	if f.err != nil {
+		return 0, f.err
+	}
+	for i := range f.baskets {
+		if f.baskets[i].Name == b.Name {
+			b.ID, b.CreatedAt = f.baskets[i].ID, f.baskets[i].CreatedAt
+			f.baskets[i] = b
+			return b.ID, nil
+		}
+	}
+	b.ID = int64(len(f.baskets) + 1)
+	f.baskets = append(f.baskets, b)
+	return b.ID, nil
+}
+
+func (f *fakeBaskets) Delete(name string) error {
+	for i, b := range f.baskets {
+		if b.Name == name {
+			f.baskets = append(f.baskets[:i], f.baskets[i+1:]...)
+			return nil
+		}
+	}
+	return repository.ErrNotFound
+}
+
+// newTestHandler returns a handler with the admin token set, the stored rates
+// of testRates and no baskets, on top of which cfg may set other
+// dependencies.
+func newTestHandler(cfg Config) *handler {
+	if cfg.RateRepo == nil {
+		cfg.RateRepo = &fakeRates{rates: testRates}
+	}
+	if cfg.BasketRepo == nil {
+		cfg.BasketRepo = &fakeBaskets{}
+	}
+	cfg.AdminToken = testToken
+	return NewHandler(&cfg).(*handler)
+}
+
+// do serves one request and returns the recorded response. Admin requests
+// carry the test token.
+func do(fn http.HandlerFunc, method, target string, body io.Reader, admin bool) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, body)
+	if admin {
+		r.Header.Set("Authorization", "Bearer "+testToken)
+	}
+	w := httptest.NewRecorder()
+	fn(w, r)
+	return w
+}
+
+// decode unmarshals the JSON body of w into v.
+func decode(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), v), w.Body.String())
+}
+
+// errorOf returns the error message of a JSON error response.
+func errorOf(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body map[string]string
+	decode(t, w, &body)
+	return body["error"]
+}
+
+func TestHandler_authorized(t *testing.T) {
+	h := newTestHandler(Config{})
+	for _, tc := range []struct {
+		name   string
+		header string
+		want   bool
+	}{
+		{"bearer token", "Bearer " + testToken, true},
+		{"bare token", testToken, false},
+		{"other scheme", "Basic " + testToken, false},
+		{"wrong token", "Bearer other", false},
+		{"missing", "", false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/admin/sync/runs", nil)
+			r.Header.Set("Authorization", tc.header)
+			assert.Equal(t, tc.want, h.authorized(r))
+		})
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/admin/sync/runs", nil)
+	r.Header.Set("Authorization", "Bearer ")
+	assert.False(t, NewHandler(&Config{}).(*handler).authorized(r), "admin endpoints are disabled without a token")
+}
+
+func TestHandler_GetRatesByDate_AsKnownAt(t *testing.T) {
+	rates := &fakeRates{known: []model.Rate{
+		{Time: "2021-03-26", Currency: "GBP", Rate: 0.8555},
+		{Time: "2021-03-26", Currency: "USD", Rate: 1.1794},
+	}}
+	h := newTestHandler(Config{RateRepo: rates})
+
+	w := do(h.GetRatesByDate, http.MethodGet, "/rates/2021-03-26?as_known_at=2021-03-27T10:00:00%2B02:00", nil, false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var er ExchangeRate
+	decode(t, w, &er)
+	assert.Equal(t, "2021-03-26", er.Date)
+	assert.Equal(t, "2021-03-27T08:00:00Z", er.AsKnownAt)
+	assert.Equal(t, map[string]float64{"GBP": 0.8555, "USD": 1.1794}, er.Rates)
+	assert.Equal(t, time.Date(2021, 3, 27, 8, 0, 0, 0, time.UTC), rates.knownAt)
+	assert.True(t, er.OverridesExcluded)
+
+	w = do(h.GetRatesByDate, http.MethodGet, "/rates/2021-03-26?as_known_at=yesterday", nil, false)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	rates.err = errors.New("db down")
+	w = do(h.GetRatesByDate, http.MethodGet, "/rates/2021-03-26?as_known_at=2021-03-27T08:00:00Z", nil, false)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestHandler_GetLatestRates(t *testing.T) {
+	w := do(newTestHandler(Config{}).GetLatestRates, http.MethodGet, "/rates/latest", nil, false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var er ExchangeRate
+	decode(t, w, &er)
+	assert.Equal(t, "EUR", er.Base)
+	assert.Equal(t, map[string]float64{"GBP": 0.8551, "JPY": 129.61, "USD": 1.1765}, er.Rates)
+	assert.False(t, er.OverridesExcluded)
+
+	w = do(newTestHandler(Config{RateRepo: &fakeRates{err: errors.New("db down")}}).GetLatestRates,
+		http.MethodGet, "/rates/latest", nil, false)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	w = do(newTestHandler(Config{}).GetLatestRates, http.MethodPost, "/rates/latest", nil, false)
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+func TestHandler_GetRatesByDate(t *testing.T) {
+	h := newTestHandler(Config{})
+	for _, tc := range []struct {
+		name   string
+		target string
+		code   int
+		date   string
+		rates  int
+	}{
+		{"publication day", "/rates/2021-03-26", http.StatusOK, "2021-03-26", 3},
+		{"weekend without fallback", "/rates/2021-03-27", http.StatusOK, "2021-03-27", 0},
+		{"weekend with fallback", "/rates/2021-03-28?fallback=true", http.StatusOK, "2021-03-26", 3},
+		{"missing publication with fallback", "/rates/2021-03-30?fallback=true", http.StatusOK, "2021-03-29", 3},
+		{"nothing earlier with fallback", "/rates/2021-03-01?fallback=true", http.StatusNotFound, "", 0},
+		{"invalid date", "/rates/26-03-2021", http.StatusNotFound, "", 0},
+		{"unknown path", "/rates/2021-03-26/USD", http.StatusNotFound, "", 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := do(h.GetRatesByDate, http.MethodGet, tc.target, nil, false)
+			assert.Equal(t, tc.code, w.Code)
+			if tc.code != http.StatusOK {
+				return
+			}
+			var er ExchangeRate
+			decode(t, w, &er)
+			assert.Equal(t, tc.date, er.Date)
+			assert.Len(t, er.Rates, tc.rates)
+		})
+	}
+
+	w := do(h.GetRatesByDate, http.MethodPost, "/rates/2021-03-26", nil, false)
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+func TestHandler_GetRatesAnalyze(t *testing.T) {
+	rates := &fakeRates{analyze: []model.RateAnalyze{
+		{Currency: "GBP", Min: 0.8551, Max: 0.8556, Avg: 0.85535, StdDev: 0.00025},
+		{Currency: "USD", Min: 1.1765, Max: 1.1795, Avg: 1.178, StdDev: 0.0015},
+	}}
+	h := newTestHandler(Config{RateRepo: rates})
+
+	w := do(h.GetRatesAnalyze, http.MethodGet, "/rates/analyze", nil, false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var body ExchangeRateAnalyze
+	decode(t, w, &body)
+	assert.Equal(t, "EUR", body.Base)
+	assert.Equal(t, map[string]RateAnalyze{
+		"GBP": {Min: 0.8551, Max: 0.8556, Avg: 0.85535, StdDev: 0.00025},
+		"USD": {Min: 1.1765, Max: 1.1795, Avg: 1.178, StdDev: 0.0015},
+	}, body.RatesAnalyze)
+	assert.True(t, body.OverridesExcluded)
+
+	rates.err = errors.New("db down")
+	w = do(h.GetRatesAnalyze, http.MethodGet, "/rates/analyze", nil, false)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	w = do(h.GetRatesAnalyze, http.MethodPost, "/rates/analyze", nil, false)
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"github.com/huyhvq/eurofxref/pkg/fx"
+	"github.com/huyhvq/eurofxref/pkg/indicators"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultIndicatorWindow = 20
+	defaultBollingerK      = 2
+)
+
+var allIndicators = []string{"sma", "ema", "bollinger", "rsi", "volatility"}
+
+type ExchangeRateIndicators struct {
+	Base       string                      `json:"base"`
+	Start      string                      `json:"start"`
+	End        string                      `json:"end"`
+	Window     int                         `json:"window"`
+	Indicators []string                    `json:"indicators"`
+	Series     map[string][]IndicatorPoint `json:"series"`
+	Derived    []string                    `json:"derived,omitempty"`
+	Overridden []string                    `json:"overridden,omitempty"`
+}
+
+type IndicatorPoint struct {
+	Date           string   `json:"date"`
+	Rate           float64  `json:"rate"`
+	SMA            *float64 `json:"sma,omitempty"`
+	EMA            *float64 `json:"ema,omitempty"`
+	BollingerUpper *float64 `json:"bollinger_upper,omitempty"`
+	BollingerLower *float64 `json:"bollinger_lower,omitempty"`
+	RSI            *float64 `json:"rsi,omitempty"`
+	Volatility     *float64 `json:"volatility,omitempty"`
+}
+
+// GetRatesIndicators responds with a list of indicators, one per base and
+// window in request order, even when only one of each is asked for.
+func (h *handler) GetRatesIndicators(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		errorRespond(w, http.StatusMethodNotAllowed, errInvalidMethod.Error())
+		return
+	}
+	start, end, err := parseRange(r, true)
+	if err != nil {
+		errorRespond(w, http.StatusBadRequest, errInvalidRequest.Error())
+		return
+	}
+	windows, err := parseWindows(r.URL.Query().Get("window"), defaultIndicatorWindow)
+	if err != nil {
+		errorRespond(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	k := float64(defaultBollingerK)
+	if v := r.URL.Query().Get("k"); v != "" {
+		if k, err = strconv.ParseFloat(v, 64); err != nil || k <= 0 {
+			errorRespond(w, http.StatusBadRequest, errInvalidRequest.Error())
+			return
+		}
+	}
+	wanted, err := parseIndicators(r.URL.Query().Get("indicators"))
+	if err != nil {
+		errorRespond(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	lookback := 0
+	for _, window := range windows {
+		if window > lookback {
+			lookback = window
+		}
+	}
+
+	bases := parseBases(r)
+	res := make([]*ExchangeRateIndicators, 0, len(bases)*len(windows))
+	for _, base := range bases {
+		series, overridden, err := h.timeSeries(base, parseSymbols(r), start, end, lookback)
+		if err == fx.ErrUnknownCurrency {
+			errorRespond(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			errorRespond(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		for _, window := range windows {
+			ri := h.indicatorsOf(series, base, start, end, window, k, wanted)
+			ri.Overridden = overridden
+			res = append(res, ri)
+		}
+	}
+	jsonRespond(w, http.StatusOK, res)
+}
+
+// indicatorsOf computes the wanted indicators over window for the points of
+// series dated from start on.
+func (h *handler) indicatorsOf(series map[string][]fx.Point, base string, start, end time.Time, window int, k float64, wanted map[string]bool) *ExchangeRateIndicators {
+	res := &ExchangeRateIndicators{
+		Base:       base,
+		Start:      start.Format("2006-01-02"),
+		End:        end.Format("2006-01-02"),
+		Window:     window,
+		Indicators: make([]string, 0, len(wanted)),
+		Series:     make(map[string][]IndicatorPoint, len(series)),
+	}
+	for _, name := range allIndicators {
+		if wanted[name] {
+			res.Indicators = append(res.Indicators, name)
+		}
+	}
+	for c, points := range series {
+		values := fx.Values(points)
+		sma, upper, lower := indicators.Bollinger(values, window, k)
+		ema := indicators.EMA(values, window)
+		rsi := indicators.RSI(values, window)
+		vol := indicators.Volatility(values, window)
+
+		out := make([]IndicatorPoint, 0, len(points))
+		for i := firstOnOrAfter(points, start); i < len(points); i++ {
+			p := IndicatorPoint{Date: points[i].Date, Rate: points[i].Rate}
+			if wanted["sma"] {
+				p.SMA = optional(sma[i])
+			}
+			if wanted["ema"] {
+				p.EMA = optional(ema[i])
+			}
+			if wanted["bollinger"] {
+				p.BollingerUpper = optional(upper[i])
+				p.BollingerLower = optional(lower[i])
+			}
+			if wanted["rsi"] {
+				p.RSI = optional(rsi[i])
+			}
+			if wanted["volatility"] {
+				p.Volatility = optional(vol[i])
+			}
+			out = append(out, p)
+		}
+		res.Series[c] = out
+		res.Derived = append(res.Derived, h.derivedSymbols([]string{c}, res.End)...)
+	}
+	sort.Strings(res.Derived)
+	return res
+}
+
+func parseIndicators(v string) (map[string]bool, error) {
+	wanted := make(map[string]bool)
+	if v == "" {
+		for _, name := range allIndicators {
+			wanted[name] = true
+		}
+		return wanted, nil
+	}
+	known := make(map[string]bool, len(allIndicators))
+	for _, name := range allIndicators {
+		known[name] = true
+	}
+	for _, name := range strings.Split(v, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !known[name] {
+			return nil, errInvalidRequest
+		}
+		wanted[name] = true
+	}
+	return wanted, nil
+}
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+// weekRates are the USD and GBP rates of the week of 2021-03-22.
+var weekRates = []model.Rate{
+	{Time: "2021-03-22", Currency: "GBP", Rate: 0.8588}, {Time: "2021-03-22", Currency: "USD", Rate: 1.1926},
+	{Time: "2021-03-23", Currency: "GBP", Rate: 0.8634}, {Time: "2021-03-23", Currency: "USD", Rate: 1.1883},
+	{Time: "2021-03-24", Currency: "GBP", Rate: 0.8633}, {Time: "2021-03-24", Currency: "USD", Rate: 1.1824},
+	{Time: "2021-03-25", Currency: "GBP", Rate: 0.8623}, {Time: "2021-03-25", Currency: "USD", Rate: 1.1814},
+	{Time: "2021-03-26", Currency: "GBP", Rate: 0.8556}, {Time: "2021-03-26", Currency: "USD", Rate: 1.1795},
+}
+
+func TestHandler_GetRatesIndicators(t *testing.T) {
+	h := newTestHandler(Config{RateRepo: &fakeRates{rates: weekRates}})
+
+	w := do(h.GetRatesIndicators, http.MethodGet, "/rates/indicators?start=2021-03-25&end=2021-03-26&symbols=USD&window=2&indicators=sma,rsi", nil, false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var single []ExchangeRateIndicators
+	decode(t, w, &single)
+	assert.Len(t, single, 1)
+	ri := single[0]
+	assert.Equal(t, "EUR", ri.Base)
+	assert.Equal(t, 2, ri.Window)
+	assert.Equal(t, []string{"sma", "rsi"}, ri.Indicators)
+	usd := ri.Series["USD"]
+	assert.Len(t, usd, 2)
+	assert.Equal(t, "2021-03-25", usd[0].Date)
+	assert.InDelta(t, (1.1824+1.1814)/2, *usd[0].SMA, 1e-12)
+	assert.InDelta(t, 0, *usd[0].RSI, 1e-12)
+	assert.Nil(t, usd[0].EMA)
+
+	w = do(h.GetRatesIndicators, http.MethodGet, "/rates/indicators?start=2021-03-25&end=2021-03-26&symbols=USD,GBP&base=eur,gbp&window=2,3,2", nil, false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var list []ExchangeRateIndicators
+	decode(t, w, &list)
+	assert.Len(t, list, 4)
+	for i, want := range []struct {
+		base   string
+		window int
+	}{{"EUR", 2}, {"EUR", 3}, {"GBP", 2}, {"GBP", 3}} {
+		assert.Equal(t, want.base, list[i].Base)
+		assert.Equal(t, want.window, list[i].Window)
+		assert.Len(t, list[i].Series["USD"], 2)
+	}
+	assert.InDelta(t, 1.1814/0.8623, list[2].Series["USD"][0].Rate, 1e-12)
+
+	for _, tc := range []struct {
+		target string
+		code   int
+	}{
+		{"/rates/indicators?start=2021-03-25", http.StatusBadRequest},
+		{"/rates/indicators?start=2021-03-25&end=2021-03-26&window=1", http.StatusBadRequest},
+		{"/rates/indicators?start=2021-03-25&end=2021-03-26&window=2,x", http.StatusBadRequest},
+		{"/rates/indicators?start=2021-03-25&end=2021-03-26&k=-1", http.StatusBadRequest},
+		{"/rates/indicators?start=2021-03-25&end=2021-03-26&indicators=macd", http.StatusBadRequest},
+		{"/rates/indicators?start=2021-03-25&end=2021-03-26&base=EUR,XXX", http.StatusBadRequest},
+	} {
+		w = do(h.GetRatesIndicators, http.MethodGet, tc.target, nil, false)
+		assert.Equal(t, tc.code, w.Code, tc.target)
+	}
+
+	w = do(h.GetRatesIndicators, http.MethodPost, "/rates/indicators", nil, false)
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestHandler_Legacy(t *testing.T) {
+	h := newTestHandler(Config{})
+
+	w := do(h.GetLatestRates, http.MethodGet, "/rates/latest?legacy=true", nil, false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var er ExchangeRate
+	decode(t, w, &er)
+	assert.Equal(t, 1.95583, er.Rates["DEM"])
+	assert.Equal(t, 30.126, er.Rates["SKK"])
+	assert.Contains(t, er.Derived, "DEM")
+	assert.NotContains(t, er.Rates, "HRK", "HRK was only replaced in 2023")
+
+	w = do(h.GetRatesByDate, http.MethodGet, "/rates/2021-03-26", nil, false)
+	er = ExchangeRate{}
+	decode(t, w, &er)
+	assert.NotContains(t, er.Rates, "DEM")
+	assert.Empty(t, er.Derived)
+
+	w = do(h.GetRatesByDate, http.MethodGet, "/rates/2021-03-26?legacy=true", nil, false)
+	er = ExchangeRate{}
+	decode(t, w, &er)
+	assert.Equal(t, 6.55957, er.Rates["FRF"])
+
+	w = do(h.Convert, http.MethodGet, "/convert?from=DEM&to=FRF&amount=1.95583&date=2021-03-26", nil, false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var res ConversionResult
+	decode(t, w, &res)
+	assert.InDelta(t, 6.55957, *res.Result, 1e-9)
+	assert.Equal(t, []string{"DEM", "FRF"}, res.Derived)
+
+	w = do(h.GetRatesFluctuation, http.MethodGet, "/rates/fluctuation?start=2021-03-26&end=2021-03-29&symbols=DEM,USD", nil, false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var f ExchangeRateFluctuation
+	decode(t, w, &f)
+	assert.Equal(t, float64(0), f.Rates["DEM"].Change)
+
+	w = do(h.GetRatesFluctuation, http.MethodGet, "/rates/fluctuation?start=2021-03-26&end=2021-03-29", nil, false)
+	f = ExchangeRateFluctuation{}
+	decode(t, w, &f)
+	assert.NotContains(t, f.Rates, "DEM")
+}
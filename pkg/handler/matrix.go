@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"encoding/csv"
+	"github.com/huyhvq/eurofxref/pkg/fx"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultPrecision = 6
+	maxPrecision     = 12
+)
+
+type RateMatrix struct {
+	Date       string      `json:"date"`
+	Symbols    []string    `json:"symbols"`
+	Matrix     [][]float64 `json:"matrix"`
+	Derived    []string    `json:"derived,omitempty"`
+	Overridden []string    `json:"overridden,omitempty"`
+}
+
+// rateMatrix serves /rates/{date|latest}/matrix. Row i holds the units of
+// every symbol per one unit of symbols[i]. Legacy currencies are only priced
+// with legacy=true, as for /rates/{date}.
+func (h *handler) rateMatrix(w http.ResponseWriter, r *http.Request, date string) {
+	precision, err := parsePrecision(r)
+	if err != nil {
+		errorRespond(w, http.StatusBadRequest, errInvalidRequest.Error())
+		return
+	}
+	rates, t, err := h.resolveRates(date)
+	if err == errInvalidRequest {
+		errorRespond(w, http.StatusNotFound, errInvalidRequest.Error())
+		return
+	}
+	if err != nil {
+		errorRespond(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if len(rates) == 0 {
+		errorRespond(w, http.StatusNotFound, errNoRates.Error())
+		return
+	}
+	d := t.Format("2006-01-02")
+	table := fx.NewTable(rates)
+	if r.URL.Query().Get("legacy") == "true" {
+		table.WithFixed(d)
+	}
+	table.WithPegged(h.pegs, d)
+	symbols := parseSymbols(r)
+	if len(symbols) == 0 {
+		symbols = table.Currencies()
+	}
+	m, err := table.Matrix(symbols)
+	if err != nil {
+		errorRespond(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	fx.RoundMatrix(m, precision)
+
+	if !wantsCSV(r) {
+		jsonRespond(w, http.StatusOK, &RateMatrix{
+			Date:       t.Format("2006-01-02"),
+			Symbols:    symbols,
+			Matrix:     m,
+			Derived:    h.derivedSymbols(symbols, t.Format("2006-01-02")),
+			Overridden: overriddenSymbols(rates, symbols),
+		})
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv")
+	w.WriteHeader(http.StatusOK)
+	cw := csv.NewWriter(w)
+	cw.Write(append([]string{t.Format("2006-01-02")}, symbols...))
+	for i, row := range m {
+		record := make([]string, 0, len(row)+1)
+		record = append(record, symbols[i])
+		for _, v := range row {
+			record = append(record, strconv.FormatFloat(v, 'f', -1, 64))
+		}
+		cw.Write(record)
+	}
+	cw.Flush()
+}
+
+func parsePrecision(r *http.Request) (int, error) {
+	p := r.URL.Query().Get("precision")
+	if p == "" {
+		return defaultPrecision, nil
+	}
+	n, err := strconv.Atoi(p)
+	if err != nil || n < 0 || n > maxPrecision {
+		return 0, errInvalidRequest
+	}
+	return n, nil
+}
+
+func wantsCSV(r *http.Request) bool {
+	if f := r.URL.Query().Get("format"); f != "" {
+		return f == "csv"
+	}
+	return strings.Contains(r.Header.Get("Accept"), "text/csv")
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler_rateMatrix(t *testing.T) {
+	h := newTestHandler(Config{})
+
+	w := do(h.GetRatesByDate, http.MethodGet, "/rates/2021-03-26/matrix?symbols=eur,usd,jpy&precision=4", nil, false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var m RateMatrix
+	decode(t, w, &m)
+	assert.Equal(t, "2021-03-26", m.Date)
+	assert.Equal(t, []string{"EUR", "USD", "JPY"}, m.Symbols)
+	assert.Equal(t, [][]float64{
+		{1, 1.1795, 129.2},
+		{0.8478, 1, 109.5379},
+		{0.0077, 0.0091, 1},
+	}, m.Matrix)
+
+	w = do(h.GetRatesByDate, http.MethodGet, "/rates/latest/matrix", nil, false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	decode(t, w, &m)
+	assert.Equal(t, []string{"EUR", "GBP", "JPY", "USD"}, m.Symbols)
+	assert.Equal(t, "2021-03-29", m.Date)
+
+	w = do(h.GetRatesByDate, http.MethodGet, "/rates/2021-03-26/matrix?legacy=true", nil, false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	m = RateMatrix{}
+	decode(t, w, &m)
+	assert.Contains(t, m.Symbols, "DEM")
+	assert.Contains(t, m.Derived, "DEM")
+	assert.Len(t, m.Matrix, len(m.Symbols))
+
+	w = do(h.GetRatesByDate, http.MethodGet, "/rates/2021-03-26/matrix?symbols=EUR,DEM&legacy=true", nil, false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	m = RateMatrix{}
+	decode(t, w, &m)
+	assert.Equal(t, [][]float64{{1, 1.95583}, {0.511292, 1}}, m.Matrix)
+
+	r := httptest.NewRequest(http.MethodGet, "/rates/2021-03-26/matrix?symbols=EUR,USD&precision=2", nil)
+	r.Header.Set("Accept", "text/csv")
+	rec := httptest.NewRecorder()
+	h.GetRatesByDate(rec, r)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/csv", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "2021-03-26,EUR,USD\nEUR,1,1.18\nUSD,0.85,1\n", rec.Body.String())
+
+	for _, tc := range []struct {
+		target string
+		code   int
+	}{
+		{"/rates/latest/matrix?symbols=EUR,XXX", http.StatusBadRequest},
+		{"/rates/latest/matrix?symbols=EUR,DEM", http.StatusBadRequest},
+		{"/rates/latest/matrix?precision=13", http.StatusBadRequest},
+		{"/rates/2021-03-01/matrix", http.StatusNotFound},
+		{"/rates/latest/grid", http.StatusNotFound},
+	} {
+		w = do(h.GetRatesByDate, http.MethodGet, tc.target, nil, false)
+		assert.Equal(t, tc.code, w.Code, tc.target)
+	}
+}
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"encoding/json"
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"github.com/huyhvq/eurofxref/pkg/override"
+	"github.com/huyhvq/eurofxref/pkg/repository"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type RateOverride struct {
+	ID        int64      `json:"id"`
+	Currency  string     `json:"currency"`
+	Start     string     `json:"start"`
+	End       string     `json:"end"`
+	Rate      float64    `json:"rate"`
+	Reason    string     `json:"reason"`
+	CreatedBy string     `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedBy string     `json:"revoked_by,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// AdminOverrides serves /admin/overrides: GET lists every override including
+// revoked ones, POST creates one.
+func (h *handler) AdminOverrides(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		errorRespond(w, http.StatusMethodNotAllowed, errInvalidMethod.Error())
+		return
+	}
+	if !h.authorized(r) {
+		errorRespond(w, http.StatusUnauthorized, errUnauthorized.Error())
+		return
+	}
+	if r.Method == http.MethodGet {
+		overrides, err := h.overrideRepo.GetAll()
+		if err != nil {
+			errorRespond(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		res := make([]*RateOverride, 0, len(overrides))
+		for _, o := range overrides {
+			res = append(res, overrideTransform(o))
+		}
+		jsonRespond(w, http.StatusOK, res)
+		return
+	}
+	var req RateOverride
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		errorRespond(w, http.StatusBadRequest, errInvalidRequest.Error())
+		return
+	}
+	o := model.RateOverride{
+		Currency:  req.Currency,
+		Start:     req.Start,
+		End:       req.End,
+		Rate:      req.Rate,
+		Reason:    req.Reason,
+		CreatedBy: req.CreatedBy,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := override.Normalize(&o); err != nil {
+		errorRespond(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	id, err := h.overrideRepo.Insert(o)
+	if err != nil {
+		errorRespond(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	o.ID = id
+	jsonRespond(w, http.StatusCreated, overrideTransform(o))
+}
+
+// AdminOverride serves /admin/overrides/{id}: GET returns the override,
+// DELETE?by= revokes it. Revoked overrides stay in the audit trail.
+func (h *handler) AdminOverride(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodDelete {
+		errorRespond(w, http.StatusMethodNotAllowed, errInvalidMethod.Error())
+		return
+	}
+	if !h.authorized(r) {
+		errorRespond(w, http.StatusUnauthorized, errUnauthorized.Error())
+		return
+	}
+	id, err := strconv.ParseInt(r.URL.Path[len("/admin/overrides/"):], 10, 64)
+	if err != nil {
+		errorRespond(w, http.StatusNotFound, errInvalidRequest.Error())
+		return
+	}
+	if r.Method == http.MethodDelete {
+		by := strings.TrimSpace(r.URL.Query().Get("by"))
+		if by == "" {
+			errorRespond(w, http.StatusBadRequest, errInvalidRequest.Error())
+			return
+		}
+		err := h.overrideRepo.Revoke(id, by, time.Now().UTC())
+		if err == repository.ErrNotFound {
+			errorRespond(w, http.StatusNotFound, errNotFound.Error())
+			return
+		}
+		if err != nil {
+			errorRespond(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	o, err := h.overrideRepo.GetByID(id)
+	if err == repository.ErrNotFound {
+		errorRespond(w, http.StatusNotFound, errNotFound.Error())
+		return
+	}
+	if err != nil {
+		errorRespond(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	jsonRespond(w, http.StatusOK, overrideTransform(o))
+}
+
+func overrideTransform(o model.RateOverride) *RateOverride {
+	res := &RateOverride{
+		ID:        o.ID,
+		Currency:  o.Currency,
+		Start:     o.Start,
+		End:       o.End,
+		Rate:      o.Rate,
+		Reason:    o.Reason,
+		CreatedBy: o.CreatedBy,
+		CreatedAt: o.CreatedAt,
+		RevokedBy: o.RevokedBy,
+	}
+	if !o.RevokedAt.IsZero() {
+		res.RevokedAt = &o.RevokedAt
+	}
+	return res
+}
@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"github.com/huyhvq/eurofxref/pkg/repository"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeOverrides keeps overrides in memory, numbered from 1.
+type fakeOverrides struct {
+	overrides []model.RateOverride
+}
+
+func (f *fakeOverrides) Insert(o model.RateOverride) (int64, error) {
+	o.ID = int64(len(f.overrides) + 1)
+	f.overrides = append(f.overrides, o)
+	return o.ID, nil
+}
+
+func (f *fakeOverrides) Revoke(id int64, by string, at time.Time) error {
+	for i := range f.overrides {
+		if o := &f.overrides[i]; o.ID == id && o.RevokedAt.IsZero() {
+			o.RevokedBy, o.RevokedAt = by, at
+			return nil
+		}
+	}
+	return repository.ErrNotFound
+}
+
+func (f *fakeOverrides) GetAll() ([]model.RateOverride, error) {
+	return f.overrides, nil
+}
+
+func (f *fakeOverrides) GetByID(id int64) (model.RateOverride, error) {
+	for _, o := range f.overrides {
+		if o.ID == id {
+			return o, nil
+		}
+	}
+	return model.RateOverride{}, repository.ErrNotFound
+}
+
+func (f *fakeOverrides) GetActive(start, end time.Time) ([]model.RateOverride, error) {
+	var active []model.RateOverride
+	for _, o := range f.overrides {
+		if o.RevokedAt.IsZero() && o.Start <= end.Format("2006-01-02") && o.End >= start.Format("2006-01-02") {
+			active = append(active, o)
+		}
+	}
+	return active, nil
+}
+
+func TestHandler_AdminOverrides(t *testing.T) {
+	overrides := &fakeOverrides{}
+	h := newTestHandler(Config{
+		RateRepo:     repository.NewOverriddenRate(&fakeRates{rates: testRates}, overrides),
+		OverrideRepo: overrides,
+	})
+
+	body := `{"currency": "usd", "start": "2021-03-29", "end": "2021-03-29", "rate": 1.2,
+		"reason": "provider outage", "created_by": "alice"}`
+	w := do(h.AdminOverrides, http.MethodPost, "/admin/overrides", strings.NewReader(body), true)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var o RateOverride
+	decode(t, w, &o)
+	assert.Equal(t, int64(1), o.ID)
+	assert.Equal(t, "USD", o.Currency)
+
+	w = do(h.GetLatestRates, http.MethodGet, "/rates/latest", nil, false)
+	var er ExchangeRate
+	decode(t, w, &er)
+	assert.Equal(t, 1.2, er.Rates["USD"])
+	assert.Equal(t, []string{"USD"}, er.Overridden)
+
+	w = do(h.GetRatesByDate, http.MethodGet, "/rates/2021-03-26/EUR/USD", nil, false)
+	var pr PairRate
+	decode(t, w, &pr)
+	assert.Equal(t, 1.1795, pr.Rate)
+	assert.Nil(t, pr.Overridden)
+
+	w = do(h.AdminOverrides, http.MethodGet, "/admin/overrides", nil, true)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var list []RateOverride
+	decode(t, w, &list)
+	assert.Len(t, list, 1)
+
+	w = do(h.AdminOverride, http.MethodDelete, "/admin/overrides/1?by=bob", nil, true)
+	assert.Equal(t, http.StatusOK, w.Code)
+	o = RateOverride{}
+	decode(t, w, &o)
+	assert.Equal(t, "bob", o.RevokedBy)
+	assert.NotNil(t, o.RevokedAt)
+
+	w = do(h.GetLatestRates, http.MethodGet, "/rates/latest", nil, false)
+	er = ExchangeRate{}
+	decode(t, w, &er)
+	assert.Equal(t, 1.1765, er.Rates["USD"])
+	assert.Nil(t, er.Overridden)
+
+	for _, tc := range []struct {
+		name   string
+		fn     http.HandlerFunc
+		method string
+		target string
+		body   string
+		admin  bool
+		code   int
+	}{
+		{"unauthorized", h.AdminOverrides, http.MethodGet, "/admin/overrides", "", false, http.StatusUnauthorized},
+		{"malformed", h.AdminOverrides, http.MethodPost, "/admin/overrides", `{"currency": `, true, http.StatusBadRequest},
+		{"no reason", h.AdminOverrides, http.MethodPost, "/admin/overrides",
+			`{"currency": "USD", "start": "2021-03-29", "end": "2021-03-29", "rate": 1.2, "created_by": "alice"}`, true, http.StatusBadRequest},
+		{"method", h.AdminOverrides, http.MethodPut, "/admin/overrides", "", true, http.StatusMethodNotAllowed},
+		{"unknown", h.AdminOverride, http.MethodGet, "/admin/overrides/9", "", true, http.StatusNotFound},
+		{"invalid id", h.AdminOverride, http.MethodGet, "/admin/overrides/x", "", true, http.StatusNotFound},
+		{"revoke without by", h.AdminOverride, http.MethodDelete, "/admin/overrides/1", "", true, http.StatusBadRequest},
+		{"revoke twice", h.AdminOverride, http.MethodDelete, "/admin/overrides/1?by=bob", "", true, http.StatusNotFound},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := do(tc.fn, tc.method, tc.target, strings.NewReader(tc.body), tc.admin)
+			assert.Equal(t, tc.code, w.Code)
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"github.com/huyhvq/eurofxref/pkg/fx"
+	"github.com/huyhvq/eurofxref/pkg/spread"
+	"net/http"
+	"strings"
+)
+
+type PairRate struct {
+	Base       string             `json:"base"`
+	Quote      string             `json:"quote"`
+	Date       string             `json:"date"`
+	Rate       float64            `json:"rate"`
+	Inverse    float64            `json:"inverse"`
+	Profile    string             `json:"profile,omitempty"`
+	Bid        float64            `json:"bid"`
+	Mid        float64            `json:"mid"`
+	Ask        float64            `json:"ask"`
+	MarkupBps  float64            `json:"markup_bps"`
+	Legs       map[string]float64 `json:"legs"`
+	Derived    []string           `json:"derived,omitempty"`
+	Overridden []string           `json:"overridden,omitempty"`
+	Baskets    []string           `json:"baskets,omitempty"`
+}
+
+// pairRate serves /rates/{date|latest}/{base}/{quote}?profile=. rate is the
+// mid rate; bid and ask carry the spread of the client profile.
+func (h *handler) pairRate(w http.ResponseWriter, r *http.Request, date, base, quote string) {
+	base, quote = strings.ToUpper(base), strings.ToUpper(quote)
+	profile := strings.TrimSpace(r.URL.Query().Get("profile"))
+	if !h.spreads.HasProfile(profile) {
+		errorRespond(w, http.StatusBadRequest, spread.ErrUnknownProfile.Error())
+		return
+	}
+	rates, t, err := h.resolveRates(date)
+	if err == errInvalidRequest {
+		errorRespond(w, http.StatusNotFound, errInvalidRequest.Error())
+		return
+	}
+	if err != nil {
+		errorRespond(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if len(rates) == 0 {
+		errorRespond(w, http.StatusNotFound, errNoRates.Error())
+		return
+	}
+	d := t.Format("2006-01-02")
+	table := fx.NewTable(rates).WithFixed(d).WithPegged(h.pegs, d)
+	rate, err := table.Cross(base, quote)
+	var baskets []string
+	if err == fx.ErrUnknownCurrency {
+		all, berr := h.basketRepo.GetAll()
+		if berr != nil {
+			errorRespond(w, http.StatusInternalServerError, berr.Error())
+			return
+		}
+		baskets = addBaskets(table, all)
+		rate, err = table.Cross(base, quote)
+	}
+	if err != nil {
+		errorRespond(w, http.StatusNotFound, err.Error())
+		return
+	}
+	q, err := h.spreads.Quote(profile, base, quote, rate)
+	if err != nil {
+		errorRespond(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	jsonRespond(w, http.StatusOK, &PairRate{
+		Base:      base,
+		Quote:     quote,
+		Date:      d,
+		Rate:      rate,
+		Inverse:   1 / rate,
+		Profile:   profile,
+		Bid:       q.Bid,
+		Mid:       q.Mid,
+		Ask:       q.Ask,
+		MarkupBps: q.Markup,
+		Legs: map[string]float64{
+			fx.Base + "/" + base:  table[base],
+			fx.Base + "/" + quote: table[quote],
+		},
+		Derived:    h.derivedSymbols([]string{base, quote}, d),
+		Overridden: overriddenSymbols(rates, []string{base, quote}),
+		Baskets:    basketSymbols(baskets, []string{base, quote}),
+	})
+}
@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestHandler_pairRate(t *testing.T) {
+	h := newTestHandler(Config{})
+
+	w := do(h.GetRatesByDate, http.MethodGet, "/rates/2021-03-28/usd/GBP", nil, false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var p PairRate
+	decode(t, w, &p)
+	assert.Equal(t, "USD", p.Base)
+	assert.Equal(t, "GBP", p.Quote)
+	assert.Equal(t, "2021-03-26", p.Date)
+	assert.InDelta(t, 0.8556/1.1795, p.Rate, 1e-12)
+	assert.InDelta(t, 1.1795/0.8556, p.Inverse, 1e-12)
+	assert.Equal(t, map[string]float64{"EUR/USD": 1.1795, "EUR/GBP": 0.8556}, p.Legs)
+	assert.Equal(t, p.Rate, p.Mid)
+
+	w = do(h.GetRatesByDate, http.MethodGet, "/rates/latest/EUR/JPY", nil, false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	decode(t, w, &p)
+	assert.Equal(t, "2021-03-29", p.Date)
+	assert.Equal(t, 129.61, p.Rate)
+
+	for _, tc := range []struct {
+		target string
+		code   int
+	}{
+		{"/rates/latest/USD/XXX", http.StatusNotFound},
+		{"/rates/2021-03-01/USD/GBP", http.StatusNotFound},
+		{"/rates/yesterday/USD/GBP", http.StatusNotFound},
+		{"/rates/latest/USD/GBP?profile=unknown", http.StatusBadRequest},
+	} {
+		w = do(h.GetRatesByDate, http.MethodGet, tc.target, nil, false)
+		assert.Equal(t, tc.code, w.Code, tc.target)
+	}
+}
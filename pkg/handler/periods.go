@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"github.com/huyhvq/eurofxref/pkg/fx"
+	"github.com/huyhvq/eurofxref/pkg/period"
+	"net/http"
+	"sort"
+	"time"
+)
+
+type ExchangeRatePeriods struct {
+	Base              string       `json:"base"`
+	Granularity       string       `json:"granularity"`
+	Periods           []RatePeriod `json:"periods"`
+	OverridesExcluded bool         `json:"overrides_excluded"`
+}
+
+type RatePeriod struct {
+	Period  string                 `json:"period"`
+	Start   string                 `json:"start"`
+	End     string                 `json:"end"`
+	Rates   map[string]PeriodStats `json:"rates"`
+	Derived []string               `json:"derived,omitempty"`
+}
+
+type PeriodStats struct {
+	Avg   float64 `json:"avg"`
+	Open  float64 `json:"open"`
+	Close float64 `json:"close"`
+	High  float64 `json:"high"`
+	Low   float64 `json:"low"`
+	Days  int     `json:"days"`
+}
+
+func (h *handler) GetRatesPeriods(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		errorRespond(w, http.StatusMethodNotAllowed, errInvalidMethod.Error())
+		return
+	}
+	g := period.Month
+	if v := r.URL.Query().Get("granularity"); v != "" {
+		var err error
+		if g, err = period.Parse(v); err != nil {
+			errorRespond(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	start, end, err := parseRange(r, true)
+	if err != nil {
+		errorRespond(w, http.StatusBadRequest, errInvalidRequest.Error())
+		return
+	}
+	periods, err := h.rateRepo.GetRatesByPeriod(g, start, end)
+	if err != nil {
+		errorRespond(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	symbols := make(map[string]struct{})
+	for _, s := range parseSymbols(r) {
+		symbols[s] = struct{}{}
+	}
+	res := &ExchangeRatePeriods{
+		Base:              fx.Base,
+		Granularity:       string(g),
+		Periods:           make([]RatePeriod, 0),
+		OverridesExcluded: true,
+	}
+	for _, p := range periods {
+		if n := len(res.Periods); n == 0 || res.Periods[n-1].Start != p.Start {
+			t, _ := time.ParseInLocation("2006-01-02", p.Start, time.UTC)
+			res.Periods = append(res.Periods, RatePeriod{
+				Period: g.Label(t),
+				Start:  p.Start,
+				End:    g.End(t).Format("2006-01-02"),
+				Rates:  make(map[string]PeriodStats),
+			})
+		}
+		res.Periods[len(res.Periods)-1].Rates[p.Currency] = PeriodStats{
+			Avg:   p.Avg,
+			Open:  p.Open,
+			Close: p.Close,
+			High:  p.High,
+			Low:   p.Low,
+			Days:  p.Count,
+		}
+	}
+	for i := range res.Periods {
+		p := &res.Periods[i]
+		h.withPegged(p)
+		for c := range p.Rates {
+			if _, ok := symbols[c]; len(symbols) > 0 && !ok {
+				delete(p.Rates, c)
+			} else if h.pegs.IsDerived(c, p.Start) {
+				p.Derived = append(p.Derived, c)
+			}
+		}
+		sort.Strings(p.Derived)
+	}
+	jsonRespond(w, http.StatusOK, res)
+}
+
+// withPegged adds the derived currencies pegged over the whole of p, scaled
+// from the statistics of their peg.
+func (h *handler) withPegged(p *RatePeriod) {
+	days := 0
+	for _, s := range p.Rates {
+		if s.Days > days {
+			days = s.Days
+		}
+	}
+	for _, d := range h.pegs.On(p.Start) {
+		if _, ok := p.Rates[d.Code]; ok || (d.ValidTo != "" && d.ValidTo < p.End) {
+			continue
+		}
+		s := PeriodStats{Avg: 1, Open: 1, Close: 1, High: 1, Low: 1, Days: days}
+		if d.Peg != fx.Base {
+			var ok bool
+			if s, ok = p.Rates[d.Peg]; !ok {
+				continue
+			}
+		}
+		p.Rates[d.Code] = PeriodStats{
+			Avg:   s.Avg * d.Ratio,
+			Open:  s.Open * d.Ratio,
+			Close: s.Close * d.Ratio,
+			High:  s.High * d.Ratio,
+			Low:   s.Low * d.Ratio,
+			Days:  s.Days,
+		}
+	}
+}
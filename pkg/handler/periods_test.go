@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"errors"
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestHandler_GetRatesPeriods(t *testing.T) {
+	rates := &fakeRates{periods: []model.RatePeriod{
+		{Start: "2021-02-01", Currency: "GBP", Avg: 0.87, Open: 0.88, Close: 0.86, High: 0.89, Low: 0.85, Count: 20},
+		{Start: "2021-02-01", Currency: "USD", Avg: 1.21, Open: 1.2, Close: 1.22, High: 1.23, Low: 1.19, Count: 20},
+		{Start: "2021-03-01", Currency: "USD", Avg: 1.19, Open: 1.2, Close: 1.17, High: 1.21, Low: 1.17, Count: 23},
+	}}
+	h := newTestHandler(Config{RateRepo: rates})
+
+	w := do(h.GetRatesPeriods, http.MethodGet, "/rates/periods?start=2021-02-01&end=2021-03-31", nil, false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var res ExchangeRatePeriods
+	decode(t, w, &res)
+	assert.Equal(t, "EUR", res.Base)
+	assert.Equal(t, "month", res.Granularity)
+	assert.True(t, res.OverridesExcluded)
+	assert.Len(t, res.Periods, 2)
+	assert.Equal(t, "2021-02", res.Periods[0].Period)
+	assert.Equal(t, "2021-02-28", res.Periods[0].End)
+	assert.Equal(t, PeriodStats{Avg: 1.21, Open: 1.2, Close: 1.22, High: 1.23, Low: 1.19, Days: 20},
+		res.Periods[0].Rates["USD"])
+	assert.Len(t, res.Periods[1].Rates, 1)
+
+	w = do(h.GetRatesPeriods, http.MethodGet, "/rates/periods?granularity=quarter&start=2021-01-01&end=2021-03-31&symbols=gbp", nil, false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	res = ExchangeRatePeriods{}
+	decode(t, w, &res)
+	assert.Equal(t, "quarter", res.Granularity)
+	assert.Len(t, res.Periods[0].Rates, 1)
+	assert.Contains(t, res.Periods[0].Rates, "GBP")
+
+	for _, target := range []string{
+		"/rates/periods?start=2021-02-01",
+		"/rates/periods?granularity=day&start=2021-02-01&end=2021-03-31",
+		"/rates/periods?start=2021-03-31&end=2021-02-01",
+	} {
+		w = do(h.GetRatesPeriods, http.MethodGet, target, nil, false)
+		assert.Equal(t, http.StatusBadRequest, w.Code, target)
+	}
+
+	rates.err = errors.New("db down")
+	w = do(h.GetRatesPeriods, http.MethodGet, "/rates/periods?start=2021-02-01&end=2021-03-31", nil, false)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
@@ -0,0 +1,213 @@
+package handler
+
+import (
+	"encoding/json"
+	"github.com/huyhvq/eurofxref/pkg/calendar"
+	"github.com/huyhvq/eurofxref/pkg/fx"
+	"github.com/huyhvq/eurofxref/pkg/risk"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	defaultVaRLookback = 250
+	maxVaRLookback     = 5000
+)
+
+var (
+	defaultConfidenceLevels = []float64{0.95, 0.99}
+	defaultHorizons         = []int{1}
+)
+
+type PortfolioRequest struct {
+	Base             string             `json:"base"`
+	Date             string             `json:"date"`
+	Lookback         int                `json:"lookback"`
+	ConfidenceLevels []float64          `json:"confidence_levels"`
+	Horizons         []int              `json:"horizons"`
+	Exposures        map[string]float64 `json:"exposures"`
+}
+
+type PortfolioVaR struct {
+	Base       string              `json:"base"`
+	Date       string              `json:"date"`
+	Lookback   int                 `json:"lookback"`
+	Value      float64             `json:"value"`
+	Positions  []PortfolioPosition `json:"positions"`
+	Risk       []PortfolioRisk     `json:"risk"`
+	Derived    []string            `json:"derived,omitempty"`
+	Overridden []string            `json:"overridden,omitempty"`
+}
+
+type PortfolioPosition struct {
+	Currency string  `json:"currency"`
+	Amount   float64 `json:"amount"`
+	Rate     float64 `json:"rate"`
+	Value    float64 `json:"value"`
+}
+
+type PortfolioRisk struct {
+	Confidence        float64  `json:"confidence"`
+	Horizon           int      `json:"horizon"`
+	Scenarios         int      `json:"scenarios"`
+	VaR               *float64 `json:"var"`
+	ExpectedShortfall *float64 `json:"expected_shortfall"`
+}
+
+// GetPortfolioVaR values a portfolio of currency exposures in base and
+// estimates its value at risk and expected shortfall by historical simulation
+// over the lookback publication days up to date.
+func (h *handler) GetPortfolioVaR(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		errorRespond(w, http.StatusMethodNotAllowed, errInvalidMethod.Error())
+		return
+	}
+	var req PortfolioRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		errorRespond(w, http.StatusBadRequest, errInvalidRequest.Error())
+		return
+	}
+	if err := normalizePortfolio(&req); err != nil {
+		errorRespond(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var (
+		end time.Time
+		err error
+	)
+	if req.Date == "" {
+		if end, err = h.rateRepo.GetLatestDate(); err != nil {
+			errorRespond(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	} else {
+		if end, err = time.ParseInLocation("2006-01-02", req.Date, time.UTC); err != nil {
+			errorRespond(w, http.StatusBadRequest, errInvalidRequest.Error())
+			return
+		}
+		end = calendar.OnOrBefore(end)
+	}
+
+	symbols := make([]string, 0, len(req.Exposures))
+	for c := range req.Exposures {
+		if c != req.Base {
+			symbols = append(symbols, c)
+		}
+	}
+	sort.Strings(symbols)
+	if len(symbols) == 0 {
+		errorRespond(w, http.StatusBadRequest, errInvalidRequest.Error())
+		return
+	}
+	series, overridden, err := h.timeSeries(req.Base, symbols, end, end, req.Lookback)
+	if err == fx.ErrUnknownCurrency {
+		errorRespond(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		errorRespond(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	for _, s := range symbols {
+		if len(series[s]) == 0 {
+			errorRespond(w, http.StatusBadRequest, fx.ErrUnknownCurrency.Error())
+			return
+		}
+	}
+	dates, rates := fx.Align(series, symbols)
+	if len(dates) == 0 {
+		errorRespond(w, http.StatusNotFound, errNoRates.Error())
+		return
+	}
+
+	last := len(dates) - 1
+	res := &PortfolioVaR{
+		Base:       req.Base,
+		Date:       dates[last],
+		Lookback:   req.Lookback,
+		Positions:  make([]PortfolioPosition, 0, len(req.Exposures)),
+		Risk:       make([]PortfolioRisk, 0, len(req.ConfidenceLevels)*len(req.Horizons)),
+		Derived:    h.derivedSymbols(symbols, dates[last]),
+		Overridden: overridden,
+	}
+	if amount, ok := req.Exposures[req.Base]; ok {
+		res.Positions = append(res.Positions, PortfolioPosition{
+			Currency: req.Base,
+			Amount:   amount,
+			Rate:     1,
+			Value:    amount,
+		})
+		res.Value += amount
+	}
+	values := make([]float64, len(symbols))
+	for i, s := range symbols {
+		rate := rates[i][last]
+		values[i] = req.Exposures[s] / rate
+		res.Value += values[i]
+		res.Positions = append(res.Positions, PortfolioPosition{
+			Currency: s,
+			Amount:   req.Exposures[s],
+			Rate:     rate,
+			Value:    fx.Round(values[i], 2),
+		})
+	}
+	res.Value = fx.Round(res.Value, 2)
+
+	for _, horizon := range req.Horizons {
+		pnl := risk.PnL(values, rates, horizon)
+		for _, c := range req.ConfidenceLevels {
+			v, es := risk.VaR(pnl, c)
+			res.Risk = append(res.Risk, PortfolioRisk{
+				Confidence:        c,
+				Horizon:           horizon,
+				Scenarios:         len(pnl),
+				VaR:               optional(fx.Round(v, 2)),
+				ExpectedShortfall: optional(fx.Round(es, 2)),
+			})
+		}
+	}
+	jsonRespond(w, http.StatusOK, res)
+}
+
+// normalizePortfolio fills in the defaults of req, upper-cases its currencies
+// and rejects parameters outside their range.
+func normalizePortfolio(req *PortfolioRequest) error {
+	req.Base = strings.ToUpper(strings.TrimSpace(req.Base))
+	if req.Base == "" {
+		req.Base = fx.Base
+	}
+	if req.Lookback == 0 {
+		req.Lookback = defaultVaRLookback
+	}
+	if req.Lookback < 2 || req.Lookback > maxVaRLookback {
+		return errInvalidRequest
+	}
+	if len(req.ConfidenceLevels) == 0 {
+		req.ConfidenceLevels = defaultConfidenceLevels
+	}
+	for _, c := range req.ConfidenceLevels {
+		if c <= 0 || c >= 1 {
+			return errInvalidRequest
+		}
+	}
+	if len(req.Horizons) == 0 {
+		req.Horizons = defaultHorizons
+	}
+	for _, n := range req.Horizons {
+		if n < 1 || n >= req.Lookback {
+			return errInvalidRequest
+		}
+	}
+	if len(req.Exposures) == 0 {
+		return errInvalidRequest
+	}
+	exposures := make(map[string]float64, len(req.Exposures))
+	for c, amount := range req.Exposures {
+		exposures[strings.ToUpper(strings.TrimSpace(c))] += amount
+	}
+	req.Exposures = exposures
+	return nil
+}
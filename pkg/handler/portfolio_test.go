@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"errors"
+	"github.com/huyhvq/eurofxref/pkg/fx"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestHandler_GetPortfolioVaR(t *testing.T) {
+	h := newTestHandler(Config{RateRepo: &fakeRates{rates: weekRates}})
+
+	body := `{"date": "2021-03-27", "lookback": 3, "confidence_levels": [0.95], "horizons": [1, 2],
+		"exposures": {"usd": 1000, "GBP": 500, "EUR": 100}}`
+	w := do(h.GetPortfolioVaR, http.MethodPost, "/portfolio/var", strings.NewReader(body), false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var res PortfolioVaR
+	decode(t, w, &res)
+	assert.Equal(t, "EUR", res.Base)
+	assert.Equal(t, "2021-03-26", res.Date)
+	assert.Equal(t, fx.Round(100+1000/1.1795+500/0.8556, 2), res.Value)
+	assert.Equal(t, []PortfolioPosition{
+		{Currency: "EUR", Amount: 100, Rate: 1, Value: 100},
+		{Currency: "GBP", Amount: 500, Rate: 0.8556, Value: fx.Round(500/0.8556, 2)},
+		{Currency: "USD", Amount: 1000, Rate: 1.1795, Value: fx.Round(1000/1.1795, 2)},
+	}, res.Positions)
+	assert.Len(t, res.Risk, 2)
+	assert.Equal(t, 1, res.Risk[0].Horizon)
+	assert.Equal(t, 3, res.Risk[0].Scenarios)
+	assert.Equal(t, 2, res.Risk[1].Scenarios)
+	assert.NotNil(t, res.Risk[0].VaR)
+
+	for _, tc := range []struct {
+		name string
+		body string
+		code int
+	}{
+		{"malformed", `{"exposures": `, http.StatusBadRequest},
+		{"no exposures", `{}`, http.StatusBadRequest},
+		{"base only", `{"exposures": {"EUR": 100}}`, http.StatusBadRequest},
+		{"confidence out of range", `{"confidence_levels": [1], "exposures": {"USD": 1}}`, http.StatusBadRequest},
+		{"horizon beyond lookback", `{"lookback": 3, "horizons": [3], "exposures": {"USD": 1}}`, http.StatusBadRequest},
+		{"invalid date", `{"date": "26-03-2021", "exposures": {"USD": 1}}`, http.StatusBadRequest},
+		{"unknown currency", `{"lookback": 3, "exposures": {"XXX": 1}}`, http.StatusBadRequest},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := do(h.GetPortfolioVaR, http.MethodPost, "/portfolio/var", strings.NewReader(tc.body), false)
+			assert.Equal(t, tc.code, w.Code)
+		})
+	}
+
+	w = do(newTestHandler(Config{RateRepo: &fakeRates{err: errors.New("db down")}}).GetPortfolioVaR,
+		http.MethodPost, "/portfolio/var", strings.NewReader(`{"exposures": {"USD": 1}}`), false)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	w = do(h.GetPortfolioVaR, http.MethodGet, "/portfolio/var", nil, false)
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
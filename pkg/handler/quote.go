@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/huyhvq/eurofxref/pkg/currency"
+	"github.com/huyhvq/eurofxref/pkg/fx"
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"github.com/huyhvq/eurofxref/pkg/repository"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const defaultQuoteTTL = 5 * time.Minute
+
+var (
+	errQuoteExpired  = errors.New("quote expired")
+	errQuoteRedeemed = errors.New("quote already redeemed")
+)
+
+type QuoteRequest struct {
+	Amount  float64 `json:"amount"`
+	From    string  `json:"from"`
+	To      string  `json:"to"`
+	Profile string  `json:"profile,omitempty"`
+	Side    string  `json:"side,omitempty"`
+}
+
+type RateQuote struct {
+	ID         string     `json:"id"`
+	Amount     float64    `json:"amount"`
+	From       string     `json:"from"`
+	To         string     `json:"to"`
+	Rate       float64    `json:"rate"`
+	Result     float64    `json:"result"`
+	RateDate   string     `json:"rate_date"`
+	Profile    string     `json:"profile,omitempty"`
+	Side       string     `json:"side"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RedeemedAt *time.Time `json:"redeemed_at,omitempty"`
+}
+
+// CreateQuote serves POST /quotes. The latest rate is locked until the quote
+// expires; side picks the mid (default), bid or ask rate of the profile. The
+// result is rounded to the minor units of the target currency.
+func (h *handler) CreateQuote(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		errorRespond(w, http.StatusMethodNotAllowed, errInvalidMethod.Error())
+		return
+	}
+	var req QuoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Amount <= 0 || !h.spreads.HasProfile(req.Profile) {
+		errorRespond(w, http.StatusBadRequest, errInvalidRequest.Error())
+		return
+	}
+	side := strings.ToLower(strings.TrimSpace(req.Side))
+	if side == "" {
+		side = "mid"
+	}
+	if side != "mid" && side != "bid" && side != "ask" {
+		errorRespond(w, http.StatusBadRequest, errInvalidRequest.Error())
+		return
+	}
+	results, err := h.convertBatch([]ConversionItem{{Amount: req.Amount, From: req.From, To: req.To}},
+		strings.TrimSpace(req.Profile))
+	if err != nil {
+		errorRespond(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	res := results[0]
+	if res.err != nil {
+		conversionError(w, res.err)
+		return
+	}
+	rate := *res.Mid
+	switch side {
+	case "bid":
+		rate = *res.Bid
+	case "ask":
+		rate = *res.Ask
+	}
+	minor := 2
+	if c, ok := currency.Lookup(res.To); ok {
+		minor = c.MinorUnits
+	}
+	id, err := newQuoteID()
+	if err != nil {
+		errorRespond(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ttl := h.quoteTTL
+	if ttl <= 0 {
+		ttl = defaultQuoteTTL
+	}
+	now := time.Now().UTC()
+	q := model.Quote{
+		ID:        id,
+		From:      res.From,
+		To:        res.To,
+		Amount:    req.Amount,
+		Rate:      rate,
+		Result:    fx.Round(req.Amount*rate, minor),
+		RateDate:  res.RateDate,
+		Profile:   strings.TrimSpace(req.Profile),
+		Side:      side,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+	if err := h.quoteRepo.Insert(q); err != nil {
+		errorRespond(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	jsonRespond(w, http.StatusCreated, quoteTransform(q))
+}
+
+// Quote serves GET /quotes/{id} and POST /quotes/{id}/redeem. Redeeming an
+// expired quote responds 410, redeeming one twice 409.
+func (h *handler) Quote(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path[len("/quotes/"):], "/"), "/")
+	redeem := len(parts) == 2 && parts[1] == "redeem"
+	if parts[0] == "" || len(parts) > 2 || (len(parts) == 2 && !redeem) {
+		errorRespond(w, http.StatusNotFound, errNotFound.Error())
+		return
+	}
+	if (redeem && r.Method != http.MethodPost) || (!redeem && r.Method != http.MethodGet) {
+		errorRespond(w, http.StatusMethodNotAllowed, errInvalidMethod.Error())
+		return
+	}
+	q, err := h.quoteRepo.GetByID(parts[0])
+	if err == repository.ErrNotFound {
+		errorRespond(w, http.StatusNotFound, errNotFound.Error())
+		return
+	}
+	if err != nil {
+		errorRespond(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !redeem {
+		jsonRespond(w, http.StatusOK, quoteTransform(q))
+		return
+	}
+
+	now := time.Now().UTC()
+	if q.RedeemedAt.IsZero() && now.Before(q.ExpiresAt) {
+		err = h.quoteRepo.Redeem(q.ID, now)
+		if err == nil {
+			q.RedeemedAt = now
+			jsonRespond(w, http.StatusOK, quoteTransform(q))
+			return
+		}
+		if err != repository.ErrNotFound {
+			errorRespond(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		// Redeemed, expired or removed since it was read.
+		q, err = h.quoteRepo.GetByID(q.ID)
+		if err == repository.ErrNotFound {
+			errorRespond(w, http.StatusNotFound, errNotFound.Error())
+			return
+		}
+		if err != nil {
+			errorRespond(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	if !q.RedeemedAt.IsZero() {
+		errorRespond(w, http.StatusConflict, errQuoteRedeemed.Error())
+		return
+	}
+	errorRespond(w, http.StatusGone, errQuoteExpired.Error())
+}
+
+// newQuoteID returns 32 random hex characters.
+func newQuoteID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func quoteTransform(q model.Quote) *RateQuote {
+	res := &RateQuote{
+		ID:        q.ID,
+		Amount:    q.Amount,
+		From:      q.From,
+		To:        q.To,
+		Rate:      q.Rate,
+		Result:    q.Result,
+		RateDate:  q.RateDate,
+		Profile:   q.Profile,
+		Side:      q.Side,
+		CreatedAt: q.CreatedAt,
+		ExpiresAt: q.ExpiresAt,
+	}
+	if !q.RedeemedAt.IsZero() {
+		res.RedeemedAt = &q.RedeemedAt
+	}
+	return res
+}
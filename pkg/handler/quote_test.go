@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"errors"
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"github.com/huyhvq/eurofxref/pkg/repository"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeQuotes keeps quotes in memory. race, when set, runs before a
+// redemption is stored, as another request would in between.
+type fakeQuotes struct {
+	quotes map[string]model.Quote
+	race   func(f *fakeQuotes)
+	err    error
+}
+
+func (f *fakeQuotes) Insert(q model.Quote) error {
+	if f.err != nil {
+		return f.err
+	}
+	if f.quotes == nil {
+		f.quotes = make(map[string]model.Quote)
+	}
+	f.quotes[q.ID] = q
+	return nil
+}
+
+func (f *fakeQuotes) GetByID(id string) (model.Quote, error) {
+	q, ok := f.quotes[id]
+	if !ok {
+		return model.Quote{}, repository.ErrNotFound
+	}
+	return q, nil
+}
+
+func (f *fakeQuotes) Redeem(id string, at time.Time) error {
+	if f.race != nil {
+		f.race(f)
+	}
+	q, ok := f.quotes[id]
+	if !ok || !q.RedeemedAt.IsZero() || !at.Before(q.ExpiresAt) {
+		return repository.ErrNotFound
+	}
+	q.RedeemedAt = at
+	f.quotes[id] = q
+	return nil
+}
+
+func TestHandler_Quotes(t *testing.T) {
+	quotes := &fakeQuotes{}
+	h := newSpreadHandler(t)
+	h.quoteRepo = quotes
+
+	w := do(h.CreateQuote, http.MethodPost, "/quotes",
+		strings.NewReader(`{"amount": 100, "from": "eur", "to": "JPY", "side": "ask"}`), false)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var q RateQuote
+	decode(t, w, &q)
+	assert.Len(t, q.ID, 32)
+	assert.Equal(t, "JPY", q.To)
+	assert.Equal(t, "ask", q.Side)
+	assert.Equal(t, "2021-03-29", q.RateDate)
+	assert.InDelta(t, 129.61*1.001, q.Rate, 1e-12)
+	assert.Equal(t, float64(12974), q.Result)
+	assert.Equal(t, defaultQuoteTTL, q.ExpiresAt.Sub(q.CreatedAt))
+	assert.Nil(t, q.RedeemedAt)
+
+	w = do(h.Quote, http.MethodGet, "/quotes/"+q.ID, nil, false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var got RateQuote
+	decode(t, w, &got)
+	assert.Equal(t, q.ID, got.ID)
+	assert.Nil(t, got.RedeemedAt)
+
+	w = do(h.Quote, http.MethodPost, "/quotes/"+q.ID+"/redeem", nil, false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	got = RateQuote{}
+	decode(t, w, &got)
+	assert.NotNil(t, got.RedeemedAt)
+
+	w = do(h.Quote, http.MethodPost, "/quotes/"+q.ID+"/redeem", nil, false)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, errQuoteRedeemed.Error(), errorOf(t, w))
+
+	now := time.Now().UTC()
+	quotes.quotes["expired"] = model.Quote{ID: "expired", CreatedAt: now.Add(-time.Hour), ExpiresAt: now.Add(-time.Minute)}
+	w = do(h.Quote, http.MethodPost, "/quotes/expired/redeem", nil, false)
+	assert.Equal(t, http.StatusGone, w.Code)
+	assert.Equal(t, errQuoteExpired.Error(), errorOf(t, w))
+	w = do(h.Quote, http.MethodGet, "/quotes/expired", nil, false)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	for _, tc := range []struct {
+		name   string
+		method string
+		target string
+		body   string
+		code   int
+	}{
+		{"malformed", http.MethodPost, "/quotes", `{"amount": `, http.StatusBadRequest},
+		{"no amount", http.MethodPost, "/quotes", `{"from": "EUR", "to": "USD"}`, http.StatusBadRequest},
+		{"unknown side", http.MethodPost, "/quotes", `{"amount": 1, "from": "EUR", "to": "USD", "side": "best"}`, http.StatusBadRequest},
+		{"unknown profile", http.MethodPost, "/quotes", `{"amount": 1, "from": "EUR", "to": "USD", "profile": "vip"}`, http.StatusBadRequest},
+		{"unknown currency", http.MethodPost, "/quotes", `{"amount": 1, "from": "EUR", "to": "XXX"}`, http.StatusBadRequest},
+		{"create method", http.MethodGet, "/quotes", "", http.StatusMethodNotAllowed},
+		{"unknown quote", http.MethodGet, "/quotes/missing", "", http.StatusNotFound},
+		{"redeem unknown quote", http.MethodPost, "/quotes/missing/redeem", "", http.StatusNotFound},
+		{"unknown action", http.MethodPost, "/quotes/" + q.ID + "/cancel", "", http.StatusNotFound},
+		{"get method", http.MethodPost, "/quotes/" + q.ID, "", http.StatusMethodNotAllowed},
+		{"redeem method", http.MethodGet, "/quotes/" + q.ID + "/redeem", "", http.StatusMethodNotAllowed},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fn := h.Quote
+			if tc.target == "/quotes" {
+				fn = h.CreateQuote
+			}
+			w := do(fn, tc.method, tc.target, strings.NewReader(tc.body), false)
+			assert.Equal(t, tc.code, w.Code)
+		})
+	}
+
+	w = do(newTestHandler(Config{RateRepo: &fakeRates{}, QuoteRepo: quotes}).CreateQuote, http.MethodPost, "/quotes",
+		strings.NewReader(`{"amount": 1, "from": "EUR", "to": "USD"}`), false)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, errNoRates.Error(), errorOf(t, w))
+
+	quotes.err = errors.New("db down")
+	w = do(h.CreateQuote, http.MethodPost, "/quotes", strings.NewReader(`{"amount": 1, "from": "EUR", "to": "USD"}`), false)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestHandler_Quote_RedeemRace(t *testing.T) {
+	now := time.Now().UTC()
+	open := model.Quote{ID: "q", CreatedAt: now, ExpiresAt: now.Add(time.Minute)}
+	for _, tc := range []struct {
+		name string
+		race func(f *fakeQuotes)
+		code int
+	}{
+		{"redeemed meanwhile", func(f *fakeQuotes) {
+			q := f.quotes["q"]
+			q.RedeemedAt = now
+			f.quotes["q"] = q
+		}, http.StatusConflict},
+		{"expired meanwhile", func(f *fakeQuotes) {
+			q := f.quotes["q"]
+			q.ExpiresAt = now
+			f.quotes["q"] = q
+		}, http.StatusGone},
+		{"removed meanwhile", func(f *fakeQuotes) { delete(f.quotes, "q") }, http.StatusNotFound},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			quotes := &fakeQuotes{quotes: map[string]model.Quote{"q": open}, race: tc.race}
+			w := do(newTestHandler(Config{QuoteRepo: quotes}).Quote, http.MethodPost, "/quotes/q/redeem", nil, false)
+			assert.Equal(t, tc.code, w.Code)
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"github.com/huyhvq/eurofxref/pkg/calendar"
+	"github.com/huyhvq/eurofxref/pkg/fx"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const maxWindow = 260
+
+// timeSeries loads the series of symbols against base between start and end,
+// extended backwards by lookback publication days so that windowed
+// calculations are defined from start on. It also returns the currencies
+// with a manually overridden rate in the loaded range. A symbol without any
+// rate in a range that has rates is fx.ErrUnknownCurrency.
+func (h *handler) timeSeries(base string, symbols []string, start, end time.Time, lookback int) (map[string][]fx.Point, []string, error) {
+	from := start
+	for i := 0; i < lookback; i++ {
+		from = calendar.Previous(from)
+	}
+	rates, err := h.rateRepo.GetRatesBetween(from, end)
+	if err != nil {
+		return nil, nil, err
+	}
+	series, err := fx.TimeSeries(rates, base, symbols, h.pegs)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, s := range symbols {
+		if len(series[s]) == 0 && len(rates) > 0 {
+			return nil, nil, fx.ErrUnknownCurrency
+		}
+	}
+	return series, overriddenSymbols(rates, nil), nil
+}
+
+// firstOnOrAfter returns the index of the first point dated on or after date.
+func firstOnOrAfter(points []fx.Point, date time.Time) int {
+	d := date.Format("2006-01-02")
+	for i, p := range points {
+		if p.Date >= d {
+			return i
+		}
+	}
+	return len(points)
+}
+
+// optional maps NaN to nil so undefined values are left out of JSON.
+func optional(v float64) *float64 {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil
+	}
+	return &v
+}
+
+func parseWindow(v string, def int) (int, error) {
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 2 || n > maxWindow {
+		return 0, errInvalidRequest
+	}
+	return n, nil
+}
+
+// parseWindows reads a comma-separated list of windows, dropping repeats.
+func parseWindows(v string, def int) ([]int, error) {
+	if v == "" {
+		return []int{def}, nil
+	}
+	windows := make([]int, 0)
+	seen := make(map[int]struct{})
+	for _, s := range strings.Split(v, ",") {
+		n, err := parseWindow(strings.TrimSpace(s), def)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := seen[n]; ok {
+			continue
+		}
+		seen[n] = struct{}{}
+		windows = append(windows, n)
+	}
+	return windows, nil
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/csv"
+	"github.com/huyhvq/eurofxref/pkg/spread"
+	"github.com/stretchr/testify/assert"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// newSpreadHandler returns a test handler marking up every pair by 10 bps,
+// and EUR/USD by 1% for the retail profile.
+func newSpreadHandler(t *testing.T) *handler {
+	rules, err := spread.New([]spread.Rule{
+		{Pair: "*", Bps: 10},
+		{Profile: "retail", Pair: "EUR/USD", Percent: 1},
+	})
+	assert.Nil(t, err)
+	return newTestHandler(Config{Spreads: rules})
+}
+
+func TestHandler_spreads(t *testing.T) {
+	h := newSpreadHandler(t)
+
+	w := do(h.GetRatesByDate, http.MethodGet, "/rates/2021-03-26/EUR/USD?profile=retail", nil, false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var p PairRate
+	decode(t, w, &p)
+	assert.Equal(t, "retail", p.Profile)
+	assert.Equal(t, 1.1795, p.Mid)
+	assert.InDelta(t, 1.1795*0.99, p.Bid, 1e-12)
+	assert.InDelta(t, 1.1795*1.01, p.Ask, 1e-12)
+	assert.InDelta(t, 100, p.MarkupBps, 1e-9)
+
+	w = do(h.GetRatesByDate, http.MethodGet, "/rates/2021-03-26/EUR/GBP?profile=retail", nil, false)
+	p = PairRate{}
+	decode(t, w, &p)
+	assert.InDelta(t, 0.8556*0.999, p.Bid, 1e-12)
+	assert.InDelta(t, 10, p.MarkupBps, 1e-9)
+
+	w = do(h.Convert, http.MethodGet, "/convert?from=EUR&to=USD&amount=100&date=2021-03-26&profile=retail", nil, false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var res ConversionResult
+	decode(t, w, &res)
+	assert.Equal(t, 117.95, *res.Result)
+	assert.InDelta(t, 1.1795*0.99, *res.Bid, 1e-12)
+	assert.InDelta(t, 1.1795*1.01, *res.Ask, 1e-12)
+
+	body := `{"profile": "retail", "items": [
+		{"amount": 1, "from": "EUR", "to": "USD", "date": "2021-03-26"},
+		{"amount": 1, "from": "EUR", "to": "GBP", "date": "2021-03-26"}
+	]}`
+	w = do(h.ConvertBatch, http.MethodPost, "/convert/batch", strings.NewReader(body), false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var batch ConversionBatchResult
+	decode(t, w, &batch)
+	assert.InDelta(t, 1.1795*1.01, *batch.Results[0].Ask, 1e-12)
+	assert.InDelta(t, 0.8556*1.001, *batch.Results[1].Ask, 1e-12)
+}
+
+func TestHandler_ConvertCSV_Profile(t *testing.T) {
+	h := newSpreadHandler(t)
+	bidAsk := func(m float64) []string {
+		return []string{
+			strconv.FormatFloat(1.1795*(1-m), 'f', -1, 64),
+			strconv.FormatFloat(1.1795*(1+m), 'f', -1, 64),
+		}
+	}
+
+	w := do(h.ConvertCSV, http.MethodPost, "/convert/csv?to=USD&profile=retail",
+		strings.NewReader("amount,currency,date\n1,EUR,2021-03-26\n1,XXX,2021-03-26\n"), false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	records, err := csv.NewReader(w.Body).ReadAll()
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{
+		{"amount", "currency", "date", "converted_amount", "rate", "bid", "ask", "rate_date", "error"},
+		append(append([]string{"1", "EUR", "2021-03-26", "1.18", "1.1795"}, bidAsk(0.01)...), "2021-03-26", ""),
+		{"1", "XXX", "2021-03-26", "", "", "", "", "", "unknown currency"},
+	}, records)
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	fw, err := mw.CreateFormFile("file", "amounts.csv")
+	assert.Nil(t, err)
+	fw.Write([]byte("amount,currency,date\n1,EUR,2021-03-26\n"))
+	assert.Nil(t, mw.WriteField("profile", " "))
+	mw.Close()
+	r := httptest.NewRequest(http.MethodPost, "/convert/csv?to=USD&profile=retail", &buf)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	w = httptest.NewRecorder()
+	h.ConvertCSV(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	records, err = csv.NewReader(w.Body).ReadAll()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"amount", "currency", "date", "converted_amount", "rate", "rate_date", "error"}, records[0])
+
+	buf.Reset()
+	mw = multipart.NewWriter(&buf)
+	assert.Nil(t, mw.WriteField("profile", "retail"))
+	fw, err = mw.CreateFormFile("file", "amounts.csv")
+	assert.Nil(t, err)
+	fw.Write([]byte("amount,currency,date\n1,EUR,2021-03-26\n"))
+	mw.Close()
+	r = httptest.NewRequest(http.MethodPost, "/convert/csv?to=USD", &buf)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	w = httptest.NewRecorder()
+	h.ConvertCSV(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	records, err = csv.NewReader(w.Body).ReadAll()
+	assert.Nil(t, err)
+	assert.Equal(t, bidAsk(0.01), records[1][5:7])
+
+	w = do(h.ConvertCSV, http.MethodPost, "/convert/csv?profile=vip", strings.NewReader("amount,currency\n1,USD\n"), false)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, errInvalidRequest.Error(), errorOf(t, w))
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/huyhvq/eurofxref/pkg/eventbus"
+	"github.com/huyhvq/eurofxref/pkg/fx"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// heartbeatInterval keeps idle streams from being closed by proxies.
+const heartbeatInterval = 15 * time.Second
+
+// StreamRates serves /rates/stream?symbols=&base=, a Server-Sent Events stream
+// with a "rates" event for every newly stored publication date. A client
+// reconnecting with Last-Event-ID first receives the events it missed; when
+// they are no longer kept it receives a "reset" event and should reload
+// /rates/latest.
+func (h *handler) StreamRates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		errorRespond(w, http.StatusMethodNotAllowed, errInvalidMethod.Error())
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok || h.bus == nil {
+		errorRespond(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("last_event_id")
+	}
+	var since uint64
+	if lastID != "" {
+		var err error
+		if since, err = strconv.ParseUint(lastID, 10, 64); err != nil {
+			errorRespond(w, http.StatusBadRequest, errInvalidRequest.Error())
+			return
+		}
+	}
+	base := parseBase(r)
+	if len(base) != 3 {
+		errorRespond(w, http.StatusBadRequest, errInvalidRequest.Error())
+		return
+	}
+	symbols := parseSymbols(r)
+
+	missed, complete, events, cancel := h.bus.Subscribe(since)
+	defer cancel()
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", 5000)
+	if lastID != "" {
+		if !complete {
+			fmt.Fprintf(w, "id: %d\nevent: reset\ndata: {}\n\n", h.bus.LastID())
+		} else {
+			for _, e := range missed {
+				h.writeRatesEvent(w, e, base, symbols)
+			}
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-events:
+			if !ok {
+				// Dropped for falling behind; the client resumes from the
+				// last ID it received.
+				return
+			}
+			h.writeRatesEvent(w, e, base, symbols)
+			flusher.Flush()
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// writeRatesEvent writes e as a "rates" event in base, restricted to symbols
+// when given. Events whose date does not price base are skipped.
+func (h *handler) writeRatesEvent(w http.ResponseWriter, e eventbus.Event, base string, symbols []string) {
+	table := fx.NewTable(e.Rates)
+	if len(symbols) > 0 {
+		table.WithFixed(e.Date)
+	}
+	rs, err := table.WithPegged(h.pegs, e.Date).Rebase(base)
+	if err != nil {
+		return
+	}
+	delete(rs, base)
+	codes := make([]string, 0, len(rs))
+	if len(symbols) > 0 {
+		filtered := make(map[string]float64, len(symbols))
+		for _, s := range symbols {
+			if v, ok := rs[s]; ok {
+				filtered[s] = v
+				codes = append(codes, s)
+			}
+		}
+		rs = filtered
+	} else {
+		for c := range rs {
+			codes = append(codes, c)
+		}
+	}
+	data, _ := json.Marshal(&ExchangeRate{
+		Base:       base,
+		Date:       e.Date,
+		Rates:      rs,
+		Derived:    h.derivedSymbols(codes, e.Date),
+		Overridden: overriddenSymbols(e.Rates, codes),
+	})
+	fmt.Fprintf(w, "id: %d\nevent: rates\ndata: %s\n\n", e.ID, data)
+}
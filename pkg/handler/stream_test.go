@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/huyhvq/eurofxref/pkg/eventbus"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// stream serves target until the events already kept are written and returns
+// the response.
+func stream(h *handler, target, lastEventID string) *httptest.ResponseRecorder {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r := httptest.NewRequest(http.MethodGet, target, nil).WithContext(ctx)
+	if lastEventID != "" {
+		r.Header.Set("Last-Event-ID", lastEventID)
+	}
+	w := httptest.NewRecorder()
+	h.StreamRates(w, r)
+	return w
+}
+
+func TestHandler_StreamRates(t *testing.T) {
+	bus := eventbus.New(1, 20210325)
+	bus.Publish(20210326, "2021-03-26", testRates[:3])
+	bus.Publish(20210329, "2021-03-29", testRates[3:])
+	h := newTestHandler(Config{Bus: bus})
+
+	w := stream(h, "/rates/stream?base=usd&symbols=GBP", "20210326")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+	events := strings.Split(strings.TrimSpace(w.Body.String()), "\n\n")
+	assert.Len(t, events, 2)
+	assert.Equal(t, "retry: 5000", events[0])
+	lines := strings.Split(events[1], "\n")
+	assert.Equal(t, []string{"id: 20210329", "event: rates"}, lines[:2])
+	var er ExchangeRate
+	assert.Nil(t, json.Unmarshal([]byte(strings.TrimPrefix(lines[2], "data: ")), &er))
+	assert.Equal(t, "USD", er.Base)
+	assert.Equal(t, "2021-03-29", er.Date)
+	assert.Len(t, er.Rates, 1)
+	assert.InDelta(t, 0.8551/1.1765, er.Rates["GBP"], 1e-12)
+
+	// The 2021-03-26 event is gone and a client behind it reloads.
+	w = stream(h, "/rates/stream?last_event_id=20210325", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "id: 20210329\nevent: reset\ndata: {}\n\n")
+	assert.NotContains(t, w.Body.String(), "event: rates")
+
+	// Without Last-Event-ID nothing is replayed.
+	w = stream(h, "/rates/stream", "")
+	assert.Equal(t, "retry: 5000\n\n", w.Body.String())
+
+	for _, target := range []string{
+		"/rates/stream?last_event_id=x",
+		"/rates/stream?base=EURO",
+	} {
+		w = stream(h, target, "")
+		assert.Equal(t, http.StatusBadRequest, w.Code, target)
+		assert.Equal(t, errInvalidRequest.Error(), errorOf(t, w))
+	}
+
+	w = stream(newTestHandler(Config{}), "/rates/stream", "")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	w = do(h.StreamRates, http.MethodPost, "/rates/stream", nil, false)
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
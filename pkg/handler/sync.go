@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"github.com/huyhvq/eurofxref/pkg/repository"
+	"github.com/huyhvq/eurofxref/pkg/syncer"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const defaultSyncRunsLimit = 20
+
+type SyncRun struct {
+	ID           int64      `json:"id"`
+	Provider     string     `json:"provider"`
+	Endpoint     string     `json:"endpoint"`
+	TriggeredBy  string     `json:"triggered_by"`
+	Status       string     `json:"status"`
+	HTTPStatus   int        `json:"http_status"`
+	FeedHash     string     `json:"feed_hash"`
+	DatesFetched int        `json:"dates_fetched"`
+	FirstDate    string     `json:"first_date,omitempty"`
+	LastDate     string     `json:"last_date,omitempty"`
+	RowsInserted int        `json:"rows_inserted"`
+	RowsRevised  int        `json:"rows_revised"`
+	Error        string     `json:"error,omitempty"`
+	StartedAt    time.Time  `json:"started_at"`
+	FinishedAt   *time.Time `json:"finished_at,omitempty"`
+}
+
+func (h *handler) TriggerSync(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		errorRespond(w, http.StatusMethodNotAllowed, errInvalidMethod.Error())
+		return
+	}
+	if !h.authorized(r) {
+		errorRespond(w, http.StatusUnauthorized, errUnauthorized.Error())
+		return
+	}
+	run, err := h.syncer.Sync(syncer.TriggerManual)
+	if err != nil && run.ID == 0 {
+		errorRespond(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	code := http.StatusOK
+	if run.Status == model.SyncFailed {
+		code = http.StatusBadGateway
+	}
+	jsonRespond(w, code, syncRunTransform(run))
+}
+
+func (h *handler) GetSyncRuns(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		errorRespond(w, http.StatusMethodNotAllowed, errInvalidMethod.Error())
+		return
+	}
+	if !h.authorized(r) {
+		errorRespond(w, http.StatusUnauthorized, errUnauthorized.Error())
+		return
+	}
+	limit := defaultSyncRunsLimit
+	if l := r.URL.Query().Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n <= 0 {
+			errorRespond(w, http.StatusBadRequest, errInvalidRequest.Error())
+			return
+		}
+		limit = n
+	}
+	runs, err := h.syncRunRepo.GetRuns(limit)
+	if err != nil {
+		errorRespond(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	rs := make([]*SyncRun, 0, len(runs))
+	for _, run := range runs {
+		rs = append(rs, syncRunTransform(run))
+	}
+	jsonRespond(w, http.StatusOK, rs)
+}
+
+func (h *handler) GetSyncRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		errorRespond(w, http.StatusMethodNotAllowed, errInvalidMethod.Error())
+		return
+	}
+	if !h.authorized(r) {
+		errorRespond(w, http.StatusUnauthorized, errUnauthorized.Error())
+		return
+	}
+	id, err := strconv.ParseInt(r.URL.Path[len("/admin/sync/runs/"):], 10, 64)
+	if err != nil {
+		errorRespond(w, http.StatusNotFound, errInvalidRequest.Error())
+		return
+	}
+	run, err := h.syncRunRepo.GetRunByID(id)
+	if err == repository.ErrNotFound {
+		errorRespond(w, http.StatusNotFound, errNotFound.Error())
+		return
+	}
+	if err != nil {
+		errorRespond(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	jsonRespond(w, http.StatusOK, syncRunTransform(run))
+}
+
+func syncRunTransform(run model.SyncRun) *SyncRun {
+	s := &SyncRun{
+		ID:           run.ID,
+		Provider:     run.Provider,
+		Endpoint:     run.Endpoint,
+		TriggeredBy:  run.TriggeredBy,
+		Status:       run.Status,
+		HTTPStatus:   run.HTTPStatus,
+		FeedHash:     run.FeedHash,
+		DatesFetched: run.DatesFetched,
+		FirstDate:    run.FirstDate,
+		LastDate:     run.LastDate,
+		RowsInserted: run.RowsInserted,
+		RowsRevised:  run.RowsRevised,
+		Error:        run.Error,
+		StartedAt:    run.StartedAt,
+	}
+	if !run.FinishedAt.IsZero() {
+		s.FinishedAt = &run.FinishedAt
+	}
+	return s
+}
+
+func (h *handler) GetConsistency(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		errorRespond(w, http.StatusMethodNotAllowed, errInvalidMethod.Error())
+		return
+	}
+	if !h.authorized(r) {
+		errorRespond(w, http.StatusUnauthorized, errUnauthorized.Error())
+		return
+	}
+	start, end, err := parseRange(r, false)
+	if err != nil {
+		errorRespond(w, http.StatusBadRequest, errInvalidRequest.Error())
+		return
+	}
+	report, err := h.syncer.Check(start, end)
+	if err != nil {
+		errorRespond(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	jsonRespond(w, http.StatusOK, report)
+}
+
+func (h *handler) Repair(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		errorRespond(w, http.StatusMethodNotAllowed, errInvalidMethod.Error())
+		return
+	}
+	if !h.authorized(r) {
+		errorRespond(w, http.StatusUnauthorized, errUnauthorized.Error())
+		return
+	}
+	start, end, err := parseRange(r, false)
+	if err != nil {
+		errorRespond(w, http.StatusBadRequest, errInvalidRequest.Error())
+		return
+	}
+	run, err := h.syncer.Repair(syncer.TriggerManual, start, end)
+	if err != nil && run.ID == 0 {
+		errorRespond(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	code := http.StatusOK
+	if run.Status == model.SyncFailed {
+		code = http.StatusBadGateway
+	}
+	jsonRespond(w, code, syncRunTransform(run))
+}
@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"errors"
+	"github.com/huyhvq/eurofxref/pkg/consistency"
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"github.com/huyhvq/eurofxref/pkg/syncer"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+var testRun = model.SyncRun{
+	ID:           7,
+	Provider:     "ecb",
+	Endpoint:     "https://example.com/feed.xml",
+	TriggeredBy:  syncer.TriggerManual,
+	Status:       model.SyncSuccess,
+	HTTPStatus:   200,
+	FeedHash:     "abc",
+	DatesFetched: 2,
+	FirstDate:    "2021-03-26",
+	LastDate:     "2021-03-29",
+	RowsInserted: 6,
+	StartedAt:    time.Date(2021, 3, 29, 16, 0, 0, 0, time.UTC),
+	FinishedAt:   time.Date(2021, 3, 29, 16, 0, 1, 0, time.UTC),
+}
+
+func TestHandler_TriggerSync(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		sc := &fakeSyncer{run: testRun}
+		w := do(newTestHandler(Config{Syncer: sc}).TriggerSync, http.MethodPost, "/admin/sync", nil, true)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var run SyncRun
+		decode(t, w, &run)
+		assert.Equal(t, int64(7), run.ID)
+		assert.Equal(t, 6, run.RowsInserted)
+		assert.Equal(t, testRun.FinishedAt, *run.FinishedAt)
+		assert.Equal(t, syncer.TriggerManual, sc.triggeredBy)
+	})
+	t.Run("failed run", func(t *testing.T) {
+		run := testRun
+		run.Status, run.Error = model.SyncFailed, "feed unavailable"
+		sc := &fakeSyncer{run: run, err: errors.New("feed unavailable")}
+		w := do(newTestHandler(Config{Syncer: sc}).TriggerSync, http.MethodPost, "/admin/sync", nil, true)
+		assert.Equal(t, http.StatusBadGateway, w.Code)
+		var body SyncRun
+		decode(t, w, &body)
+		assert.Equal(t, "feed unavailable", body.Error)
+	})
+	t.Run("run not recorded", func(t *testing.T) {
+		sc := &fakeSyncer{err: errors.New("db down")}
+		w := do(newTestHandler(Config{Syncer: sc}).TriggerSync, http.MethodPost, "/admin/sync", nil, true)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, "db down", errorOf(t, w))
+	})
+	t.Run("unauthorized", func(t *testing.T) {
+		w := do(newTestHandler(Config{Syncer: &fakeSyncer{}}).TriggerSync, http.MethodPost, "/admin/sync", nil, false)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+	t.Run("invalid method", func(t *testing.T) {
+		w := do(newTestHandler(Config{Syncer: &fakeSyncer{}}).TriggerSync, http.MethodGet, "/admin/sync", nil, true)
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	})
+}
+
+func TestHandler_GetSyncRuns(t *testing.T) {
+	older := testRun
+	older.ID, older.FinishedAt = 6, time.Time{}
+	h := newTestHandler(Config{SyncRunRepo: &fakeSyncRuns{runs: []model.SyncRun{testRun, older}}})
+
+	w := do(h.GetSyncRuns, http.MethodGet, "/admin/sync/runs", nil, true)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var runs []SyncRun
+	decode(t, w, &runs)
+	assert.Len(t, runs, 2)
+	assert.Nil(t, runs[1].FinishedAt)
+
+	w = do(h.GetSyncRuns, http.MethodGet, "/admin/sync/runs?limit=1", nil, true)
+	decode(t, w, &runs)
+	assert.Len(t, runs, 1)
+
+	for _, target := range []string{"/admin/sync/runs?limit=0", "/admin/sync/runs?limit=x"} {
+		w = do(h.GetSyncRuns, http.MethodGet, target, nil, true)
+		assert.Equal(t, http.StatusBadRequest, w.Code, target)
+	}
+
+	w = do(h.GetSyncRuns, http.MethodGet, "/admin/sync/runs", nil, false)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = do(newTestHandler(Config{SyncRunRepo: &fakeSyncRuns{err: errors.New("db down")}}).GetSyncRuns,
+		http.MethodGet, "/admin/sync/runs", nil, true)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestHandler_GetSyncRun(t *testing.T) {
+	h := newTestHandler(Config{SyncRunRepo: &fakeSyncRuns{runs: []model.SyncRun{testRun}}})
+
+	w := do(h.GetSyncRun, http.MethodGet, "/admin/sync/runs/7", nil, true)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var run SyncRun
+	decode(t, w, &run)
+	assert.Equal(t, "abc", run.FeedHash)
+
+	w = do(h.GetSyncRun, http.MethodGet, "/admin/sync/runs/8", nil, true)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, errNotFound.Error(), errorOf(t, w))
+
+	w = do(h.GetSyncRun, http.MethodGet, "/admin/sync/runs/x", nil, true)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = do(h.GetSyncRun, http.MethodGet, "/admin/sync/runs/7", nil, false)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestHandler_GetConsistency(t *testing.T) {
+	report := consistency.Report{
+		Start:        "2021-03-01",
+		End:          "2021-03-31",
+		StoredDates:  21,
+		MissingDates: []string{"2021-03-15"},
+		PartialDates: []consistency.PartialDate{{Date: "2021-03-16", Currencies: 30, Expected: 32}},
+	}
+	sc := &fakeSyncer{report: report}
+	h := newTestHandler(Config{Syncer: sc})
+
+	w := do(h.GetConsistency, http.MethodGet, "/admin/consistency?start=2021-03-01&end=2021-03-31", nil, true)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var body consistency.Report
+	decode(t, w, &body)
+	assert.Equal(t, report, body)
+	assert.Equal(t, "2021-03-01", sc.start.Format("2006-01-02"))
+
+	w = do(h.GetConsistency, http.MethodGet, "/admin/consistency", nil, true)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, sc.start.IsZero() && sc.end.IsZero())
+
+	w = do(h.GetConsistency, http.MethodGet, "/admin/consistency?start=2021-03-31&end=2021-03-01", nil, true)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = do(h.GetConsistency, http.MethodGet, "/admin/consistency", nil, false)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = do(newTestHandler(Config{Syncer: &fakeSyncer{err: errors.New("db down")}}).GetConsistency,
+		http.MethodGet, "/admin/consistency", nil, true)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestHandler_Repair(t *testing.T) {
+	run := testRun
+	run.TriggeredBy, run.RowsInserted = syncer.TriggerManual, 32
+	sc := &fakeSyncer{run: run}
+	h := newTestHandler(Config{Syncer: sc})
+
+	w := do(h.Repair, http.MethodPost, "/admin/repair?start=2021-03-15&end=2021-03-16", nil, true)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var body SyncRun
+	decode(t, w, &body)
+	assert.Equal(t, 32, body.RowsInserted)
+	assert.Equal(t, "2021-03-16", sc.end.Format("2006-01-02"))
+
+	w = do(h.Repair, http.MethodPost, "/admin/repair?start=yesterday", nil, true)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = do(h.Repair, http.MethodGet, "/admin/repair", nil, true)
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+
+	w = do(h.Repair, http.MethodPost, "/admin/repair", nil, false)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	run.Status = model.SyncFailed
+	w = do(newTestHandler(Config{Syncer: &fakeSyncer{run: run, err: errors.New("feed unavailable")}}).Repair,
+		http.MethodPost, "/admin/repair", nil, true)
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+}
@@ -0,0 +1,216 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"github.com/huyhvq/eurofxref/pkg/repository"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultDeliveryLimit = 50
+	maxDeliveryLimit     = 1000
+)
+
+type WebhookRequest struct {
+	URL        string   `json:"url"`
+	Secret     string   `json:"secret,omitempty"`
+	Currencies []string `json:"currencies,omitempty"`
+}
+
+type Webhook struct {
+	ID         int64     `json:"id"`
+	URL        string    `json:"url"`
+	Secret     string    `json:"secret,omitempty"`
+	Currencies []string  `json:"currencies"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type WebhookDelivery struct {
+	ID             int64      `json:"id"`
+	Event          string     `json:"event"`
+	SyncRunID      int64      `json:"sync_run_id"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+	LastStatusCode int        `json:"last_status_code,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+}
+
+// AdminWebhooks serves /admin/webhooks: GET lists the webhooks, POST
+// registers one. Without a secret one is generated. The secret is only
+// returned on registration.
+func (h *handler) AdminWebhooks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		errorRespond(w, http.StatusMethodNotAllowed, errInvalidMethod.Error())
+		return
+	}
+	if !h.authorized(r) {
+		errorRespond(w, http.StatusUnauthorized, errUnauthorized.Error())
+		return
+	}
+	if r.Method == http.MethodGet {
+		webhooks, err := h.webhookRepo.GetAll()
+		if err != nil {
+			errorRespond(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		res := make([]*Webhook, 0, len(webhooks))
+		for _, wh := range webhooks {
+			res = append(res, webhookTransform(wh, false))
+		}
+		jsonRespond(w, http.StatusOK, res)
+		return
+	}
+	var req WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		errorRespond(w, http.StatusBadRequest, errInvalidRequest.Error())
+		return
+	}
+	u, err := url.Parse(strings.TrimSpace(req.URL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || len(u.String()) > 2048 {
+		errorRespond(w, http.StatusBadRequest, errInvalidRequest.Error())
+		return
+	}
+	wh := model.Webhook{URL: u.String(), Secret: req.Secret, CreatedAt: time.Now().UTC()}
+	for _, c := range req.Currencies {
+		c = strings.ToUpper(strings.TrimSpace(c))
+		if len(c) != 3 {
+			errorRespond(w, http.StatusBadRequest, errInvalidRequest.Error())
+			return
+		}
+		wh.Currencies = append(wh.Currencies, c)
+	}
+	if wh.Secret == "" {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			errorRespond(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		wh.Secret = hex.EncodeToString(b)
+	}
+	if len(wh.Secret) > 255 {
+		errorRespond(w, http.StatusBadRequest, errInvalidRequest.Error())
+		return
+	}
+	if wh.ID, err = h.webhookRepo.Insert(wh); err != nil {
+		errorRespond(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	jsonRespond(w, http.StatusCreated, webhookTransform(wh, true))
+}
+
+// AdminWebhook serves /admin/webhooks/{id} (GET, DELETE) and the delivery
+// log at /admin/webhooks/{id}/deliveries?limit=.
+func (h *handler) AdminWebhook(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(r) {
+		errorRespond(w, http.StatusUnauthorized, errUnauthorized.Error())
+		return
+	}
+	parts := strings.Split(strings.Trim(r.URL.Path[len("/admin/webhooks/"):], "/"), "/")
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || len(parts) > 2 || (len(parts) == 2 && parts[1] != "deliveries") {
+		errorRespond(w, http.StatusNotFound, errNotFound.Error())
+		return
+	}
+	if len(parts) == 2 {
+		h.webhookDeliveries(w, r, id)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		wh, err := h.webhookRepo.GetByID(id)
+		if err == repository.ErrNotFound {
+			errorRespond(w, http.StatusNotFound, errNotFound.Error())
+			return
+		}
+		if err != nil {
+			errorRespond(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		jsonRespond(w, http.StatusOK, webhookTransform(wh, false))
+	case http.MethodDelete:
+		err := h.webhookRepo.Delete(id)
+		if err == repository.ErrNotFound {
+			errorRespond(w, http.StatusNotFound, errNotFound.Error())
+			return
+		}
+		if err != nil {
+			errorRespond(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		errorRespond(w, http.StatusMethodNotAllowed, errInvalidMethod.Error())
+	}
+}
+
+func (h *handler) webhookDeliveries(w http.ResponseWriter, r *http.Request, id int64) {
+	if r.Method != http.MethodGet {
+		errorRespond(w, http.StatusMethodNotAllowed, errInvalidMethod.Error())
+		return
+	}
+	limit := defaultDeliveryLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxDeliveryLimit {
+			errorRespond(w, http.StatusBadRequest, errInvalidRequest.Error())
+			return
+		}
+		limit = n
+	}
+	deliveries, err := h.webhookRepo.GetDeliveries(id, limit)
+	if err != nil {
+		errorRespond(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	res := make([]*WebhookDelivery, 0, len(deliveries))
+	for _, d := range deliveries {
+		res = append(res, deliveryTransform(d))
+	}
+	jsonRespond(w, http.StatusOK, res)
+}
+
+func webhookTransform(wh model.Webhook, withSecret bool) *Webhook {
+	res := &Webhook{
+		ID:         wh.ID,
+		URL:        wh.URL,
+		Currencies: wh.Currencies,
+		CreatedAt:  wh.CreatedAt,
+	}
+	if res.Currencies == nil {
+		res.Currencies = []string{}
+	}
+	if withSecret {
+		res.Secret = wh.Secret
+	}
+	return res
+}
+
+func deliveryTransform(d model.WebhookDelivery) *WebhookDelivery {
+	res := &WebhookDelivery{
+		ID:             d.ID,
+		Event:          d.Event,
+		SyncRunID:      d.SyncRunID,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		LastStatusCode: d.LastStatusCode,
+		LastError:      d.LastError,
+		CreatedAt:      d.CreatedAt,
+	}
+	if d.Status == model.DeliveryPending {
+		res.NextAttemptAt = &d.NextAttemptAt
+	}
+	if !d.DeliveredAt.IsZero() {
+		res.DeliveredAt = &d.DeliveredAt
+	}
+	return res
+}
@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"errors"
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"github.com/huyhvq/eurofxref/pkg/repository"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeWebhooks keeps webhooks in memory, numbered from 1.
+type fakeWebhooks struct {
+	repository.WebhookRepository
+	webhooks   []model.Webhook
+	deliveries []model.WebhookDelivery
+	err        error
+}
+
+func (f *fakeWebhooks) Insert(wh model.Webhook) (int64, error) {
+	wh.ID = int64(len(f.webhooks) + 1)
+	f.webhooks = append(f.webhooks, wh)
+	return wh.ID, f.err
+}
+
+func (f *fakeWebhooks) GetAll() ([]model.Webhook, error) {
+	return f.webhooks, f.err
+}
+
+func (f *fakeWebhooks) GetByID(id int64) (model.Webhook, error) {
+	for _, wh := range f.webhooks {
+		if wh.ID == id {
+			return wh, f.err
+		}
+	}
+	return model.Webhook{}, repository.ErrNotFound
+}
+
+func (f *fakeWebhooks) Delete(id int64) error {
+	for i, wh := range f.webhooks {
+		if wh.ID == id {
+			f.webhooks = append(f.webhooks[:i], f.webhooks[i+1:]...)
+			return nil
+		}
+	}
+	return repository.ErrNotFound
+}
+
+func (f *fakeWebhooks) GetDeliveries(webhookID int64, limit int) ([]model.WebhookDelivery, error) {
+	var res []model.WebhookDelivery
+	for _, d := range f.deliveries {
+		if d.WebhookID == webhookID && len(res) < limit {
+			res = append(res, d)
+		}
+	}
+	return res, f.err
+}
+
+func TestHandler_AdminWebhooks(t *testing.T) {
+	now := time.Date(2021, 3, 30, 16, 0, 0, 0, time.UTC)
+	webhooks := &fakeWebhooks{deliveries: []model.WebhookDelivery{
+		{ID: 2, WebhookID: 1, Event: "rates.updated", SyncRunID: 9, Status: model.DeliveryPending,
+			Attempts: 1, NextAttemptAt: now, LastStatusCode: 500, CreatedAt: now},
+		{ID: 1, WebhookID: 1, Event: "rates.updated", SyncRunID: 8, Status: model.DeliveryDelivered,
+			Attempts: 1, NextAttemptAt: now, CreatedAt: now, DeliveredAt: now},
+	}}
+	h := newTestHandler(Config{WebhookRepo: webhooks})
+
+	body := `{"url": "https://example.com/hook", "currencies": ["usd"]}`
+	w := do(h.AdminWebhooks, http.MethodPost, "/admin/webhooks", strings.NewReader(body), true)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var wh Webhook
+	decode(t, w, &wh)
+	assert.Equal(t, int64(1), wh.ID)
+	assert.Equal(t, []string{"USD"}, wh.Currencies)
+	assert.Len(t, wh.Secret, 64)
+
+	w = do(h.AdminWebhooks, http.MethodGet, "/admin/webhooks", nil, true)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var list []Webhook
+	decode(t, w, &list)
+	assert.Len(t, list, 1)
+	assert.Empty(t, list[0].Secret)
+
+	w = do(h.AdminWebhook, http.MethodGet, "/admin/webhooks/1", nil, true)
+	assert.Equal(t, http.StatusOK, w.Code)
+	wh = Webhook{}
+	decode(t, w, &wh)
+	assert.Equal(t, "https://example.com/hook", wh.URL)
+	assert.Empty(t, wh.Secret)
+
+	w = do(h.AdminWebhook, http.MethodGet, "/admin/webhooks/1/deliveries?limit=1", nil, true)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var deliveries []WebhookDelivery
+	decode(t, w, &deliveries)
+	assert.Len(t, deliveries, 1)
+	assert.Equal(t, int64(2), deliveries[0].ID)
+	assert.Equal(t, &now, deliveries[0].NextAttemptAt)
+	assert.Nil(t, deliveries[0].DeliveredAt)
+
+	w = do(h.AdminWebhook, http.MethodDelete, "/admin/webhooks/1", nil, true)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	for _, tc := range []struct {
+		name   string
+		fn     http.HandlerFunc
+		method string
+		target string
+		body   string
+		admin  bool
+		code   int
+	}{
+		{"unauthorized", h.AdminWebhooks, http.MethodGet, "/admin/webhooks", "", false, http.StatusUnauthorized},
+		{"unauthorized webhook", h.AdminWebhook, http.MethodGet, "/admin/webhooks/1", "", false, http.StatusUnauthorized},
+		{"malformed", h.AdminWebhooks, http.MethodPost, "/admin/webhooks", `{"url": `, true, http.StatusBadRequest},
+		{"invalid url", h.AdminWebhooks, http.MethodPost, "/admin/webhooks", `{"url": "ftp://example.com"}`, true, http.StatusBadRequest},
+		{"invalid currency", h.AdminWebhooks, http.MethodPost, "/admin/webhooks",
+			`{"url": "https://example.com", "currencies": ["EURO"]}`, true, http.StatusBadRequest},
+		{"method", h.AdminWebhooks, http.MethodPut, "/admin/webhooks", "", true, http.StatusMethodNotAllowed},
+		{"deleted", h.AdminWebhook, http.MethodGet, "/admin/webhooks/1", "", true, http.StatusNotFound},
+		{"delete twice", h.AdminWebhook, http.MethodDelete, "/admin/webhooks/1", "", true, http.StatusNotFound},
+		{"invalid id", h.AdminWebhook, http.MethodGet, "/admin/webhooks/x", "", true, http.StatusNotFound},
+		{"unknown sub-resource", h.AdminWebhook, http.MethodGet, "/admin/webhooks/1/secret", "", true, http.StatusNotFound},
+		{"invalid limit", h.AdminWebhook, http.MethodGet, "/admin/webhooks/1/deliveries?limit=0", "", true, http.StatusBadRequest},
+		{"deliveries method", h.AdminWebhook, http.MethodPost, "/admin/webhooks/1/deliveries", "", true, http.StatusMethodNotAllowed},
+		{"webhook method", h.AdminWebhook, http.MethodPut, "/admin/webhooks/1", "", true, http.StatusMethodNotAllowed},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := do(tc.fn, tc.method, tc.target, strings.NewReader(tc.body), tc.admin)
+			assert.Equal(t, tc.code, w.Code)
+		})
+	}
+
+	h = newTestHandler(Config{WebhookRepo: &fakeWebhooks{err: errors.New("db down")}})
+	w = do(h.AdminWebhooks, http.MethodGet, "/admin/webhooks", nil, true)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "db down", errorOf(t, w))
+}
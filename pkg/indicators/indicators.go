@@ -0,0 +1,145 @@
+// Package indicators computes technical indicators over a series of rates.
+//
+// Every function returns a slice as long as its input. Positions without
+// enough history for the window hold NaN.
+package indicators
+
+import "math"
+
+// TradingDays annualises daily volatility.
+const TradingDays = 252
+
+// SMA is the simple moving average over n values.
+func SMA(values []float64, n int) []float64 {
+	out := nans(len(values))
+	if n <= 0 {
+		return out
+	}
+	var sum float64
+	for i, v := range values {
+		sum += v
+		if i >= n {
+			sum -= values[i-n]
+		}
+		if i >= n-1 {
+			out[i] = sum / float64(n)
+		}
+	}
+	return out
+}
+
+// EMA is the exponential moving average with smoothing 2/(n+1), seeded with
+// the SMA of the first n values.
+func EMA(values []float64, n int) []float64 {
+	out := nans(len(values))
+	if n <= 0 || len(values) < n {
+		return out
+	}
+	alpha := 2 / float64(n+1)
+	var sum float64
+	for _, v := range values[:n] {
+		sum += v
+	}
+	out[n-1] = sum / float64(n)
+	for i := n; i < len(values); i++ {
+		out[i] = alpha*values[i] + (1-alpha)*out[i-1]
+	}
+	return out
+}
+
+// Bollinger returns the middle (SMA), upper and lower bands k population
+// standard deviations away from the middle.
+func Bollinger(values []float64, n int, k float64) ([]float64, []float64, []float64) {
+	middle := SMA(values, n)
+	upper, lower := nans(len(values)), nans(len(values))
+	for i := n - 1; n > 0 && i < len(values); i++ {
+		var ss float64
+		for _, v := range values[i-n+1 : i+1] {
+			ss += (v - middle[i]) * (v - middle[i])
+		}
+		sd := math.Sqrt(ss / float64(n))
+		upper[i] = middle[i] + k*sd
+		lower[i] = middle[i] - k*sd
+	}
+	return middle, upper, lower
+}
+
+// RSI is Wilder's relative strength index over n changes.
+func RSI(values []float64, n int) []float64 {
+	out := nans(len(values))
+	if n <= 0 || len(values) <= n {
+		return out
+	}
+	var gain, loss float64
+	for i := 1; i <= n; i++ {
+		d := values[i] - values[i-1]
+		if d > 0 {
+			gain += d
+		} else {
+			loss -= d
+		}
+	}
+	gain, loss = gain/float64(n), loss/float64(n)
+	out[n] = rsi(gain, loss)
+	for i := n + 1; i < len(values); i++ {
+		d := values[i] - values[i-1]
+		g, l := math.Max(d, 0), math.Max(-d, 0)
+		gain = (gain*float64(n-1) + g) / float64(n)
+		loss = (loss*float64(n-1) + l) / float64(n)
+		out[i] = rsi(gain, loss)
+	}
+	return out
+}
+
+// rsi is a neutral 50 when prices did not move over the window.
+func rsi(gain, loss float64) float64 {
+	if gain == 0 && loss == 0 {
+		return 50
+	}
+	if loss == 0 {
+		return 100
+	}
+	return 100 - 100/(1+gain/loss)
+}
+
+// LogReturns returns the daily log returns of values; the first position is
+// NaN.
+func LogReturns(values []float64) []float64 {
+	out := nans(len(values))
+	for i := 1; i < len(values); i++ {
+		out[i] = math.Log(values[i] / values[i-1])
+	}
+	return out
+}
+
+// Volatility is the annualised sample standard deviation of the last n daily
+// log returns.
+func Volatility(values []float64, n int) []float64 {
+	out := nans(len(values))
+	if n <= 1 {
+		return out
+	}
+	returns := LogReturns(values)
+	for i := n; i < len(values); i++ {
+		window := returns[i-n+1 : i+1]
+		var mean float64
+		for _, r := range window {
+			mean += r
+		}
+		mean /= float64(n)
+		var ss float64
+		for _, r := range window {
+			ss += (r - mean) * (r - mean)
+		}
+		out[i] = math.Sqrt(ss/float64(n-1)) * math.Sqrt(TradingDays)
+	}
+	return out
+}
+
+func nans(n int) []float64 {
+	out := make([]float64, n)
+	for i := range out {
+		out[i] = math.NaN()
+	}
+	return out
+}
@@ -0,0 +1,66 @@
+package indicators
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+var values = []float64{1, 2, 3, 4, 5, 4, 3}
+
+func TestSMA(t *testing.T) {
+	out := SMA(values, 3)
+	assert.True(t, math.IsNaN(out[0]))
+	assert.True(t, math.IsNaN(out[1]))
+	assert.Equal(t, []float64{2, 3, 4, 13.0 / 3, 4}, out[2:])
+	assert.True(t, math.IsNaN(SMA(values, 0)[6]))
+}
+
+func TestEMA(t *testing.T) {
+	out := EMA(values, 3)
+	assert.True(t, math.IsNaN(out[1]))
+	assert.Equal(t, float64(2), out[2])
+	assert.Equal(t, float64(3), out[3])
+	assert.Equal(t, float64(4), out[4])
+	assert.Equal(t, float64(4), out[5])
+	assert.Equal(t, 3.5, out[6])
+	assert.True(t, math.IsNaN(EMA(values, 8)[6]))
+}
+
+func TestBollinger(t *testing.T) {
+	middle, upper, lower := Bollinger(values, 3, 2)
+	assert.True(t, math.IsNaN(upper[1]))
+	sd := math.Sqrt(2.0 / 3)
+	assert.Equal(t, float64(2), middle[2])
+	assert.InDelta(t, 2+2*sd, upper[2], 1e-12)
+	assert.InDelta(t, 2-2*sd, lower[2], 1e-12)
+}
+
+func TestRSI(t *testing.T) {
+	out := RSI(values, 3)
+	assert.True(t, math.IsNaN(out[2]))
+	assert.Equal(t, float64(100), out[3])
+	assert.Equal(t, float64(100), out[4])
+	// gain 2/3, loss 1/3 after the first drop
+	assert.InDelta(t, 100-100/(1+2.0), out[5], 1e-9)
+	assert.True(t, math.IsNaN(RSI(values, 7)[6]))
+
+	flat := RSI([]float64{2, 2, 2, 2}, 2)
+	assert.Equal(t, []float64{50, 50}, flat[2:])
+}
+
+func TestVolatility(t *testing.T) {
+	flat := Volatility([]float64{2, 2, 2, 2}, 2)
+	assert.True(t, math.IsNaN(flat[1]))
+	assert.Equal(t, float64(0), flat[2])
+	assert.Equal(t, float64(0), flat[3])
+
+	out := Volatility([]float64{1, math.E, 1}, 2)
+	assert.InDelta(t, math.Sqrt(2)*math.Sqrt(TradingDays), out[2], 1e-9)
+}
+
+func TestLogReturns(t *testing.T) {
+	out := LogReturns([]float64{1, math.E})
+	assert.True(t, math.IsNaN(out[0]))
+	assert.InDelta(t, 1, out[1], 1e-12)
+}
@@ -0,0 +1,40 @@
+package model
+
+import "time"
+
+const (
+	AlertAbove        = "above"
+	AlertBelow        = "below"
+	AlertCrossesAbove = "crosses_above"
+	AlertCrossesBelow = "crosses_below"
+	AlertChangePct    = "change_pct"
+)
+
+// AlertRule watches the Base/Quote rate after every ingest. It fires at most
+// once per Cooldown and is delivered to the named Sinks, all when empty.
+type AlertRule struct {
+	ID              int64
+	Name            string
+	Base            string
+	Quote           string
+	Condition       string
+	Threshold       float64
+	Cooldown        time.Duration
+	Sinks           []string
+	CreatedAt       time.Time
+	LastTriggeredAt time.Time
+}
+
+// Alert records a rule firing on the rates of Date. Previous is the rate of
+// the publication before Date, zero when there was none. Error lists the
+// sinks that failed to deliver it.
+type Alert struct {
+	ID          int64
+	RuleID      int64
+	Date        string
+	Value       float64
+	Previous    float64
+	Message     string
+	Error       string
+	TriggeredAt time.Time
+}
@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+const (
+	BasketUnits   = "units"
+	BasketWeights = "weights"
+)
+
+// Basket is a named composite of currencies. A units basket holds fixed
+// amounts of each currency, SDR-style. A weights basket splits BaseValue EUR
+// by weight on BaseDate and holds the resulting amounts from then on.
+type Basket struct {
+	ID         int64
+	Name       string
+	Kind       string
+	BaseDate   string
+	BaseValue  float64
+	Components []BasketComponent
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+type BasketComponent struct {
+	Currency string
+	Weight   float64
+	Units    float64
+}
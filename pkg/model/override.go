@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// RateOverride replaces the rate of Currency on every date between Start and
+// End inclusive. Revoked overrides are kept for the audit trail.
+type RateOverride struct {
+	ID        int64
+	Currency  string
+	Start     string
+	End       string
+	Rate      float64
+	Reason    string
+	CreatedBy string
+	CreatedAt time.Time
+	RevokedBy string
+	RevokedAt time.Time
+}
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// Quote locks the rate of converting Amount of From into To until ExpiresAt.
+// Side is the mid, bid or ask rate of Profile. A quote is redeemed at most
+// once.
+type Quote struct {
+	ID         string
+	From       string
+	To         string
+	Amount     float64
+	Rate       float64
+	Result     float64
+	RateDate   string
+	Profile    string
+	Side       string
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+	RedeemedAt time.Time
+}
@@ -1,9 +1,14 @@
 package model
 
+import "time"
+
+// Rate is the EUR reference rate of a currency on a date. OverrideID is set
+// when a manual override replaced the provider rate.
 type Rate struct {
-	Time     string
-	Currency string
-	Rate     float64
+	Time       string
+	Currency   string
+	Rate       float64
+	OverrideID int64
 }
 
 type RateAnalyze struct {
@@ -11,4 +16,61 @@ type RateAnalyze struct {
 	Min      float64
 	Max      float64
 	Avg      float64
+	StdDev   float64
+}
+
+// RateChange is a stored revision as seen by change feed consumers. Seq is its
+// change sequence number. Previous is the value it replaced; Revised is false
+// for the first value of a currency and date. Seeded revisions were copied
+// from rates stored before revisions were kept, their FetchedAt is the rate
+// date rather than a fetch time.
+type RateChange struct {
+	Seq       int64
+	Time      string
+	Currency  string
+	Rate      float64
+	Previous  float64
+	Revised   bool
+	Source    string
+	Seeded    bool
+	FetchedAt time.Time
+}
+
+// RateRevision is one observed value of a rate. A revision stays current until
+// a later fetch observes a different value and sets SupersededAt.
+type RateRevision struct {
+	ID           int64
+	Time         string
+	Currency     string
+	Rate         float64
+	Source       string
+	FeedHash     string
+	FetchedAt    time.Time
+	SupersededAt time.Time
+}
+
+// DateCount is the number of currencies stored for a date.
+type DateCount struct {
+	Time  string
+	Count int
+}
+
+// RatePeriod summarises the rates of a currency over a period starting on
+// Start.
+type RatePeriod struct {
+	Start    string
+	Currency string
+	Avg      float64
+	Open     float64
+	Close    float64
+	High     float64
+	Low      float64
+	Count    int
+}
+
+// CurrencyRange is the first and last date a currency has rates for.
+type CurrencyRange struct {
+	Currency string
+	First    string
+	Last     string
 }
@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+const (
+	SyncRunning = "running"
+	SyncSuccess = "success"
+	SyncFailed  = "failed"
+)
+
+type SyncRun struct {
+	ID           int64
+	Provider     string
+	Endpoint     string
+	TriggeredBy  string
+	Status       string
+	HTTPStatus   int
+	FeedHash     string
+	DatesFetched int
+	FirstDate    string
+	LastDate     string
+	RowsInserted int
+	RowsRevised  int
+	Error        string
+	StartedAt    time.Time
+	FinishedAt   time.Time
+}
@@ -0,0 +1,36 @@
+package model
+
+import "time"
+
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// Webhook receives the rates of every sync that changed any of Currencies,
+// or of any currency when Currencies is empty.
+type Webhook struct {
+	ID         int64
+	URL        string
+	Secret     string
+	Currencies []string
+	CreatedAt  time.Time
+}
+
+// WebhookDelivery is one payload queued for a webhook. Pending deliveries
+// are retried until they succeed or run out of attempts.
+type WebhookDelivery struct {
+	ID             int64
+	WebhookID      int64
+	Event          string
+	SyncRunID      int64
+	Payload        string
+	Status         string
+	Attempts       int
+	NextAttemptAt  time.Time
+	LastStatusCode int
+	LastError      string
+	CreatedAt      time.Time
+	DeliveredAt    time.Time
+}
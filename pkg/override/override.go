@@ -0,0 +1,105 @@
+// Package override applies manual rate overrides on top of provider rates.
+package override
+
+import (
+	"errors"
+	"github.com/huyhvq/eurofxref/pkg/fx"
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrInvalidOverride = errors.New("invalid override")
+
+// Normalize upper-cases the currency of o and checks that it covers a valid
+// range with a positive rate and records who made it and why.
+func Normalize(o *model.RateOverride) error {
+	o.Currency = strings.ToUpper(strings.TrimSpace(o.Currency))
+	o.Reason = strings.TrimSpace(o.Reason)
+	o.CreatedBy = strings.TrimSpace(o.CreatedBy)
+	if len(o.Currency) != 3 || o.Currency == fx.Base || o.Rate <= 0 || o.Reason == "" || o.CreatedBy == "" {
+		return ErrInvalidOverride
+	}
+	if len(o.Reason) > 255 || len(o.CreatedBy) > 64 {
+		return ErrInvalidOverride
+	}
+	start, err := time.Parse("2006-01-02", o.Start)
+	if err != nil {
+		return ErrInvalidOverride
+	}
+	end, err := time.Parse("2006-01-02", o.End)
+	if err != nil || end.Before(start) {
+		return ErrInvalidOverride
+	}
+	return nil
+}
+
+// Apply returns rates with every rate covered by an override replaced. A
+// currency without a provider rate on a date that has other rates is added.
+// When overrides overlap the most recently created one wins. rates keep
+// their order; added rates follow the rates of their date.
+func Apply(rates []model.Rate, overrides []model.RateOverride) []model.Rate {
+	if len(overrides) == 0 {
+		return rates
+	}
+	newest := append([]model.RateOverride{}, overrides...)
+	sort.SliceStable(newest, func(i, j int) bool { return newest[i].CreatedAt.After(newest[j].CreatedAt) })
+	find := func(date, currency string) (model.RateOverride, bool) {
+		for _, o := range newest {
+			if o.Currency == currency && covers(o, date) {
+				return o, true
+			}
+		}
+		return model.RateOverride{}, false
+	}
+
+	out := make([]model.Rate, 0, len(rates))
+	for i := 0; i < len(rates); {
+		j := i
+		seen := make(map[string]struct{})
+		for ; j < len(rates) && rates[j].Time == rates[i].Time; j++ {
+			r := rates[j]
+			if o, ok := find(r.Time, r.Currency); ok {
+				r.Rate, r.OverrideID = o.Rate, o.ID
+			}
+			seen[r.Currency] = struct{}{}
+			out = append(out, r)
+		}
+		date := rates[i].Time
+		for _, o := range newest {
+			if _, ok := seen[o.Currency]; ok || !covers(o, date) {
+				continue
+			}
+			seen[o.Currency] = struct{}{}
+			out = append(out, model.Rate{Time: date, Currency: o.Currency, Rate: o.Rate, OverrideID: o.ID})
+		}
+		i = j
+	}
+	return out
+}
+
+// covers reports whether o applies on date, of which only the day part is
+// compared.
+func covers(o model.RateOverride, date string) bool {
+	if len(date) > 10 {
+		date = date[:10]
+	}
+	return o.Start <= date && date <= o.End
+}
+
+// Overridden returns the currencies of rates that carry an override, in
+// alphabetical order, or nil when there are none.
+func Overridden(rates []model.Rate) []string {
+	var cs []string
+	seen := make(map[string]struct{})
+	for _, r := range rates {
+		if _, ok := seen[r.Currency]; ok || r.OverrideID == 0 {
+			continue
+		}
+		seen[r.Currency] = struct{}{}
+		cs = append(cs, r.Currency)
+	}
+	sort.Strings(cs)
+	return cs
+}
@@ -0,0 +1,71 @@
+package override
+
+import (
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestNormalize(t *testing.T) {
+	o := model.RateOverride{Currency: " usd", Start: "2021-03-01", End: "2021-03-31", Rate: 1.2,
+		Reason: " contract ", CreatedBy: "alice"}
+	assert.Nil(t, Normalize(&o))
+	assert.Equal(t, "USD", o.Currency)
+	assert.Equal(t, "contract", o.Reason)
+
+	for _, invalid := range []model.RateOverride{
+		{Currency: "EUR", Start: "2021-03-01", End: "2021-03-31", Rate: 1, Reason: "r", CreatedBy: "a"},
+		{Currency: "USD", Start: "2021-03-01", End: "2021-03-31", Rate: 0, Reason: "r", CreatedBy: "a"},
+		{Currency: "USD", Start: "2021-03-01", End: "2021-03-31", Rate: 1, CreatedBy: "a"},
+		{Currency: "USD", Start: "2021-03-01", End: "2021-03-31", Rate: 1, Reason: "r"},
+		{Currency: "USD", Start: "2021-03-31", End: "2021-03-01", Rate: 1, Reason: "r", CreatedBy: "a"},
+		{Currency: "USD", Start: "2021-3-1", End: "2021-03-31", Rate: 1, Reason: "r", CreatedBy: "a"},
+	} {
+		assert.Equal(t, ErrInvalidOverride, Normalize(&invalid))
+	}
+}
+
+func TestApply(t *testing.T) {
+	rates := []model.Rate{
+		{Time: "2021-03-24", Currency: "GBP", Rate: 0.86},
+		{Time: "2021-03-24", Currency: "USD", Rate: 1.18},
+		{Time: "2021-03-25", Currency: "GBP", Rate: 0.87},
+		{Time: "2021-03-25", Currency: "USD", Rate: 1.19},
+	}
+	assert.Equal(t, rates, Apply(rates, nil))
+
+	now := time.Now()
+	overrides := []model.RateOverride{
+		{ID: 1, Currency: "USD", Start: "2021-03-01", End: "2021-03-31", Rate: 1.2, CreatedAt: now.Add(-time.Hour)},
+		{ID: 2, Currency: "USD", Start: "2021-03-25", End: "2021-03-25", Rate: 1.25, CreatedAt: now},
+		{ID: 3, Currency: "RUB", Start: "2021-03-25", End: "2021-03-26", Rate: 90, CreatedAt: now},
+	}
+	assert.Equal(t, []model.Rate{
+		{Time: "2021-03-24", Currency: "GBP", Rate: 0.86},
+		{Time: "2021-03-24", Currency: "USD", Rate: 1.2, OverrideID: 1},
+		{Time: "2021-03-25", Currency: "GBP", Rate: 0.87},
+		{Time: "2021-03-25", Currency: "USD", Rate: 1.25, OverrideID: 2},
+		{Time: "2021-03-25", Currency: "RUB", Rate: 90, OverrideID: 3},
+	}, Apply(rates, overrides))
+	assert.Equal(t, 1.18, rates[1].Rate)
+
+	timestamps := []model.Rate{
+		{Time: "2021-03-25T00:00:00Z", Currency: "GBP", Rate: 0.87},
+		{Time: "2021-03-25T00:00:00Z", Currency: "USD", Rate: 1.19},
+	}
+	assert.Equal(t, []model.Rate{
+		{Time: "2021-03-25T00:00:00Z", Currency: "GBP", Rate: 0.87},
+		{Time: "2021-03-25T00:00:00Z", Currency: "USD", Rate: 1.25, OverrideID: 2},
+		{Time: "2021-03-25T00:00:00Z", Currency: "RUB", Rate: 90, OverrideID: 3},
+	}, Apply(timestamps, overrides))
+}
+
+func TestOverridden(t *testing.T) {
+	assert.Nil(t, Overridden([]model.Rate{{Currency: "USD"}}))
+	assert.Equal(t, []string{"RUB", "USD"}, Overridden([]model.Rate{
+		{Currency: "USD", OverrideID: 1},
+		{Currency: "RUB", OverrideID: 3},
+		{Currency: "USD", OverrideID: 2},
+	}))
+}
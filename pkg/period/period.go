@@ -0,0 +1,72 @@
+// Package period buckets dates into weeks, months, quarters and years.
+package period
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+type Granularity string
+
+const (
+	Week    Granularity = "week"
+	Month   Granularity = "month"
+	Quarter Granularity = "quarter"
+	Year    Granularity = "year"
+)
+
+var ErrInvalidGranularity = errors.New("invalid granularity")
+
+func Parse(s string) (Granularity, error) {
+	switch g := Granularity(s); g {
+	case Week, Month, Quarter, Year:
+		return g, nil
+	}
+	return "", ErrInvalidGranularity
+}
+
+// Start returns the first day of the period containing t. Weeks start on
+// Monday.
+func (g Granularity) Start(t time.Time) time.Time {
+	y, m, d := t.Date()
+	switch g {
+	case Week:
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(y, m, d-offset, 0, 0, 0, 0, time.UTC)
+	case Quarter:
+		return time.Date(y, m-(m-1)%3, 1, 0, 0, 0, 0, time.UTC)
+	case Year:
+		return time.Date(y, time.January, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// End returns the last day of the period containing t.
+func (g Granularity) End(t time.Time) time.Time {
+	s := g.Start(t)
+	switch g {
+	case Week:
+		return s.AddDate(0, 0, 6)
+	case Quarter:
+		return s.AddDate(0, 3, -1)
+	case Year:
+		return s.AddDate(1, 0, -1)
+	}
+	return s.AddDate(0, 1, -1)
+}
+
+// Label names the period containing t, e.g. 2021-W12, 2021-03, 2021-Q1 or
+// 2021.
+func (g Granularity) Label(t time.Time) string {
+	switch g {
+	case Week:
+		y, w := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", y, w)
+	case Quarter:
+		return fmt.Sprintf("%d-Q%d", t.Year(), (int(t.Month())-1)/3+1)
+	case Year:
+		return fmt.Sprintf("%d", t.Year())
+	}
+	return t.Format("2006-01")
+}
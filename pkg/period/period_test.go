@@ -0,0 +1,42 @@
+package period
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func d(s string) time.Time {
+	t, _ := time.ParseInLocation("2006-01-02", s, time.UTC)
+	return t
+}
+
+func TestParse(t *testing.T) {
+	g, err := Parse("quarter")
+	assert.Nil(t, err)
+	assert.Equal(t, Quarter, g)
+	_, err = Parse("day")
+	assert.Equal(t, ErrInvalidGranularity, err)
+}
+
+func TestGranularity(t *testing.T) {
+	cases := []struct {
+		g          Granularity
+		date       string
+		start, end string
+		label      string
+	}{
+		{Week, "2021-03-25", "2021-03-22", "2021-03-28", "2021-W12"},
+		{Week, "2021-03-28", "2021-03-22", "2021-03-28", "2021-W12"},
+		{Week, "2021-01-01", "2020-12-28", "2021-01-03", "2020-W53"},
+		{Month, "2021-02-14", "2021-02-01", "2021-02-28", "2021-02"},
+		{Quarter, "2021-05-31", "2021-04-01", "2021-06-30", "2021-Q2"},
+		{Quarter, "2021-12-31", "2021-10-01", "2021-12-31", "2021-Q4"},
+		{Year, "2020-07-04", "2020-01-01", "2020-12-31", "2020"},
+	}
+	for _, c := range cases {
+		assert.Equal(t, d(c.start), c.g.Start(d(c.date)), "%s start of %s", c.g, c.date)
+		assert.Equal(t, d(c.end), c.g.End(d(c.date)), "%s end of %s", c.g, c.date)
+		assert.Equal(t, c.label, c.g.Label(d(c.date)))
+	}
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"database/sql"
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"github.com/huyhvq/eurofxref/pkg/period"
+	"sort"
+	"time"
+)
+
+// aggregateColumns lists the columns of a rate_aggregates row, filled by
+// aggregateSelect from the rates table.
+const aggregateColumns = "`currency`, `month`, `count`, `sum`, `sum_sq`, `min`, `max`, " +
+	"`first_date`, `first_rate`, `last_date`, `last_rate`"
+
+const aggregateSelect = "SELECT `currency`, DATE_SUB(`created_at`, INTERVAL DAYOFMONTH(`created_at`) - 1 DAY) AS `m`, " +
+	"COUNT(*), SUM(`rate`), SUM(`rate` * `rate`), MIN(`rate`), MAX(`rate`), " +
+	"MIN(`created_at`), SUBSTRING_INDEX(GROUP_CONCAT(`rate` ORDER BY `created_at` ASC), ',', 1), " +
+	"MAX(`created_at`), SUBSTRING_INDEX(GROUP_CONCAT(`rate` ORDER BY `created_at` DESC), ',', 1) FROM `rates`"
+
+type aggregateKey struct {
+	currency string
+	month    time.Time
+}
+
+// refreshAggregates recomputes the monthly aggregates touched by rates inside
+// tx. A month holds at most 23 publications, so recomputing it is as cheap as
+// patching it and stays correct when a rate is revised.
+func refreshAggregates(tx *sql.Tx, rates []model.Rate) error {
+	seen := make(map[aggregateKey]struct{})
+	keys := make([]aggregateKey, 0)
+	for _, rate := range rates {
+		if len(rate.Time) < 10 {
+			continue
+		}
+		t, err := time.ParseInLocation("2006-01-02", rate.Time[:10], time.UTC)
+		if err != nil {
+			return err
+		}
+		k := aggregateKey{currency: rate.Currency, month: period.Month.Start(t)}
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = struct{}{}
+		keys = append(keys, k)
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].currency != keys[j].currency {
+			return keys[i].currency < keys[j].currency
+		}
+		return keys[i].month.Before(keys[j].month)
+	})
+
+	del, err := tx.Prepare("DELETE FROM rate_aggregates WHERE currency = ? AND month = ?")
+	if err != nil {
+		return err
+	}
+	ins, err := tx.Prepare("INSERT INTO rate_aggregates(" + aggregateColumns + ") " + aggregateSelect +
+		" WHERE `currency` = ? AND `created_at` BETWEEN ? AND ? GROUP BY `currency`, `m`")
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		m := k.month.Format("2006-01-02")
+		if _, err := del.Exec(k.currency, m); err != nil {
+			return err
+		}
+		if _, err := ins.Exec(k.currency, m, period.Month.End(k.month).Format("2006-01-02")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RebuildAggregates recomputes every monthly aggregate from the rates table.
+func (r *rateRepo) RebuildAggregates() error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM rate_aggregates"); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err := tx.Exec("INSERT INTO rate_aggregates(" + aggregateColumns + ") " + aggregateSelect +
+		" GROUP BY `currency`, `m`"); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// FillAggregates builds the monthly aggregates from the rates table when
+// there are none yet, as on a database that held rates before the aggregates
+// table was created.
+func (r *rateRepo) FillAggregates() error {
+	_, err := r.db.Exec("INSERT INTO rate_aggregates(" + aggregateColumns + ") " + aggregateSelect +
+		" WHERE NOT EXISTS (SELECT 1 FROM `rate_aggregates`) GROUP BY `currency`, `m`")
+	return err
+}
+
+// wholeMonths returns the first days of the first and last whole months
+// within [start, end]. ok is false when the range holds no whole month.
+func wholeMonths(start, end time.Time) (first, last time.Time, ok bool) {
+	first, last = period.Month.Start(start), period.Month.Start(end)
+	if !first.Equal(start) {
+		first = first.AddDate(0, 1, 0)
+	}
+	if !period.Month.End(end).Equal(end) {
+		last = last.AddDate(0, -1, 0)
+	}
+	return first, last, !first.After(last)
+}
@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"database/sql"
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"strings"
+	"time"
+)
+
+type AlertRepository interface {
+	InsertRule(r model.AlertRule) (int64, error)
+	GetRules() ([]model.AlertRule, error)
+	GetRule(id int64) (model.AlertRule, error)
+	DeleteRule(id int64) error
+	Trigger(a model.Alert) (int64, error)
+	UpdateError(id int64, msg string) error
+	GetAlerts(ruleID int64, limit int) ([]model.Alert, error)
+}
+
+type alertRepo struct {
+	db *sql.DB
+}
+
+const (
+	alertRuleColumns = "`id`,`name`,`base`,`quote`,`condition`,`threshold`,`cooldown_seconds`,`sinks`,`created_at`,`last_triggered_at`"
+	alertColumns     = "`id`,`rule_id`,`date`,`value`,`previous`,`message`,`error`,`triggered_at`"
+)
+
+func NewAlert(db *sql.DB) AlertRepository {
+	return &alertRepo{db: db}
+}
+
+func (r *alertRepo) InsertRule(rule model.AlertRule) (int64, error) {
+	q := "INSERT INTO alert_rules(name, base, quote, `condition`, threshold, cooldown_seconds, sinks, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
+	res, err := r.db.Exec(q, rule.Name, rule.Base, rule.Quote, rule.Condition, rule.Threshold,
+		int64(rule.Cooldown/time.Second), strings.Join(rule.Sinks, ","), rule.CreatedAt)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (r *alertRepo) GetRules() ([]model.AlertRule, error) {
+	rules := make([]model.AlertRule, 0)
+	results, err := r.db.Query("SELECT " + alertRuleColumns + " FROM `alert_rules` ORDER BY `id` ASC")
+	if err != nil {
+		return nil, err
+	}
+	defer results.Close()
+	for results.Next() {
+		rule, err := scanAlertRule(results)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, results.Err()
+}
+
+func (r *alertRepo) GetRule(id int64) (model.AlertRule, error) {
+	rule, err := scanAlertRule(r.db.QueryRow("SELECT "+alertRuleColumns+" FROM `alert_rules` WHERE `id` = ?", id))
+	if err == sql.ErrNoRows {
+		return model.AlertRule{}, ErrNotFound
+	}
+	return rule, err
+}
+
+// DeleteRule removes the rule. Its alert history is kept.
+func (r *alertRepo) DeleteRule(id int64) error {
+	res, err := r.db.Exec("DELETE FROM alert_rules WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Trigger records a and starts the cooldown of its rule, in one transaction.
+func (r *alertRepo) Trigger(a model.Alert) (int64, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	var previous interface{}
+	if a.Previous != 0 {
+		previous = a.Previous
+	}
+	res, err := tx.Exec("INSERT INTO alerts(rule_id, date, value, previous, message, error, triggered_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		a.RuleID, a.Date, a.Value, previous, a.Message, a.Error, a.TriggeredAt)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if _, err := tx.Exec("UPDATE alert_rules SET last_triggered_at = ? WHERE id = ?", a.TriggeredAt, a.RuleID); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+// UpdateError records the sink failures of a delivered alert.
+func (r *alertRepo) UpdateError(id int64, msg string) error {
+	_, err := r.db.Exec("UPDATE alerts SET error = ? WHERE id = ?", msg, id)
+	return err
+}
+
+// GetAlerts returns the latest limit alerts, newest first, of one rule or of
+// all rules when ruleID is zero.
+func (r *alertRepo) GetAlerts(ruleID int64, limit int) ([]model.Alert, error) {
+	q := "SELECT " + alertColumns + " FROM `alerts` ORDER BY `id` DESC LIMIT ?"
+	args := []interface{}{limit}
+	if ruleID != 0 {
+		q = "SELECT " + alertColumns + " FROM `alerts` WHERE `rule_id` = ? ORDER BY `id` DESC LIMIT ?"
+		args = []interface{}{ruleID, limit}
+	}
+	alerts := make([]model.Alert, 0)
+	results, err := r.db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer results.Close()
+	for results.Next() {
+		var (
+			a        model.Alert
+			date     time.Time
+			previous sql.NullFloat64
+		)
+		if err := results.Scan(&a.ID, &a.RuleID, &date, &a.Value, &previous, &a.Message, &a.Error, &a.TriggeredAt); err != nil {
+			return nil, err
+		}
+		a.Date = date.Format("2006-01-02")
+		a.Previous = previous.Float64
+		a.TriggeredAt = a.TriggeredAt.UTC()
+		alerts = append(alerts, a)
+	}
+	return alerts, results.Err()
+}
+
+func scanAlertRule(s scanner) (model.AlertRule, error) {
+	var (
+		rule          model.AlertRule
+		cooldown      int64
+		sinks         string
+		lastTriggered sql.NullTime
+	)
+	if err := s.Scan(&rule.ID, &rule.Name, &rule.Base, &rule.Quote, &rule.Condition, &rule.Threshold, &cooldown,
+		&sinks, &rule.CreatedAt, &lastTriggered); err != nil {
+		return model.AlertRule{}, err
+	}
+	rule.Cooldown = time.Duration(cooldown) * time.Second
+	if sinks != "" {
+		rule.Sinks = strings.Split(sinks, ",")
+	}
+	rule.CreatedAt = rule.CreatedAt.UTC()
+	if lastTriggered.Valid {
+		rule.LastTriggeredAt = lastTriggered.Time.UTC()
+	}
+	return rule, nil
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestAlertRepo_InsertRule(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "Error when opening a stub database connection")
+	defer db.Close()
+
+	now := time.Date(2021, 3, 31, 10, 0, 0, 0, time.UTC)
+	mock.ExpectExec("INSERT INTO alert_rules\\(name, base, quote, `condition`, threshold, cooldown_seconds, sinks, created_at\\)").
+		WithArgs("usd", "EUR", "USD", model.AlertCrossesAbove, 1.2, int64(86400), "log,file", now).
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	id, err := NewAlert(db).InsertRule(model.AlertRule{Name: "usd", Base: "EUR", Quote: "USD",
+		Condition: model.AlertCrossesAbove, Threshold: 1.2, Cooldown: 24 * time.Hour, Sinks: []string{"log", "file"},
+		CreatedAt: now})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), id)
+	assert.Nil(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+}
+
+func TestAlertRepo_GetRules(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "Error when opening a stub database connection")
+	defer db.Close()
+
+	now := time.Date(2021, 3, 31, 10, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT (.+) FROM `alert_rules` ORDER BY `id` ASC").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "base", "quote", "condition", "threshold",
+			"cooldown_seconds", "sinks", "created_at", "last_triggered_at"}).
+			AddRow(1, "", "EUR", "GBP", model.AlertChangePct, 1, 3600, "", now, now))
+	rules, err := NewAlert(db).GetRules()
+	assert.Nil(t, err)
+	assert.Equal(t, []model.AlertRule{{ID: 1, Base: "EUR", Quote: "GBP", Condition: model.AlertChangePct,
+		Threshold: 1, Cooldown: time.Hour, CreatedAt: now, LastTriggeredAt: now}}, rules)
+	assert.Nil(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+}
+
+func TestAlertRepo_Trigger(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "Error when opening a stub database connection")
+	defer db.Close()
+
+	now := time.Date(2021, 3, 31, 16, 0, 0, 0, time.UTC)
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO alerts\\(rule_id, date, value, previous, message, error, triggered_at\\)").
+		WithArgs(int64(1), "2021-03-31", 1.2012, 1.1987, "EUR/USD crossed above 1.2", "", now).
+		WillReturnResult(sqlmock.NewResult(7, 1))
+	mock.ExpectExec("UPDATE alert_rules SET last_triggered_at = \\? WHERE id = \\?").WithArgs(now, int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	id, err := NewAlert(db).Trigger(model.Alert{RuleID: 1, Date: "2021-03-31", Value: 1.2012, Previous: 1.1987,
+		Message: "EUR/USD crossed above 1.2", TriggeredAt: now})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(7), id)
+	assert.Nil(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+}
+
+func TestAlertRepo_UpdateError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "Error when opening a stub database connection")
+	defer db.Close()
+
+	mock.ExpectExec("UPDATE alerts SET error = \\? WHERE id = \\?").WithArgs("b: boom", int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.Nil(t, NewAlert(db).UpdateError(7, "b: boom"))
+	assert.Nil(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+}
+
+func TestAlertRepo_GetAlerts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "Error when opening a stub database connection")
+	defer db.Close()
+
+	now := time.Date(2021, 3, 31, 16, 0, 0, 0, time.UTC)
+	columns := []string{"id", "rule_id", "date", "value", "previous", "message", "error", "triggered_at"}
+	mock.ExpectQuery("SELECT (.+) FROM `alerts` WHERE `rule_id` = \\? ORDER BY `id` DESC LIMIT \\?").WithArgs(int64(1), 10).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(7, 1, time.Date(2021, 3, 31, 0, 0, 0, 0, time.UTC), 1.2012, nil, "m", "", now))
+	alerts, err := NewAlert(db).GetAlerts(1, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, []model.Alert{{ID: 7, RuleID: 1, Date: "2021-03-31", Value: 1.2012, Message: "m", TriggeredAt: now}}, alerts)
+
+	mock.ExpectQuery("SELECT (.+) FROM `alerts` ORDER BY `id` DESC LIMIT \\?").WithArgs(10).
+		WillReturnRows(sqlmock.NewRows(columns))
+	alerts, err = NewAlert(db).GetAlerts(0, 10)
+	assert.Nil(t, err)
+	assert.Empty(t, alerts)
+	assert.Nil(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+}
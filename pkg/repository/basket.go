@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"database/sql"
+	"github.com/huyhvq/eurofxref/pkg/model"
+)
+
+type BasketRepository interface {
+	Save(b model.Basket) (int64, error)
+	GetAll() ([]model.Basket, error)
+	GetByName(name string) (model.Basket, error)
+	Delete(name string) error
+}
+
+type basketRepo struct {
+	db *sql.DB
+}
+
+func NewBasket(db *sql.DB) BasketRepository {
+	return &basketRepo{db: db}
+}
+
+// Save creates the basket or replaces the definition and components of the
+// basket with the same name.
+func (r *basketRepo) Save(b model.Basket) (int64, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	q := "INSERT INTO baskets(name, kind, base_date, base_value, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?) " +
+		"ON DUPLICATE KEY UPDATE id = LAST_INSERT_ID(id), kind = VALUES(kind), base_date = VALUES(base_date), " +
+		"base_value = VALUES(base_value), updated_at = VALUES(updated_at)"
+	var baseValue interface{}
+	if b.Kind == model.BasketWeights {
+		baseValue = b.BaseValue
+	}
+	res, err := tx.Exec(q, b.Name, b.Kind, nullDate(b.BaseDate), baseValue, b.CreatedAt, b.UpdatedAt)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if _, err := tx.Exec("DELETE FROM basket_components WHERE basket_id = ?", id); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	stmt, err := tx.Prepare("INSERT INTO basket_components(basket_id, currency, weight, units) VALUES (?, ?, ?, ?)")
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	for _, c := range b.Components {
+		var weight interface{}
+		if b.Kind == model.BasketWeights {
+			weight = c.Weight
+		}
+		if _, err := stmt.Exec(id, c.Currency, weight, c.Units); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+	return id, tx.Commit()
+}
+
+const basketColumns = "`id`,`name`,`kind`,`base_date`,`base_value`,`created_at`,`updated_at`"
+
+func (r *basketRepo) GetAll() ([]model.Basket, error) {
+	baskets := make([]model.Basket, 0)
+	results, err := r.db.Query("SELECT " + basketColumns + " FROM `baskets` ORDER BY `name` ASC")
+	if err != nil {
+		return nil, err
+	}
+	defer results.Close()
+	index := make(map[int64]int)
+	for results.Next() {
+		b, err := scanBasket(results)
+		if err != nil {
+			return nil, err
+		}
+		index[b.ID] = len(baskets)
+		baskets = append(baskets, b)
+	}
+	if err := results.Err(); err != nil {
+		return nil, err
+	}
+	components, err := r.db.Query("SELECT `basket_id`,`currency`,`weight`,`units` FROM `basket_components` ORDER BY `basket_id`, `currency` ASC")
+	if err != nil {
+		return nil, err
+	}
+	defer components.Close()
+	for components.Next() {
+		id, c, err := scanBasketComponent(components)
+		if err != nil {
+			return nil, err
+		}
+		if i, ok := index[id]; ok {
+			baskets[i].Components = append(baskets[i].Components, c)
+		}
+	}
+	return baskets, components.Err()
+}
+
+func (r *basketRepo) GetByName(name string) (model.Basket, error) {
+	b, err := scanBasket(r.db.QueryRow("SELECT "+basketColumns+" FROM `baskets` WHERE `name` = ?", name))
+	if err == sql.ErrNoRows {
+		return model.Basket{}, ErrNotFound
+	}
+	if err != nil {
+		return model.Basket{}, err
+	}
+	results, err := r.db.Query("SELECT `basket_id`,`currency`,`weight`,`units` FROM `basket_components` WHERE `basket_id` = ? ORDER BY `currency` ASC", b.ID)
+	if err != nil {
+		return model.Basket{}, err
+	}
+	defer results.Close()
+	for results.Next() {
+		_, c, err := scanBasketComponent(results)
+		if err != nil {
+			return model.Basket{}, err
+		}
+		b.Components = append(b.Components, c)
+	}
+	return b, results.Err()
+}
+
+func (r *basketRepo) Delete(name string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM basket_components WHERE basket_id IN (SELECT id FROM baskets WHERE name = ?)", name); err != nil {
+		tx.Rollback()
+		return err
+	}
+	res, err := tx.Exec("DELETE FROM baskets WHERE name = ?", name)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		tx.Rollback()
+		if err != nil {
+			return err
+		}
+		return ErrNotFound
+	}
+	return tx.Commit()
+}
+
+func scanBasket(s scanner) (model.Basket, error) {
+	var (
+		b         model.Basket
+		baseDate  sql.NullTime
+		baseValue sql.NullFloat64
+	)
+	if err := s.Scan(&b.ID, &b.Name, &b.Kind, &baseDate, &baseValue, &b.CreatedAt, &b.UpdatedAt); err != nil {
+		return model.Basket{}, err
+	}
+	if baseDate.Valid {
+		b.BaseDate = baseDate.Time.Format("2006-01-02")
+	}
+	b.BaseValue = baseValue.Float64
+	b.CreatedAt = b.CreatedAt.UTC()
+	b.UpdatedAt = b.UpdatedAt.UTC()
+	b.Components = make([]model.BasketComponent, 0)
+	return b, nil
+}
+
+func scanBasketComponent(s scanner) (int64, model.BasketComponent, error) {
+	var (
+		id     int64
+		c      model.BasketComponent
+		weight sql.NullFloat64
+	)
+	if err := s.Scan(&id, &c.Currency, &weight, &c.Units); err != nil {
+		return 0, model.BasketComponent{}, err
+	}
+	c.Weight = weight.Float64
+	return id, c, nil
+}
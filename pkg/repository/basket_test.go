@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+var (
+	basketColumnNames    = []string{"id", "name", "kind", "base_date", "base_value", "created_at", "updated_at"}
+	componentColumnNames = []string{"basket_id", "currency", "weight", "units"}
+)
+
+func TestNewBasket(t *testing.T) {
+	db, _, err := sqlmock.New()
+	assert.Nil(t, err, "Error when opening a stub database connection")
+	defer db.Close()
+	assert.NotNil(t, NewBasket(db))
+}
+
+func TestBasketRepo_Save(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "Error when opening a stub database connection")
+	defer db.Close()
+
+	now := time.Date(2021, 3, 27, 10, 0, 0, 0, time.UTC)
+	b := model.Basket{
+		Name:      "REVENUE",
+		Kind:      model.BasketWeights,
+		BaseDate:  "2021-01-04",
+		BaseValue: 100,
+		Components: []model.BasketComponent{
+			{Currency: "GBP", Weight: 0.4, Units: 36},
+			{Currency: "USD", Weight: 0.6, Units: 73.5},
+		},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO baskets\\(name, kind, base_date, base_value, created_at, updated_at\\) (.+) ON DUPLICATE KEY UPDATE id = LAST_INSERT_ID\\(id\\)").
+		WithArgs("REVENUE", model.BasketWeights, "2021-01-04", 100.0, now, now).
+		WillReturnResult(sqlmock.NewResult(7, 1))
+	mock.ExpectExec("DELETE FROM basket_components WHERE basket_id = \\?").WithArgs(int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	prep := mock.ExpectPrepare("INSERT INTO basket_components\\(basket_id, currency, weight, units\\)")
+	prep.ExpectExec().WithArgs(int64(7), "GBP", 0.4, 36.0).WillReturnResult(sqlmock.NewResult(0, 1))
+	prep.ExpectExec().WithArgs(int64(7), "USD", 0.6, 73.5).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	id, err := NewBasket(db).Save(b)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(7), id)
+
+	expectedErr := errors.New("expected error")
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO baskets").
+		WithArgs("SDR", model.BasketUnits, nil, nil, now, now).
+		WillReturnError(expectedErr)
+	mock.ExpectRollback()
+	_, err = NewBasket(db).Save(model.Basket{Name: "SDR", Kind: model.BasketUnits, CreatedAt: now, UpdatedAt: now})
+	assert.Equal(t, expectedErr, err)
+	assert.Nil(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+}
+
+func TestBasketRepo_GetAll(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "Error when opening a stub database connection")
+	defer db.Close()
+
+	now := time.Date(2021, 3, 27, 10, 0, 0, 0, time.UTC)
+	bd := time.Date(2021, 1, 4, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT (.+) FROM `baskets` ORDER BY `name`").
+		WillReturnRows(sqlmock.NewRows(basketColumnNames).
+			AddRow(7, "REVENUE", model.BasketWeights, bd, 100.0, now, now).
+			AddRow(8, "SDR", model.BasketUnits, nil, nil, now, now))
+	mock.ExpectQuery("SELECT (.+) FROM `basket_components` ORDER BY `basket_id`, `currency`").
+		WillReturnRows(sqlmock.NewRows(componentColumnNames).
+			AddRow(7, "USD", 0.6, 73.5).
+			AddRow(8, "USD", nil, 0.57813))
+	bs, err := NewBasket(db).GetAll()
+	assert.Nil(t, err)
+	assert.Equal(t, []model.Basket{
+		{
+			ID:         7,
+			Name:       "REVENUE",
+			Kind:       model.BasketWeights,
+			BaseDate:   "2021-01-04",
+			BaseValue:  100,
+			Components: []model.BasketComponent{{Currency: "USD", Weight: 0.6, Units: 73.5}},
+			CreatedAt:  now,
+			UpdatedAt:  now,
+		},
+		{
+			ID:         8,
+			Name:       "SDR",
+			Kind:       model.BasketUnits,
+			Components: []model.BasketComponent{{Currency: "USD", Units: 0.57813}},
+			CreatedAt:  now,
+			UpdatedAt:  now,
+		},
+	}, bs)
+
+	expectedErr := errors.New("expected error")
+	mock.ExpectQuery("SELECT (.+) FROM `baskets`").WillReturnError(expectedErr)
+	bs, err = NewBasket(db).GetAll()
+	assert.Nil(t, bs)
+	assert.Equal(t, expectedErr, err)
+	assert.Nil(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+}
+
+func TestBasketRepo_GetByName(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "Error when opening a stub database connection")
+	defer db.Close()
+
+	now := time.Date(2021, 3, 27, 10, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT (.+) FROM `baskets` WHERE `name` = \\?").WithArgs("SDR").
+		WillReturnRows(sqlmock.NewRows(basketColumnNames).AddRow(8, "SDR", model.BasketUnits, nil, nil, now, now))
+	mock.ExpectQuery("SELECT (.+) FROM `basket_components` WHERE `basket_id` = \\?").WithArgs(int64(8)).
+		WillReturnRows(sqlmock.NewRows(componentColumnNames).
+			AddRow(8, "CNY", nil, 1.0174).
+			AddRow(8, "USD", nil, 0.57813))
+	b, err := NewBasket(db).GetByName("SDR")
+	assert.Nil(t, err)
+	assert.Equal(t, "SDR", b.Name)
+	assert.Equal(t, []model.BasketComponent{{Currency: "CNY", Units: 1.0174}, {Currency: "USD", Units: 0.57813}}, b.Components)
+
+	mock.ExpectQuery("SELECT (.+) FROM `baskets` WHERE `name` = \\?").WithArgs("NONE").WillReturnError(sql.ErrNoRows)
+	_, err = NewBasket(db).GetByName("NONE")
+	assert.Equal(t, ErrNotFound, err)
+	assert.Nil(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+}
+
+func TestBasketRepo_Delete(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "Error when opening a stub database connection")
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM basket_components WHERE basket_id IN").WithArgs("SDR").
+		WillReturnResult(sqlmock.NewResult(0, 5))
+	mock.ExpectExec("DELETE FROM baskets WHERE name = \\?").WithArgs("SDR").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	assert.Nil(t, NewBasket(db).Delete("SDR"))
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM basket_components").WithArgs("NONE").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM baskets").WithArgs("NONE").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+	assert.Equal(t, ErrNotFound, NewBasket(db).Delete("NONE"))
+	assert.Nil(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+}
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"database/sql"
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"github.com/huyhvq/eurofxref/pkg/override"
+	"time"
+)
+
+type OverrideRepository interface {
+	Insert(o model.RateOverride) (int64, error)
+	Revoke(id int64, by string, at time.Time) error
+	GetAll() ([]model.RateOverride, error)
+	GetByID(id int64) (model.RateOverride, error)
+	GetActive(start, end time.Time) ([]model.RateOverride, error)
+}
+
+type overrideRepo struct {
+	db *sql.DB
+}
+
+const overrideColumns = "`id`,`currency`,`start_date`,`end_date`,`rate`,`reason`,`created_by`,`created_at`,`revoked_by`,`revoked_at`"
+
+func NewOverride(db *sql.DB) OverrideRepository {
+	return &overrideRepo{db: db}
+}
+
+func (r *overrideRepo) Insert(o model.RateOverride) (int64, error) {
+	q := "INSERT INTO rate_overrides(currency, start_date, end_date, rate, reason, created_by, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)"
+	res, err := r.db.Exec(q, o.Currency, o.Start, o.End, o.Rate, o.Reason, o.CreatedBy, o.CreatedAt)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// Revoke stops an override from applying. The row is kept with who revoked
+// it and when.
+func (r *overrideRepo) Revoke(id int64, by string, at time.Time) error {
+	q := "UPDATE rate_overrides SET revoked_by = ?, revoked_at = ? WHERE id = ? AND revoked_at IS NULL"
+	res, err := r.db.Exec(q, by, at, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *overrideRepo) GetAll() ([]model.RateOverride, error) {
+	return r.query("SELECT " + overrideColumns + " FROM `rate_overrides` ORDER BY `id` DESC")
+}
+
+func (r *overrideRepo) GetByID(id int64) (model.RateOverride, error) {
+	o, err := scanOverride(r.db.QueryRow("SELECT "+overrideColumns+" FROM `rate_overrides` WHERE `id` = ?", id))
+	if err == sql.ErrNoRows {
+		return model.RateOverride{}, ErrNotFound
+	}
+	return o, err
+}
+
+// GetActive returns the overrides not revoked that cover any date between
+// start and end.
+func (r *overrideRepo) GetActive(start, end time.Time) ([]model.RateOverride, error) {
+	q := "SELECT " + overrideColumns + " FROM `rate_overrides` WHERE `revoked_at` IS NULL AND `start_date` <= ? AND `end_date` >= ? ORDER BY `id` ASC"
+	return r.query(q, end.Format("2006-01-02"), start.Format("2006-01-02"))
+}
+
+func (r *overrideRepo) query(q string, args ...interface{}) ([]model.RateOverride, error) {
+	overrides := make([]model.RateOverride, 0)
+	results, err := r.db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer results.Close()
+	for results.Next() {
+		o, err := scanOverride(results)
+		if err != nil {
+			return nil, err
+		}
+		overrides = append(overrides, o)
+	}
+	return overrides, results.Err()
+}
+
+func scanOverride(s scanner) (model.RateOverride, error) {
+	var (
+		o          model.RateOverride
+		start, end time.Time
+		revokedAt  sql.NullTime
+	)
+	if err := s.Scan(&o.ID, &o.Currency, &start, &end, &o.Rate, &o.Reason, &o.CreatedBy, &o.CreatedAt,
+		&o.RevokedBy, &revokedAt); err != nil {
+		return model.RateOverride{}, err
+	}
+	o.Start = start.Format("2006-01-02")
+	o.End = end.Format("2006-01-02")
+	o.CreatedAt = o.CreatedAt.UTC()
+	if revokedAt.Valid {
+		o.RevokedAt = revokedAt.Time.UTC()
+	}
+	return o, nil
+}
+
+// overriddenRate serves the rates with the active overrides applied. Writes
+// and aggregates go to the provider rates unchanged.
+type overriddenRate struct {
+	RateRepository
+	overrides OverrideRepository
+}
+
+// NewOverriddenRate wraps rates so that reads of daily rates give active
+// overrides precedence over the provider rates.
+func NewOverriddenRate(rates RateRepository, overrides OverrideRepository) RateRepository {
+	return &overriddenRate{RateRepository: rates, overrides: overrides}
+}
+
+func (r *overriddenRate) GetLatestRates() ([]model.Rate, error) {
+	d, err := r.GetLatestDate()
+	if err != nil {
+		return nil, err
+	}
+	return r.GetRatesByDate(d)
+}
+
+func (r *overriddenRate) GetRatesByDate(date time.Time) ([]model.Rate, error) {
+	rates, err := r.RateRepository.GetRatesByDate(date)
+	if err != nil {
+		return nil, err
+	}
+	return r.apply(rates, date, date)
+}
+
+func (r *overriddenRate) GetRatesBetween(start, end time.Time) ([]model.Rate, error) {
+	rates, err := r.RateRepository.GetRatesBetween(start, end)
+	if err != nil {
+		return nil, err
+	}
+	return r.apply(rates, start, end)
+}
+
+func (r *overriddenRate) apply(rates []model.Rate, start, end time.Time) ([]model.Rate, error) {
+	if len(rates) == 0 {
+		return rates, nil
+	}
+	overrides, err := r.overrides.GetActive(start, end)
+	if err != nil {
+		return nil, err
+	}
+	return override.Apply(rates, overrides), nil
+}
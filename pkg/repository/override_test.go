@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+var overrideColumnNames = []string{"id", "currency", "start_date", "end_date", "rate", "reason", "created_by",
+	"created_at", "revoked_by", "revoked_at"}
+
+func TestOverrideRepo_Insert(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "Error when opening a stub database connection")
+	defer db.Close()
+
+	now := time.Date(2021, 3, 27, 10, 0, 0, 0, time.UTC)
+	mock.ExpectExec("INSERT INTO rate_overrides\\(currency, start_date, end_date, rate, reason, created_by, created_at\\)").
+		WithArgs("USD", "2021-03-01", "2021-03-31", 1.2, "contract", "alice", now).
+		WillReturnResult(sqlmock.NewResult(4, 1))
+	id, err := NewOverride(db).Insert(model.RateOverride{
+		Currency:  "USD",
+		Start:     "2021-03-01",
+		End:       "2021-03-31",
+		Rate:      1.2,
+		Reason:    "contract",
+		CreatedBy: "alice",
+		CreatedAt: now,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4), id)
+
+	expectedErr := errors.New("expected error")
+	mock.ExpectExec("INSERT INTO rate_overrides").WillReturnError(expectedErr)
+	_, err = NewOverride(db).Insert(model.RateOverride{})
+	assert.Equal(t, expectedErr, err)
+	assert.Nil(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+}
+
+func TestOverrideRepo_Revoke(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "Error when opening a stub database connection")
+	defer db.Close()
+
+	now := time.Date(2021, 3, 28, 10, 0, 0, 0, time.UTC)
+	mock.ExpectExec("UPDATE rate_overrides SET revoked_by = \\?, revoked_at = \\? WHERE id = \\? AND revoked_at IS NULL").
+		WithArgs("bob", now, int64(4)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.Nil(t, NewOverride(db).Revoke(4, "bob", now))
+
+	mock.ExpectExec("UPDATE rate_overrides").WithArgs("bob", now, int64(5)).WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.Equal(t, ErrNotFound, NewOverride(db).Revoke(5, "bob", now))
+	assert.Nil(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+}
+
+func TestOverrideRepo_GetAll(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "Error when opening a stub database connection")
+	defer db.Close()
+
+	st, _ := time.ParseInLocation("2006-01-02", "2021-03-01", time.UTC)
+	et, _ := time.ParseInLocation("2006-01-02", "2021-03-31", time.UTC)
+	ct := time.Date(2021, 3, 27, 10, 0, 0, 0, time.UTC)
+	rt := time.Date(2021, 3, 28, 10, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT (.+) FROM `rate_overrides` ORDER BY `id` DESC").
+		WillReturnRows(sqlmock.NewRows(overrideColumnNames).
+			AddRow(5, "RUB", st, et, 90.0, "feed issue", "alice", ct, "", nil).
+			AddRow(4, "USD", st, et, 1.2, "contract", "alice", ct, "bob", rt))
+	overrides, err := NewOverride(db).GetAll()
+	assert.Nil(t, err)
+	assert.Equal(t, []model.RateOverride{
+		{ID: 5, Currency: "RUB", Start: "2021-03-01", End: "2021-03-31", Rate: 90, Reason: "feed issue", CreatedBy: "alice", CreatedAt: ct},
+		{ID: 4, Currency: "USD", Start: "2021-03-01", End: "2021-03-31", Rate: 1.2, Reason: "contract", CreatedBy: "alice", CreatedAt: ct,
+			RevokedBy: "bob", RevokedAt: rt},
+	}, overrides)
+
+	expectedErr := errors.New("expected error")
+	mock.ExpectQuery("SELECT (.+) FROM `rate_overrides`").WillReturnError(expectedErr)
+	overrides, err = NewOverride(db).GetAll()
+	assert.Nil(t, overrides)
+	assert.Equal(t, expectedErr, err)
+	assert.Nil(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+}
+
+func TestOverrideRepo_GetByID(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "Error when opening a stub database connection")
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT (.+) FROM `rate_overrides` WHERE `id` = \\?").WithArgs(int64(9)).WillReturnError(sql.ErrNoRows)
+	_, err = NewOverride(db).GetByID(9)
+	assert.Equal(t, ErrNotFound, err)
+	assert.Nil(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+}
+
+func TestOverriddenRate_GetRatesBetween(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "Error when opening a stub database connection")
+	defer db.Close()
+
+	st, _ := time.ParseInLocation("2006-01-02", "2021-03-24", time.UTC)
+	et, _ := time.ParseInLocation("2006-01-02", "2021-03-25", time.UTC)
+	ct := time.Date(2021, 3, 27, 10, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT `currency`,`rate`,`created_at` from `rates` WHERE `created_at` BETWEEN \\? AND \\?").
+		WithArgs("2021-03-24", "2021-03-25").
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "rate", "created_at"}).
+			AddRow("USD", 1.18, st).
+			AddRow("USD", 1.19, et))
+	mock.ExpectQuery("SELECT (.+) FROM `rate_overrides` WHERE `revoked_at` IS NULL AND `start_date` <= \\? AND `end_date` >= \\?").
+		WithArgs("2021-03-25", "2021-03-24").
+		WillReturnRows(sqlmock.NewRows(overrideColumnNames).
+			AddRow(4, "USD", et, et, 1.2, "contract", "alice", ct, "", nil))
+	rates, err := NewOverriddenRate(NewRate(db), NewOverride(db)).GetRatesBetween(st, et)
+	assert.Nil(t, err)
+	assert.Equal(t, []model.Rate{
+		{Time: "2021-03-24", Currency: "USD", Rate: 1.18},
+		{Time: "2021-03-25", Currency: "USD", Rate: 1.2, OverrideID: 4},
+	}, rates)
+
+	expectedErr := errors.New("expected error")
+	mock.ExpectQuery("SELECT `currency`,`rate`,`created_at` from `rates`").
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "rate", "created_at"}).AddRow("USD", 1.18, st))
+	mock.ExpectQuery("SELECT (.+) FROM `rate_overrides`").WillReturnError(expectedErr)
+	rates, err = NewOverriddenRate(NewRate(db), NewOverride(db)).GetRatesByDate(st)
+	assert.Nil(t, rates)
+	assert.Equal(t, expectedErr, err)
+	assert.Nil(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+}
+
+func TestOverriddenRate_GetRatesByDate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "Error when opening a stub database connection")
+	defer db.Close()
+
+	st, _ := time.ParseInLocation("2006-01-02", "2021-03-24", time.UTC)
+	et, _ := time.ParseInLocation("2006-01-02", "2021-03-25", time.UTC)
+	ct := time.Date(2021, 3, 27, 10, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		name       string
+		start, end time.Time
+	}{
+		{"last day", st, et},
+		{"single day", et, et},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mock.ExpectQuery("SELECT `currency`,`rate`,`created_at` from `rates` WHERE `created_at`= \\?").
+				WithArgs("2021-03-25").
+				WillReturnRows(sqlmock.NewRows([]string{"currency", "rate", "created_at"}).
+					AddRow("GBP", 0.86, et).
+					AddRow("USD", 1.19, et))
+			mock.ExpectQuery("SELECT (.+) FROM `rate_overrides` WHERE `revoked_at` IS NULL AND `start_date` <= \\? AND `end_date` >= \\?").
+				WithArgs("2021-03-25", "2021-03-25").
+				WillReturnRows(sqlmock.NewRows(overrideColumnNames).
+					AddRow(4, "USD", tc.start, tc.end, 1.2, "contract", "alice", ct, "", nil).
+					AddRow(5, "RUB", tc.start, tc.end, 90, "outage", "alice", ct, "", nil))
+			rates, err := NewOverriddenRate(NewRate(db), NewOverride(db)).GetRatesByDate(et)
+			assert.Nil(t, err)
+			assert.Equal(t, []model.Rate{
+				{Time: "2021-03-25", Currency: "GBP", Rate: 0.86},
+				{Time: "2021-03-25", Currency: "USD", Rate: 1.2, OverrideID: 4},
+				{Time: "2021-03-25", Currency: "RUB", Rate: 90, OverrideID: 5},
+			}, rates)
+			assert.Nil(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+		})
+	}
+}
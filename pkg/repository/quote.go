@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"database/sql"
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"time"
+)
+
+type QuoteRepository interface {
+	Insert(q model.Quote) error
+	GetByID(id string) (model.Quote, error)
+	Redeem(id string, at time.Time) error
+}
+
+type quoteRepo struct {
+	db *sql.DB
+}
+
+const quoteColumns = "`id`,`from_currency`,`to_currency`,`amount`,`rate`,`result`,`rate_date`,`profile`,`side`,`created_at`,`expires_at`,`redeemed_at`"
+
+func NewQuote(db *sql.DB) QuoteRepository {
+	return &quoteRepo{db: db}
+}
+
+func (r *quoteRepo) Insert(q model.Quote) error {
+	_, err := r.db.Exec("INSERT INTO quotes(id, from_currency, to_currency, amount, rate, result, rate_date, profile, side, created_at, expires_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		q.ID, q.From, q.To, q.Amount, q.Rate, q.Result, q.RateDate, q.Profile, q.Side, q.CreatedAt, q.ExpiresAt)
+	return err
+}
+
+func (r *quoteRepo) GetByID(id string) (model.Quote, error) {
+	var (
+		q          model.Quote
+		rateDate   time.Time
+		redeemedAt sql.NullTime
+	)
+	err := r.db.QueryRow("SELECT "+quoteColumns+" FROM `quotes` WHERE `id` = ?", id).Scan(&q.ID, &q.From, &q.To,
+		&q.Amount, &q.Rate, &q.Result, &rateDate, &q.Profile, &q.Side, &q.CreatedAt, &q.ExpiresAt, &redeemedAt)
+	if err == sql.ErrNoRows {
+		return model.Quote{}, ErrNotFound
+	}
+	if err != nil {
+		return model.Quote{}, err
+	}
+	q.RateDate = rateDate.Format("2006-01-02")
+	q.CreatedAt = q.CreatedAt.UTC()
+	q.ExpiresAt = q.ExpiresAt.UTC()
+	if redeemedAt.Valid {
+		q.RedeemedAt = redeemedAt.Time.UTC()
+	}
+	return q, nil
+}
+
+// Redeem marks the quote redeemed at at. It is a single conditional update so
+// concurrent redemptions cannot both succeed; ErrNotFound is returned when
+// the quote does not exist, has expired or was already redeemed.
+func (r *quoteRepo) Redeem(id string, at time.Time) error {
+	q := "UPDATE quotes SET redeemed_at = ? WHERE id = ? AND redeemed_at IS NULL AND expires_at > ?"
+	res, err := r.db.Exec(q, at, id, at)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestQuoteRepo_Insert(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "Error when opening a stub database connection")
+	defer db.Close()
+
+	now := time.Date(2021, 3, 29, 10, 0, 0, 0, time.UTC)
+	q := model.Quote{ID: "abc", From: "EUR", To: "USD", Amount: 100, Rate: 1.18, Result: 118, RateDate: "2021-03-26",
+		Side: "mid", CreatedAt: now, ExpiresAt: now.Add(5 * time.Minute)}
+	mock.ExpectExec("INSERT INTO quotes\\(id, from_currency, to_currency, amount, rate, result, rate_date, profile, side, created_at, expires_at\\)").
+		WithArgs("abc", "EUR", "USD", float64(100), 1.18, float64(118), "2021-03-26", "", "mid", now, now.Add(5*time.Minute)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.Nil(t, NewQuote(db).Insert(q))
+
+	expectedErr := errors.New("expected error")
+	mock.ExpectExec("INSERT INTO quotes").WillReturnError(expectedErr)
+	assert.Equal(t, expectedErr, NewQuote(db).Insert(q))
+	assert.Nil(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+}
+
+func TestQuoteRepo_GetByID(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "Error when opening a stub database connection")
+	defer db.Close()
+
+	now := time.Date(2021, 3, 29, 10, 0, 0, 0, time.UTC)
+	columns := []string{"id", "from_currency", "to_currency", "amount", "rate", "result", "rate_date", "profile",
+		"side", "created_at", "expires_at", "redeemed_at"}
+	mock.ExpectQuery("SELECT (.+) FROM `quotes` WHERE `id` = \\?").WithArgs("abc").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("abc", "EUR", "USD", 100, 1.18, 118,
+			time.Date(2021, 3, 26, 0, 0, 0, 0, time.UTC), "vip", "ask", now, now.Add(5*time.Minute), now.Add(time.Minute)))
+	q, err := NewQuote(db).GetByID("abc")
+	assert.Nil(t, err)
+	assert.Equal(t, model.Quote{ID: "abc", From: "EUR", To: "USD", Amount: 100, Rate: 1.18, Result: 118,
+		RateDate: "2021-03-26", Profile: "vip", Side: "ask", CreatedAt: now, ExpiresAt: now.Add(5 * time.Minute),
+		RedeemedAt: now.Add(time.Minute)}, q)
+
+	mock.ExpectQuery("SELECT (.+) FROM `quotes`").WithArgs("xyz").WillReturnRows(sqlmock.NewRows(columns))
+	_, err = NewQuote(db).GetByID("xyz")
+	assert.Equal(t, ErrNotFound, err)
+	assert.Nil(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+}
+
+func TestQuoteRepo_Redeem(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "Error when opening a stub database connection")
+	defer db.Close()
+
+	now := time.Date(2021, 3, 29, 10, 0, 0, 0, time.UTC)
+	mock.ExpectExec("UPDATE quotes SET redeemed_at = \\? WHERE id = \\? AND redeemed_at IS NULL AND expires_at > \\?").
+		WithArgs(now, "abc", now).WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.Nil(t, NewQuote(db).Redeem("abc", now))
+
+	mock.ExpectExec("UPDATE quotes").WithArgs(now, "abc", now).WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.Equal(t, ErrNotFound, NewQuote(db).Redeem("abc", now))
+	assert.Nil(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+}
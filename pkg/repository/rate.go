@@ -2,16 +2,28 @@ package repository
 
 import (
 	"database/sql"
+	"fmt"
 	"github.com/huyhvq/eurofxref/pkg/model"
+	"github.com/huyhvq/eurofxref/pkg/period"
+	"strings"
 	"time"
 )
 
 type RateRepository interface {
-	InsertMany([]model.Rate) error
 	GetLatestDate() (time.Time, error)
+	GetDateOnOrBefore(date time.Time) (time.Time, error)
 	GetLatestRates() ([]model.Rate, error)
 	GetRatesAnalyze() ([]model.RateAnalyze, error)
+	GetRatesByPeriod(g period.Granularity, start, end time.Time) ([]model.RatePeriod, error)
 	GetRatesByDate(date time.Time) ([]model.Rate, error)
+	GetRatesByDateAsOf(date, knownAt time.Time) ([]model.Rate, error)
+	GetRatesBetween(start, end time.Time) ([]model.Rate, error)
+	GetDateCounts(start, end time.Time) ([]model.DateCount, error)
+	GetCurrencyRanges() ([]model.CurrencyRange, error)
+	GetChanges(afterSeq int64, limit int) ([]model.RateChange, error)
+	SaveRevisions(revisions []model.RateRevision, deliveries []model.WebhookDelivery) error
+	RebuildAggregates() error
+	FillAggregates() error
 }
 
 type rateRepo struct {
@@ -22,22 +34,6 @@ func NewRate(db *sql.DB) RateRepository {
 	return &rateRepo{db: db}
 }
 
-func (r *rateRepo) InsertMany(rates []model.Rate) error {
-	tx, err := r.db.Begin()
-	if err != nil {
-		return err
-	}
-	q := "INSERT INTO rates(currency, rate, created_at) VALUES (?, ?, ?)"
-	stmt, err := tx.Prepare(q)
-	for _, rate := range rates {
-		if _, err := stmt.Exec(rate.Currency, rate.Rate, rate.Time); err != nil {
-			tx.Rollback()
-			return err
-		}
-	}
-	return tx.Commit()
-}
-
 func (r *rateRepo) GetLatestDate() (time.Time, error) {
 	var lds time.Time
 	if err := r.db.QueryRow("SELECT `created_at` FROM `rates` ORDER BY `created_at` DESC LIMIT 1").Scan(&lds); err != nil {
@@ -49,6 +45,20 @@ func (r *rateRepo) GetLatestDate() (time.Time, error) {
 	return lds.UTC(), nil
 }
 
+// GetDateOnOrBefore returns the most recent stored date not after date, or the
+// zero time when there is none.
+func (r *rateRepo) GetDateOnOrBefore(date time.Time) (time.Time, error) {
+	var d time.Time
+	q := "SELECT `created_at` FROM `rates` WHERE `created_at` <= ? ORDER BY `created_at` DESC LIMIT 1"
+	if err := r.db.QueryRow(q, date.Format("2006-01-02")).Scan(&d); err != nil {
+		if err == sql.ErrNoRows {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+	return d.UTC(), nil
+}
+
 func (r *rateRepo) GetLatestRates() ([]model.Rate, error) {
 	d, err := r.GetLatestDate()
 	if err != nil {
@@ -58,15 +68,25 @@ func (r *rateRepo) GetLatestRates() ([]model.Rate, error) {
 }
 
 func (r *rateRepo) GetRatesByDate(date time.Time) ([]model.Rate, error) {
-	rates := make([]model.Rate, 0)
 	q := "SELECT `currency`,`rate`,`created_at` from `rates` WHERE `created_at`= ? ORDER BY `currency` ASC"
-	results, err := r.db.Query(q, date.Format("2006-01-02"))
+	return r.queryRates(q, date.Format("2006-01-02"))
+}
+
+// GetRatesAnalyze combines the monthly aggregates instead of scanning the
+// whole rates table.
+func (r *rateRepo) GetRatesAnalyze() ([]model.RateAnalyze, error) {
+	rates := make([]model.RateAnalyze, 0)
+	q := "SELECT currency, SUM(`sum`) / SUM(`count`) AS avg_rate, MIN(`min`) AS min_rate, MAX(`max`) AS max_rate, " +
+		"SQRT(GREATEST(SUM(`sum_sq`) / SUM(`count`) - POW(SUM(`sum`) / SUM(`count`), 2), 0)) AS std_dev " +
+		"FROM rate_aggregates GROUP BY currency ORDER BY currency ASC"
+	results, err := r.db.Query(q)
 	if err != nil {
 		return nil, err
 	}
+	defer results.Close()
 	for results.Next() {
-		var rate model.Rate
-		if err := results.Scan(&rate.Currency, &rate.Rate, &rate.Time); err != nil {
+		var rate model.RateAnalyze
+		if err := results.Scan(&rate.Currency, &rate.Avg, &rate.Min, &rate.Max, &rate.StdDev); err != nil {
 			return nil, err
 		}
 		rates = append(rates, rate)
@@ -74,19 +94,258 @@ func (r *rateRepo) GetRatesByDate(date time.Time) ([]model.Rate, error) {
 	return rates, nil
 }
 
-func (r *rateRepo) GetRatesAnalyze() ([]model.RateAnalyze, error) {
-	rates := make([]model.RateAnalyze, 0)
-	q := "SELECT currency, AVG(rate) as avg_rate, MIN(rate) AS min_rate, MAX(rate) AS max_rate FROM rates GROUP BY currency ORDER BY currency ASC"
+// GetRatesByDateAsOf returns the rates of date as they were known at knownAt,
+// ignoring revisions fetched later.
+func (r *rateRepo) GetRatesByDateAsOf(date, knownAt time.Time) ([]model.Rate, error) {
+	q := "SELECT `currency`,`rate`,`rate_date` from `rate_revisions` WHERE `rate_date` = ? AND `fetched_at` <= ? " +
+		"AND (`superseded_at` IS NULL OR `superseded_at` > ?) ORDER BY `currency` ASC"
+	return r.queryRates(q, date.Format("2006-01-02"), knownAt, knownAt)
+}
+
+func (r *rateRepo) GetRatesBetween(start, end time.Time) ([]model.Rate, error) {
+	q := "SELECT `currency`,`rate`,`created_at` from `rates` WHERE `created_at` BETWEEN ? AND ? ORDER BY `created_at`, `currency` ASC"
+	return r.queryRates(q, start.Format("2006-01-02"), end.Format("2006-01-02"))
+}
+
+func (r *rateRepo) GetDateCounts(start, end time.Time) ([]model.DateCount, error) {
+	counts := make([]model.DateCount, 0)
+	q := "SELECT `created_at`, COUNT(*) FROM `rates` WHERE `created_at` BETWEEN ? AND ? GROUP BY `created_at` ORDER BY `created_at` ASC"
+	results, err := r.db.Query(q, start.Format("2006-01-02"), end.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer results.Close()
+	for results.Next() {
+		var (
+			c model.DateCount
+			t time.Time
+		)
+		if err := results.Scan(&t, &c.Count); err != nil {
+			return nil, err
+		}
+		c.Time = t.Format("2006-01-02")
+		counts = append(counts, c)
+	}
+	return counts, results.Err()
+}
+
+// GetCurrencyRanges reads the first and last date of every currency from the
+// monthly aggregates instead of scanning the rates table.
+func (r *rateRepo) GetCurrencyRanges() ([]model.CurrencyRange, error) {
+	ranges := make([]model.CurrencyRange, 0)
+	q := "SELECT `currency`, MIN(`first_date`), MAX(`last_date`) FROM `rate_aggregates` GROUP BY `currency` ORDER BY `currency` ASC"
 	results, err := r.db.Query(q)
 	if err != nil {
 		return nil, err
 	}
+	defer results.Close()
 	for results.Next() {
-		var rate model.RateAnalyze
-		if err := results.Scan(&rate.Currency, &rate.Avg, &rate.Min, &rate.Max); err != nil {
+		var (
+			c           model.CurrencyRange
+			first, last time.Time
+		)
+		if err := results.Scan(&c.Currency, &first, &last); err != nil {
 			return nil, err
 		}
+		c.First = first.Format("2006-01-02")
+		c.Last = last.Format("2006-01-02")
+		ranges = append(ranges, c)
+	}
+	return ranges, results.Err()
+}
+
+func (r *rateRepo) queryRates(q string, args ...interface{}) ([]model.Rate, error) {
+	rates := make([]model.Rate, 0)
+	results, err := r.db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer results.Close()
+	for results.Next() {
+		var (
+			rate model.Rate
+			t    time.Time
+		)
+		if err := results.Scan(&rate.Currency, &rate.Rate, &t); err != nil {
+			return nil, err
+		}
+		rate.Time = t.Format("2006-01-02")
 		rates = append(rates, rate)
 	}
-	return rates, nil
+	return rates, results.Err()
+}
+
+// GetChanges returns up to limit revisions with a change sequence above
+// afterSeq in commit order, each with the value it replaced if any.
+func (r *rateRepo) GetChanges(afterSeq int64, limit int) ([]model.RateChange, error) {
+	changes := make([]model.RateChange, 0)
+	q := "SELECT r.`seq`, r.`currency`, r.`rate_date`, r.`rate`, r.`source`, r.`feed_hash` = '', r.`fetched_at`, " +
+		"(SELECT p.`rate` FROM `rate_revisions` p WHERE p.`currency` = r.`currency` AND p.`rate_date` = r.`rate_date` " +
+		"AND p.`seq` < r.`seq` ORDER BY p.`seq` DESC LIMIT 1) " +
+		"FROM `rate_revisions` r WHERE r.`seq` > ? ORDER BY r.`seq` ASC LIMIT ?"
+	results, err := r.db.Query(q, afterSeq, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer results.Close()
+	for results.Next() {
+		var (
+			c        model.RateChange
+			t        time.Time
+			previous sql.NullFloat64
+		)
+		if err := results.Scan(&c.Seq, &c.Currency, &t, &c.Rate, &c.Source, &c.Seeded, &c.FetchedAt, &previous); err != nil {
+			return nil, err
+		}
+		c.Time = t.Format("2006-01-02")
+		c.FetchedAt = c.FetchedAt.UTC()
+		c.Previous, c.Revised = previous.Float64, previous.Valid
+		changes = append(changes, c)
+	}
+	return changes, results.Err()
+}
+
+// SaveRevisions stores new observed values. The current revision of each
+// currency and date is superseded and the rates table and its monthly
+// aggregates are updated to the new value in place. The webhook deliveries
+// announcing the change are queued in the same transaction, so they exist
+// exactly when the change does.
+//
+// The new revisions take the next change sequence numbers. Taking them locks
+// the change_sequence row until commit, so a concurrent save waits and its
+// numbers are never visible before these.
+func (r *rateRepo) SaveRevisions(revisions []model.RateRevision, deliveries []model.WebhookDelivery) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	stmts := make([]*sql.Stmt, 0, 4)
+	for _, q := range []string{
+		"UPDATE rate_revisions SET superseded_at = ? WHERE currency = ? AND rate_date = ? AND superseded_at IS NULL",
+		"INSERT INTO rate_revisions(currency, rate_date, rate, source, feed_hash, fetched_at, seq) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		"UPDATE rates SET rate = ? WHERE currency = ? AND created_at = ?",
+		"INSERT INTO rates(currency, rate, created_at) SELECT ?, ?, ? FROM DUAL " +
+			"WHERE NOT EXISTS (SELECT 1 FROM rates WHERE currency = ? AND created_at = ?)",
+	} {
+		stmt, err := tx.Prepare(q)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		stmts = append(stmts, stmt)
+	}
+	seq, err := nextChangeSeq(tx, len(revisions))
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	rates := make([]model.Rate, 0, len(revisions))
+	for i, rev := range revisions {
+		args := [][]interface{}{
+			{rev.FetchedAt, rev.Currency, rev.Time},
+			{rev.Currency, rev.Time, rev.Rate, rev.Source, rev.FeedHash, rev.FetchedAt, seq + int64(i)},
+			{rev.Rate, rev.Currency, rev.Time},
+			{rev.Currency, rev.Rate, rev.Time, rev.Currency, rev.Time},
+		}
+		for i, stmt := range stmts {
+			if _, err := stmt.Exec(args[i]...); err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+		rates = append(rates, model.Rate{Time: rev.Time, Currency: rev.Currency, Rate: rev.Rate})
+	}
+	if err := refreshAggregates(tx, rates); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := enqueueDeliveries(tx, deliveries); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// nextChangeSeq reserves n change sequence numbers within tx and returns the
+// first. The counter row stays locked until tx ends.
+func nextChangeSeq(tx *sql.Tx, n int) (int64, error) {
+	if n == 0 {
+		return 0, nil
+	}
+	res, err := tx.Exec("UPDATE change_sequence SET seq = LAST_INSERT_ID(seq + ?) WHERE id = 1", n)
+	if err != nil {
+		return 0, err
+	}
+	last, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return last - int64(n) + 1, nil
+}
+
+// periodStarts maps each granularity to a SQL expression for the first day of
+// the period containing the date column %[1]s.
+var periodStarts = map[period.Granularity]string{
+	period.Week:    "DATE_SUB(%[1]s, INTERVAL WEEKDAY(%[1]s) DAY)",
+	period.Month:   "DATE_SUB(%[1]s, INTERVAL DAYOFMONTH(%[1]s) - 1 DAY)",
+	period.Quarter: "MAKEDATE(YEAR(%[1]s), 1) + INTERVAL QUARTER(%[1]s) - 1 QUARTER",
+	period.Year:    "MAKEDATE(YEAR(%[1]s), 1)",
+}
+
+// GetRatesByPeriod aggregates the rates between start and end per period and
+// currency. Open and close are the first and last published rates of the
+// period. Whole months are read from the monthly aggregates and only the days
+// around them from the rates table; weeks do not line up with months and are
+// always read from the rates table.
+func (r *rateRepo) GetRatesByPeriod(g period.Granularity, start, end time.Time) ([]model.RatePeriod, error) {
+	expr, ok := periodStarts[g]
+	if !ok {
+		return nil, period.ErrInvalidGranularity
+	}
+	// Every part yields partial aggregates per period and currency, a raw rate
+	// being a partial aggregate of one.
+	parts := make([]string, 0, 3)
+	args := make([]interface{}, 0, 6)
+	days := func(from, to time.Time) {
+		parts = append(parts, "SELECT "+fmt.Sprintf(expr, "`created_at`")+" AS `period`, `currency`, 1 AS `count`, "+
+			"`rate` AS `sum`, `rate` AS `min`, `rate` AS `max`, `created_at` AS `first_date`, `rate` AS `first_rate`, "+
+			"`created_at` AS `last_date`, `rate` AS `last_rate` FROM `rates` WHERE `created_at` BETWEEN ? AND ?")
+		args = append(args, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	}
+	first, last, ok := wholeMonths(start, end)
+	if g == period.Week || !ok {
+		days(start, end)
+	} else {
+		if start.Before(first) {
+			days(start, first.AddDate(0, 0, -1))
+		}
+		parts = append(parts, "SELECT "+fmt.Sprintf(expr, "`month`")+" AS `period`, `currency`, `count`, `sum`, "+
+			"`min`, `max`, `first_date`, `first_rate`, `last_date`, `last_rate` FROM `rate_aggregates` WHERE `month` BETWEEN ? AND ?")
+		args = append(args, first.Format("2006-01-02"), last.Format("2006-01-02"))
+		if lastDay := period.Month.End(last); lastDay.Before(end) {
+			days(lastDay.AddDate(0, 0, 1), end)
+		}
+	}
+	q := "SELECT `period`, `currency`, SUM(`sum`) / SUM(`count`), MIN(`min`), MAX(`max`), " +
+		"SUBSTRING_INDEX(GROUP_CONCAT(`first_rate` ORDER BY `first_date` ASC), ',', 1), " +
+		"SUBSTRING_INDEX(GROUP_CONCAT(`last_rate` ORDER BY `last_date` DESC), ',', 1), SUM(`count`) " +
+		"FROM (" + strings.Join(parts, " UNION ALL ") + ") p GROUP BY `period`, `currency` ORDER BY `period`, `currency` ASC"
+	results, err := r.db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer results.Close()
+	periods := make([]model.RatePeriod, 0)
+	for results.Next() {
+		var (
+			p model.RatePeriod
+			t time.Time
+		)
+		if err := results.Scan(&t, &p.Currency, &p.Avg, &p.Low, &p.High, &p.Open, &p.Close, &p.Count); err != nil {
+			return nil, err
+		}
+		p.Start = t.Format("2006-01-02")
+		periods = append(periods, p)
+	}
+	return periods, results.Err()
 }
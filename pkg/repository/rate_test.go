@@ -5,6 +5,7 @@ import (
 	"errors"
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/huyhvq/eurofxref/pkg/model"
+	"github.com/huyhvq/eurofxref/pkg/period"
 	"github.com/stretchr/testify/assert"
 	"testing"
 	"time"
@@ -89,7 +90,7 @@ func TestRateRepo_GetRatesByDate(t *testing.T) {
 	et, err := time.ParseInLocation("2006-01-02", "2021-03-25", time.UTC)
 	assert.Nil(t, err, "Error when parse time")
 	mock.ExpectQuery("SELECT (.+) from `rates` (.+) ORDER BY `currency` ASC").WithArgs("2021-03-25").
-		WillReturnRows(sqlmock.NewRows(columns).AddRow("USD", 1.345, "2021-03-25"))
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("USD", 1.345, et))
 	r := NewRate(db)
 	rs, err := r.GetRatesByDate(et)
 	assert.Nil(t, err)
@@ -121,52 +122,13 @@ func TestRateRepo_GetRatesByDate_Error(t *testing.T) {
 
 	columns := []string{"currency", "rate", "created_at"}
 	mock.ExpectQuery("SELECT (.+) from `rates` (.+) ORDER BY `currency` ASC").WithArgs("2021-03-25").
-		WillReturnRows(sqlmock.NewRows(columns).AddRow("USD", "ahihi", "2021-03-25"))
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("USD", "ahihi", et))
 	rs, err = r.GetRatesByDate(et)
 	assert.NotNil(t, err)
 	assert.Nil(t, rs)
 	assert.Nil(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
 }
 
-func TestRateRepo_InsertMany(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	assert.Nil(t, err, "Error when opening a stub database connection")
-	defer db.Close()
-
-	mock.ExpectBegin()
-	ep := mock.ExpectPrepare("INSERT INTO rates\\(currency, rate, created_at\\) VALUES \\(\\?, \\?, \\?\\)")
-	for _, rate := range rates {
-		ep.ExpectExec().WithArgs(rate.Currency, rate.Rate, rate.Time).WillReturnResult(sqlmock.NewResult(1, 1))
-	}
-	mock.ExpectCommit()
-	r := NewRate(db)
-	err = r.InsertMany(rates)
-	assert.Nil(t, err)
-	assert.Nil(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
-}
-
-func TestRateRepo_InsertMany_Error(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	assert.Nil(t, err, "Error when opening a stub database connection")
-	defer db.Close()
-
-	mock.ExpectBegin()
-	epf := mock.ExpectPrepare("INSERT INTO rates\\(currency, rate, created_at\\) VALUES \\(\\?, \\?, \\?\\)")
-	expectedErr := errors.New("expected error")
-	epf.ExpectExec().WillReturnError(expectedErr)
-	mock.ExpectRollback()
-	r := NewRate(db)
-	err = r.InsertMany(rates)
-	assert.NotNil(t, err)
-	assert.Equal(t, expectedErr, err)
-
-	mock.ExpectBegin().WillReturnError(expectedErr)
-	err = r.InsertMany(rates)
-	assert.NotNil(t, err)
-	assert.Equal(t, expectedErr, err)
-	assert.Nil(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
-}
-
 func TestRateRepo_GetLatestRates(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	assert.Nil(t, err, "Error when opening a stub database connection")
@@ -178,7 +140,7 @@ func TestRateRepo_GetLatestRates(t *testing.T) {
 		WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(et))
 
 	mock.ExpectQuery("SELECT (.+) from `rates` (.+) ORDER BY `currency` ASC").WithArgs("2021-03-25").
-		WillReturnRows(sqlmock.NewRows([]string{"currency", "rate", "created_at"}).AddRow("USD", 1.345, "2021-03-25"))
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "rate", "created_at"}).AddRow("USD", 1.345, et))
 	rs, err := NewRate(db).GetLatestRates()
 	assert.Nil(t, err)
 	assert.NotNil(t, rs)
@@ -211,8 +173,8 @@ func TestRateRepo_GetRatesAnalyze(t *testing.T) {
 	assert.Nil(t, err, "Error when opening a stub database connection")
 	defer db.Close()
 
-	mock.ExpectQuery("SELECT (.+) FROM rates GROUP BY currency ORDER BY currency ASC").
-		WillReturnRows(sqlmock.NewRows([]string{"currency", "avg_rate", "min_rate", "max_rate"}).AddRow("USD", 1.456, 1.345, 1.567))
+	mock.ExpectQuery("SELECT (.+) FROM rate_aggregates GROUP BY currency ORDER BY currency ASC").
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "avg_rate", "min_rate", "max_rate", "std_dev"}).AddRow("USD", 1.456, 1.345, 1.567, 0.05))
 	r := NewRate(db)
 	rs, err := r.GetRatesAnalyze()
 	assert.Nil(t, err)
@@ -223,6 +185,7 @@ func TestRateRepo_GetRatesAnalyze(t *testing.T) {
 		Min:      1.345,
 		Max:      1.567,
 		Avg:      1.456,
+		StdDev:   0.05,
 	}}, rs)
 	assert.Nil(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
 }
@@ -233,7 +196,7 @@ func TestRateRepo_GetRatesAnalyze_Error(t *testing.T) {
 	defer db.Close()
 	expectedErr := errors.New("expected error")
 
-	mock.ExpectQuery("SELECT (.+) FROM rates GROUP BY currency ORDER BY currency ASC").
+	mock.ExpectQuery("SELECT (.+) FROM rate_aggregates GROUP BY currency ORDER BY currency ASC").
 		WillReturnError(expectedErr)
 	r := NewRate(db)
 	rs, err := r.GetRatesAnalyze()
@@ -241,11 +204,351 @@ func TestRateRepo_GetRatesAnalyze_Error(t *testing.T) {
 	assert.NotNil(t, err)
 	assert.Equal(t, expectedErr, err)
 
-	mock.ExpectQuery("SELECT (.+) FROM rates GROUP BY currency ORDER BY currency ASC").
-		WillReturnRows(sqlmock.NewRows([]string{"currency", "avg_rate", "min_rate", "max_rate"}).AddRow("USD", 1.456, 1.345, "error"))
+	mock.ExpectQuery("SELECT (.+) FROM rate_aggregates GROUP BY currency ORDER BY currency ASC").
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "avg_rate", "min_rate", "max_rate", "std_dev"}).AddRow("USD", 1.456, 1.345, "error", 0.05))
 	rs, err = r.GetRatesAnalyze()
 	assert.Nil(t, rs)
 	assert.NotNil(t, err)
 	assert.NotEqual(t, expectedErr, err)
 	assert.Nil(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
 }
+
+func TestRateRepo_GetRatesByDateAsOf(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "Error when opening a stub database connection")
+	defer db.Close()
+
+	et, _ := time.ParseInLocation("2006-01-02", "2021-03-25", time.UTC)
+	ka := time.Date(2021, 3, 26, 9, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT (.+) from `rate_revisions` WHERE `rate_date` = \\? AND `fetched_at` <= \\? (.+) ORDER BY `currency` ASC").
+		WithArgs("2021-03-25", ka, ka).
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "rate", "rate_date"}).AddRow("USD", 1.345, et))
+	rs, err := NewRate(db).GetRatesByDateAsOf(et, ka)
+	assert.Nil(t, err)
+	assert.Equal(t, []model.Rate{{Time: "2021-03-25", Currency: "USD", Rate: 1.345}}, rs)
+
+	expectedErr := errors.New("expected error")
+	mock.ExpectQuery("SELECT (.+) from `rate_revisions`").WillReturnError(expectedErr)
+	rs, err = NewRate(db).GetRatesByDateAsOf(et, ka)
+	assert.Nil(t, rs)
+	assert.Equal(t, expectedErr, err)
+	assert.Nil(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+}
+
+func TestRateRepo_GetRatesBetween(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "Error when opening a stub database connection")
+	defer db.Close()
+
+	st, _ := time.ParseInLocation("2006-01-02", "2021-03-24", time.UTC)
+	et, _ := time.ParseInLocation("2006-01-02", "2021-03-25", time.UTC)
+	mock.ExpectQuery("SELECT (.+) from `rates` WHERE `created_at` BETWEEN \\? AND \\? ORDER BY `created_at`, `currency` ASC").
+		WithArgs("2021-03-24", "2021-03-25").
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "rate", "created_at"}).
+			AddRow("USD", 1.344, st).
+			AddRow("USD", 1.345, et))
+	rs, err := NewRate(db).GetRatesBetween(st, et)
+	assert.Nil(t, err)
+	assert.Equal(t, []model.Rate{
+		{Time: "2021-03-24", Currency: "USD", Rate: 1.344},
+		{Time: "2021-03-25", Currency: "USD", Rate: 1.345},
+	}, rs)
+
+	mock.ExpectQuery("SELECT (.+) from `rates` WHERE `created_at` BETWEEN").
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "rate", "created_at"}).AddRow("USD", "error", et))
+	rs, err = NewRate(db).GetRatesBetween(st, et)
+	assert.Nil(t, rs)
+	assert.NotNil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+}
+
+func TestRateRepo_SaveRevisions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "Error when opening a stub database connection")
+	defer db.Close()
+
+	fa := time.Date(2021, 3, 26, 9, 0, 0, 0, time.UTC)
+	revs := []model.RateRevision{
+		{Time: "2021-03-25", Currency: "USD", Rate: 1.346, Source: "ecb", FeedHash: "abc", FetchedAt: fa},
+	}
+	mock.ExpectBegin()
+	eu := mock.ExpectPrepare("UPDATE rate_revisions SET superseded_at = \\? WHERE (.+) AND superseded_at IS NULL")
+	ei := mock.ExpectPrepare("INSERT INTO rate_revisions\\(currency, rate_date, rate, source, feed_hash, fetched_at, seq\\)")
+	ep := mock.ExpectPrepare("UPDATE rates SET rate = \\? WHERE currency = \\? AND created_at = \\?")
+	er := mock.ExpectPrepare("INSERT INTO rates\\(currency, rate, created_at\\) SELECT (.+) WHERE NOT EXISTS")
+	mock.ExpectExec("UPDATE change_sequence SET seq = LAST_INSERT_ID\\(seq \\+ \\?\\) WHERE id = 1").WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(17, 1))
+	eu.ExpectExec().WithArgs(fa, "USD", "2021-03-25").WillReturnResult(sqlmock.NewResult(0, 1))
+	ei.ExpectExec().WithArgs("USD", "2021-03-25", 1.346, "ecb", "abc", fa, int64(17)).WillReturnResult(sqlmock.NewResult(2, 1))
+	ep.ExpectExec().WithArgs(1.346, "USD", "2021-03-25").WillReturnResult(sqlmock.NewResult(0, 1))
+	er.ExpectExec().WithArgs("USD", 1.346, "2021-03-25", "USD", "2021-03-25").WillReturnResult(sqlmock.NewResult(0, 0))
+	ead := mock.ExpectPrepare("DELETE FROM rate_aggregates")
+	eai := mock.ExpectPrepare("INSERT INTO rate_aggregates")
+	ead.ExpectExec().WithArgs("USD", "2021-03-01").WillReturnResult(sqlmock.NewResult(0, 1))
+	eai.ExpectExec().WithArgs("USD", "2021-03-01", "2021-03-31").WillReturnResult(sqlmock.NewResult(0, 1))
+	ed := mock.ExpectPrepare("INSERT INTO webhook_deliveries\\(webhook_id, event, sync_run_id, payload, status, next_attempt_at, created_at\\)")
+	ed.ExpectExec().WithArgs(int64(1), "rates.updated", int64(9), "{}", model.DeliveryPending, fa, fa).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	ed.ExpectExec().WithArgs(int64(2), "rates.updated", int64(9), "{}", model.DeliveryPending, fa, fa).
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectCommit()
+	d := model.WebhookDelivery{WebhookID: 1, Event: "rates.updated", SyncRunID: 9, Payload: "{}",
+		Status: model.DeliveryPending, NextAttemptAt: fa, CreatedAt: fa}
+	d2 := d
+	d2.WebhookID = 2
+	assert.Nil(t, NewRate(db).SaveRevisions(revs, []model.WebhookDelivery{d, d2}))
+	assert.Nil(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+}
+
+func TestRateRepo_SaveRevisions_Error(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "Error when opening a stub database connection")
+	defer db.Close()
+
+	expectedErr := errors.New("expected error")
+	revs := []model.RateRevision{{Time: "2021-03-25", Currency: "USD", Rate: 1.346}}
+	mock.ExpectBegin()
+	eu := mock.ExpectPrepare("UPDATE rate_revisions")
+	mock.ExpectPrepare("INSERT INTO rate_revisions")
+	mock.ExpectPrepare("UPDATE rates")
+	mock.ExpectPrepare("INSERT INTO rates")
+	mock.ExpectExec("UPDATE change_sequence").WillReturnResult(sqlmock.NewResult(1, 1))
+	eu.ExpectExec().WillReturnError(expectedErr)
+	mock.ExpectRollback()
+	assert.Equal(t, expectedErr, NewRate(db).SaveRevisions(revs, nil))
+
+	// A delivery that cannot be queued rolls the revisions back.
+	mock.ExpectBegin()
+	stmts := []*sqlmock.ExpectedPrepare{
+		mock.ExpectPrepare("UPDATE rate_revisions"),
+		mock.ExpectPrepare("INSERT INTO rate_revisions"),
+		mock.ExpectPrepare("UPDATE rates"),
+		mock.ExpectPrepare("INSERT INTO rates"),
+	}
+	mock.ExpectExec("UPDATE change_sequence").WillReturnResult(sqlmock.NewResult(2, 1))
+	for _, e := range stmts {
+		e.ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	ead := mock.ExpectPrepare("DELETE FROM rate_aggregates")
+	eai := mock.ExpectPrepare("INSERT INTO rate_aggregates")
+	ead.ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+	eai.ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare("INSERT INTO webhook_deliveries").ExpectExec().WillReturnError(expectedErr)
+	mock.ExpectRollback()
+	assert.Equal(t, expectedErr, NewRate(db).SaveRevisions(revs, []model.WebhookDelivery{{WebhookID: 1}}))
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare("UPDATE rate_revisions").WillReturnError(expectedErr)
+	mock.ExpectRollback()
+	assert.Equal(t, expectedErr, NewRate(db).SaveRevisions(revs, nil))
+
+	// Nothing is stored without change sequence numbers.
+	mock.ExpectBegin()
+	mock.ExpectPrepare("UPDATE rate_revisions")
+	mock.ExpectPrepare("INSERT INTO rate_revisions")
+	mock.ExpectPrepare("UPDATE rates")
+	mock.ExpectPrepare("INSERT INTO rates")
+	mock.ExpectExec("UPDATE change_sequence").WillReturnError(expectedErr)
+	mock.ExpectRollback()
+	assert.Equal(t, expectedErr, NewRate(db).SaveRevisions(revs, nil))
+
+	mock.ExpectBegin().WillReturnError(expectedErr)
+	assert.Equal(t, expectedErr, NewRate(db).SaveRevisions(revs, nil))
+	assert.Nil(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+}
+
+func TestRateRepo_GetDateOnOrBefore(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "Error when opening a stub database connection")
+	defer db.Close()
+
+	qt, _ := time.ParseInLocation("2006-01-02", "2021-03-28", time.UTC)
+	et, _ := time.ParseInLocation("2006-01-02", "2021-03-26", time.UTC)
+	mock.ExpectQuery("SELECT `created_at` FROM `rates` WHERE `created_at` <= \\? ORDER BY `created_at` DESC LIMIT 1").
+		WithArgs("2021-03-28").
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(et))
+	r := NewRate(db)
+	rt, err := r.GetDateOnOrBefore(qt)
+	assert.Nil(t, err)
+	assert.Equal(t, et, rt)
+
+	mock.ExpectQuery("SELECT `created_at` FROM `rates` WHERE").WillReturnError(sql.ErrNoRows)
+	rt, err = r.GetDateOnOrBefore(qt)
+	assert.Nil(t, err)
+	assert.True(t, rt.IsZero())
+
+	expectedErr := errors.New("expected error")
+	mock.ExpectQuery("SELECT `created_at` FROM `rates` WHERE").WillReturnError(expectedErr)
+	_, err = r.GetDateOnOrBefore(qt)
+	assert.Equal(t, expectedErr, err)
+	assert.Nil(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+}
+
+func TestRateRepo_GetDateCounts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "Error when opening a stub database connection")
+	defer db.Close()
+
+	st, _ := time.ParseInLocation("2006-01-02", "2021-03-24", time.UTC)
+	et, _ := time.ParseInLocation("2006-01-02", "2021-03-25", time.UTC)
+	mock.ExpectQuery("SELECT `created_at`, COUNT\\(\\*\\) FROM `rates` WHERE `created_at` BETWEEN \\? AND \\? GROUP BY `created_at`").
+		WithArgs("2021-03-24", "2021-03-25").
+		WillReturnRows(sqlmock.NewRows([]string{"created_at", "count"}).AddRow(st, 32).AddRow(et, 31))
+	cs, err := NewRate(db).GetDateCounts(st, et)
+	assert.Nil(t, err)
+	assert.Equal(t, []model.DateCount{{Time: "2021-03-24", Count: 32}, {Time: "2021-03-25", Count: 31}}, cs)
+
+	expectedErr := errors.New("expected error")
+	mock.ExpectQuery("SELECT `created_at`, COUNT").WillReturnError(expectedErr)
+	cs, err = NewRate(db).GetDateCounts(st, et)
+	assert.Nil(t, cs)
+	assert.Equal(t, expectedErr, err)
+	assert.Nil(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+}
+
+func TestRateRepo_GetCurrencyRanges(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "Error when opening a stub database connection")
+	defer db.Close()
+
+	ft, _ := time.ParseInLocation("2006-01-02", "1999-01-04", time.UTC)
+	lt, _ := time.ParseInLocation("2006-01-02", "2022-03-01", time.UTC)
+	mock.ExpectQuery("SELECT `currency`, MIN\\(`first_date`\\), MAX\\(`last_date`\\) FROM `rate_aggregates` GROUP BY `currency`").
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "first", "last"}).AddRow("RUB", ft, lt))
+	cs, err := NewRate(db).GetCurrencyRanges()
+	assert.Nil(t, err)
+	assert.Equal(t, []model.CurrencyRange{{Currency: "RUB", First: "1999-01-04", Last: "2022-03-01"}}, cs)
+
+	expectedErr := errors.New("expected error")
+	mock.ExpectQuery("SELECT `currency`, MIN").WillReturnError(expectedErr)
+	cs, err = NewRate(db).GetCurrencyRanges()
+	assert.Nil(t, cs)
+	assert.Equal(t, expectedErr, err)
+	assert.Nil(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+}
+
+func TestRateRepo_GetChanges(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "Error when opening a stub database connection")
+	defer db.Close()
+
+	d, _ := time.ParseInLocation("2006-01-02", "2021-03-31", time.UTC)
+	fetched := time.Date(2021, 3, 31, 16, 5, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT r.`seq`, (.+) FROM `rate_revisions` r WHERE r.`seq` > \\? ORDER BY r.`seq` ASC LIMIT \\?").
+		WithArgs(int64(40), 3).
+		WillReturnRows(sqlmock.NewRows([]string{"seq", "currency", "rate_date", "rate", "source", "seeded", "fetched_at", "previous"}).
+			AddRow(41, "USD", d, 1.1725, "ecb", false, fetched, nil).
+			AddRow(42, "GBP", d, 0.85209, "ecb", false, fetched, 0.8521).
+			AddRow(43, "JPY", d, 129.91, "ecb", true, d, nil))
+	changes, err := NewRate(db).GetChanges(40, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, []model.RateChange{
+		{Seq: 41, Time: "2021-03-31", Currency: "USD", Rate: 1.1725, Source: "ecb", FetchedAt: fetched},
+		{Seq: 42, Time: "2021-03-31", Currency: "GBP", Rate: 0.85209, Previous: 0.8521, Revised: true, Source: "ecb",
+			FetchedAt: fetched},
+		{Seq: 43, Time: "2021-03-31", Currency: "JPY", Rate: 129.91, Source: "ecb", Seeded: true, FetchedAt: d},
+	}, changes)
+
+	expectedErr := errors.New("expected error")
+	mock.ExpectQuery("SELECT r.`seq`").WillReturnError(expectedErr)
+	_, err = NewRate(db).GetChanges(0, 10)
+	assert.Equal(t, expectedErr, err)
+	assert.Nil(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+}
+
+func TestRateRepo_GetRatesByPeriod(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "Error when opening a stub database connection")
+	defer db.Close()
+
+	st, _ := time.ParseInLocation("2006-01-02", "2021-01-01", time.UTC)
+	et, _ := time.ParseInLocation("2006-01-02", "2021-03-31", time.UTC)
+	columns := []string{"period", "currency", "avg", "min", "max", "open", "close", "count"}
+	mock.ExpectQuery("SELECT `period`, `currency`, (.+) FROM \\(SELECT (.+) DAYOFMONTH\\(`month`\\)(.+) FROM `rate_aggregates` WHERE (.+)\\) p GROUP BY `period`, `currency`").
+		WithArgs("2021-01-01", "2021-03-01").
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(st, "USD", 1.21, 1.19, 1.23, "1.2225", "1.2146", 20))
+	rs, err := NewRate(db).GetRatesByPeriod(period.Month, st, et)
+	assert.Nil(t, err)
+	assert.Equal(t, []model.RatePeriod{{
+		Start:    "2021-01-01",
+		Currency: "USD",
+		Avg:      1.21,
+		Open:     1.2225,
+		Close:    1.2146,
+		High:     1.23,
+		Low:      1.19,
+		Count:    20,
+	}}, rs)
+
+	// Whole months come from the aggregates, the days around them from rates.
+	ms, _ := time.ParseInLocation("2006-01-02", "2021-01-15", time.UTC)
+	me, _ := time.ParseInLocation("2006-01-02", "2021-03-15", time.UTC)
+	mock.ExpectQuery("SELECT `period`, `currency`, (.+) FROM \\(SELECT (.+) FROM `rates` WHERE (.+) UNION ALL "+
+		"SELECT (.+) FROM `rate_aggregates` WHERE (.+) UNION ALL SELECT (.+) FROM `rates` WHERE (.+)\\) p GROUP BY `period`, `currency`").
+		WithArgs("2021-01-15", "2021-01-31", "2021-02-01", "2021-02-01", "2021-03-01", "2021-03-15").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(st, "USD", 1.21, 1.19, 1.23, "1.2225", "1.2146", 20))
+	_, err = NewRate(db).GetRatesByPeriod(period.Month, ms, me)
+	assert.Nil(t, err)
+
+	// Without a whole month, and for weeks, only rates are read.
+	for _, tc := range []struct {
+		g          period.Granularity
+		start, end time.Time
+	}{
+		{period.Month, st, st.AddDate(0, 0, 20)},
+		{period.Week, st, et},
+	} {
+		mock.ExpectQuery("SELECT `period`, `currency`, (.+) FROM \\(SELECT (.+) FROM `rates` WHERE `created_at` BETWEEN \\? AND \\?\\) p GROUP BY `period`, `currency`").
+			WithArgs(tc.start.Format("2006-01-02"), tc.end.Format("2006-01-02")).
+			WillReturnRows(sqlmock.NewRows(columns))
+		rs, err = NewRate(db).GetRatesByPeriod(tc.g, tc.start, tc.end)
+		assert.Nil(t, err)
+		assert.Empty(t, rs)
+	}
+
+	_, err = NewRate(db).GetRatesByPeriod("day", st, et)
+	assert.Equal(t, period.ErrInvalidGranularity, err)
+
+	expectedErr := errors.New("expected error")
+	mock.ExpectQuery("SELECT `period`").WillReturnError(expectedErr)
+	rs, err = NewRate(db).GetRatesByPeriod(period.Year, st, et)
+	assert.Nil(t, rs)
+	assert.Equal(t, expectedErr, err)
+	assert.Nil(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+}
+
+func TestRateRepo_RebuildAggregates(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "Error when opening a stub database connection")
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM rate_aggregates").WillReturnResult(sqlmock.NewResult(0, 12))
+	mock.ExpectExec("INSERT INTO rate_aggregates\\((.+)\\) SELECT (.+) FROM `rates` GROUP BY `currency`, `m`").
+		WillReturnResult(sqlmock.NewResult(0, 12))
+	mock.ExpectCommit()
+	assert.Nil(t, NewRate(db).RebuildAggregates())
+
+	expectedErr := errors.New("expected error")
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM rate_aggregates").WillReturnError(expectedErr)
+	mock.ExpectRollback()
+	assert.Equal(t, expectedErr, NewRate(db).RebuildAggregates())
+	assert.Nil(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+}
+
+func TestRateRepo_FillAggregates(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "Error when opening a stub database connection")
+	defer db.Close()
+
+	mock.ExpectExec("INSERT INTO rate_aggregates\\((.+)\\) SELECT (.+) FROM `rates` WHERE NOT EXISTS \\(SELECT 1 FROM `rate_aggregates`\\) GROUP BY `currency`, `m`").
+		WillReturnResult(sqlmock.NewResult(0, 12))
+	assert.Nil(t, NewRate(db).FillAggregates())
+
+	expectedErr := errors.New("expected error")
+	mock.ExpectExec("INSERT INTO rate_aggregates").WillReturnError(expectedErr)
+	assert.Equal(t, expectedErr, NewRate(db).FillAggregates())
+	assert.Nil(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"time"
+)
+
+var ErrNotFound = errors.New("not found")
+
+type SyncRunRepository interface {
+	Insert(run model.SyncRun) (int64, error)
+	Finish(run model.SyncRun) error
+	GetRuns(limit int) ([]model.SyncRun, error)
+	GetRunByID(id int64) (model.SyncRun, error)
+}
+
+type syncRunRepo struct {
+	db *sql.DB
+}
+
+const syncRunColumns = "`id`,`provider`,`endpoint`,`triggered_by`,`status`,`http_status`,`feed_hash`,`dates_fetched`," +
+	"`first_date`,`last_date`,`rows_inserted`,`rows_revised`,`error`,`started_at`,`finished_at`"
+
+func NewSyncRun(db *sql.DB) SyncRunRepository {
+	return &syncRunRepo{db: db}
+}
+
+func (r *syncRunRepo) Insert(run model.SyncRun) (int64, error) {
+	q := "INSERT INTO sync_runs(provider, endpoint, triggered_by, status, started_at) VALUES (?, ?, ?, ?, ?)"
+	res, err := r.db.Exec(q, run.Provider, run.Endpoint, run.TriggeredBy, run.Status, run.StartedAt)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (r *syncRunRepo) Finish(run model.SyncRun) error {
+	q := "UPDATE sync_runs SET endpoint = ?, status = ?, http_status = ?, feed_hash = ?, dates_fetched = ?, " +
+		"first_date = ?, last_date = ?, rows_inserted = ?, rows_revised = ?, error = ?, finished_at = ? WHERE id = ?"
+	_, err := r.db.Exec(q, run.Endpoint, run.Status, run.HTTPStatus, run.FeedHash, run.DatesFetched,
+		nullDate(run.FirstDate), nullDate(run.LastDate), run.RowsInserted, run.RowsRevised, run.Error, nullTime(run.FinishedAt), run.ID)
+	return err
+}
+
+func (r *syncRunRepo) GetRuns(limit int) ([]model.SyncRun, error) {
+	runs := make([]model.SyncRun, 0)
+	q := "SELECT " + syncRunColumns + " FROM `sync_runs` ORDER BY `id` DESC LIMIT ?"
+	results, err := r.db.Query(q, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer results.Close()
+	for results.Next() {
+		run, err := scanSyncRun(results)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, results.Err()
+}
+
+func (r *syncRunRepo) GetRunByID(id int64) (model.SyncRun, error) {
+	q := "SELECT " + syncRunColumns + " FROM `sync_runs` WHERE `id` = ?"
+	run, err := scanSyncRun(r.db.QueryRow(q, id))
+	if err == sql.ErrNoRows {
+		return model.SyncRun{}, ErrNotFound
+	}
+	return run, err
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSyncRun(s scanner) (model.SyncRun, error) {
+	var (
+		run                 model.SyncRun
+		first, last, finish sql.NullTime
+	)
+	if err := s.Scan(&run.ID, &run.Provider, &run.Endpoint, &run.TriggeredBy, &run.Status, &run.HTTPStatus,
+		&run.FeedHash, &run.DatesFetched, &first, &last, &run.RowsInserted, &run.RowsRevised, &run.Error, &run.StartedAt, &finish); err != nil {
+		return model.SyncRun{}, err
+	}
+	if first.Valid {
+		run.FirstDate = first.Time.Format("2006-01-02")
+	}
+	if last.Valid {
+		run.LastDate = last.Time.Format("2006-01-02")
+	}
+	if finish.Valid {
+		run.FinishedAt = finish.Time.UTC()
+	}
+	run.StartedAt = run.StartedAt.UTC()
+	return run, nil
+}
+
+func nullDate(d string) interface{} {
+	if d == "" {
+		return nil
+	}
+	return d
+}
+
+func nullTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
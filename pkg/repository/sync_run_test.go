@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+var syncRunColumnNames = []string{"id", "provider", "endpoint", "triggered_by", "status", "http_status", "feed_hash",
+	"dates_fetched", "first_date", "last_date", "rows_inserted", "rows_revised", "error", "started_at", "finished_at"}
+
+func TestNewSyncRun(t *testing.T) {
+	db, _, err := sqlmock.New()
+	assert.Nil(t, err, "Error when opening a stub database connection")
+	defer db.Close()
+	assert.NotNil(t, NewSyncRun(db))
+}
+
+func TestSyncRunRepo_Insert(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "Error when opening a stub database connection")
+	defer db.Close()
+
+	st := time.Date(2021, 3, 25, 16, 0, 0, 0, time.UTC)
+	mock.ExpectExec("INSERT INTO sync_runs\\(provider, endpoint, triggered_by, status, started_at\\)").
+		WithArgs("ecb", "", "manual", model.SyncRunning, st).
+		WillReturnResult(sqlmock.NewResult(3, 1))
+	id, err := NewSyncRun(db).Insert(model.SyncRun{
+		Provider:    "ecb",
+		TriggeredBy: "manual",
+		Status:      model.SyncRunning,
+		StartedAt:   st,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), id)
+
+	expectedErr := errors.New("expected error")
+	mock.ExpectExec("INSERT INTO sync_runs").WillReturnError(expectedErr)
+	_, err = NewSyncRun(db).Insert(model.SyncRun{})
+	assert.Equal(t, expectedErr, err)
+	assert.Nil(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+}
+
+func TestSyncRunRepo_Finish(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "Error when opening a stub database connection")
+	defer db.Close()
+
+	ft := time.Date(2021, 3, 25, 16, 0, 5, 0, time.UTC)
+	mock.ExpectExec("UPDATE sync_runs SET (.+) WHERE id = \\?").
+		WithArgs("http://mock", model.SyncFailed, 503, "", 0, nil, nil, 0, 0, "unable to connect", ft, int64(3)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	err = NewSyncRun(db).Finish(model.SyncRun{
+		ID:         3,
+		Endpoint:   "http://mock",
+		Status:     model.SyncFailed,
+		HTTPStatus: 503,
+		Error:      "unable to connect",
+		FinishedAt: ft,
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+}
+
+func TestSyncRunRepo_GetRuns(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "Error when opening a stub database connection")
+	defer db.Close()
+
+	st := time.Date(2021, 3, 25, 16, 0, 0, 0, time.UTC)
+	ft := st.Add(2 * time.Second)
+	d1 := time.Date(2021, 3, 24, 0, 0, 0, 0, time.UTC)
+	d2 := time.Date(2021, 3, 25, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT (.+) FROM `sync_runs` ORDER BY `id` DESC LIMIT \\?").WithArgs(5).
+		WillReturnRows(sqlmock.NewRows(syncRunColumnNames).
+			AddRow(2, "ecb", "http://mock", "manual", "success", 200, "abc", 2, d1, d2, 64, 3, "", st, ft).
+			AddRow(1, "ecb", "", "startup", "running", 0, "", 0, nil, nil, 0, 0, "", st, nil))
+	runs, err := NewSyncRun(db).GetRuns(5)
+	assert.Nil(t, err)
+	assert.Equal(t, []model.SyncRun{{
+		ID:           2,
+		Provider:     "ecb",
+		Endpoint:     "http://mock",
+		TriggeredBy:  "manual",
+		Status:       "success",
+		HTTPStatus:   200,
+		FeedHash:     "abc",
+		DatesFetched: 2,
+		FirstDate:    "2021-03-24",
+		LastDate:     "2021-03-25",
+		RowsInserted: 64,
+		RowsRevised:  3,
+		StartedAt:    st,
+		FinishedAt:   ft,
+	}, {
+		ID:          1,
+		Provider:    "ecb",
+		TriggeredBy: "startup",
+		Status:      "running",
+		StartedAt:   st,
+	}}, runs)
+	assert.Nil(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+}
+
+func TestSyncRunRepo_GetRuns_Error(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "Error when opening a stub database connection")
+	defer db.Close()
+
+	expectedErr := errors.New("expected error")
+	mock.ExpectQuery("SELECT (.+) FROM `sync_runs`").WillReturnError(expectedErr)
+	runs, err := NewSyncRun(db).GetRuns(5)
+	assert.Nil(t, runs)
+	assert.Equal(t, expectedErr, err)
+
+	mock.ExpectQuery("SELECT (.+) FROM `sync_runs`").
+		WillReturnRows(sqlmock.NewRows(syncRunColumnNames).
+			AddRow("error", "ecb", "", "startup", "running", 0, "", 0, nil, nil, 0, 0, "", time.Now(), nil))
+	runs, err = NewSyncRun(db).GetRuns(5)
+	assert.Nil(t, runs)
+	assert.NotNil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+}
+
+func TestSyncRunRepo_GetRunByID(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "Error when opening a stub database connection")
+	defer db.Close()
+
+	st := time.Date(2021, 3, 25, 16, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT (.+) FROM `sync_runs` WHERE `id` = \\?").WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows(syncRunColumnNames).
+			AddRow(1, "ecb", "", "startup", "running", 0, "", 0, nil, nil, 0, 0, "", st, nil))
+	run, err := NewSyncRun(db).GetRunByID(1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), run.ID)
+	assert.Equal(t, st, run.StartedAt)
+
+	mock.ExpectQuery("SELECT (.+) FROM `sync_runs` WHERE `id` = \\?").WithArgs(int64(9)).
+		WillReturnError(sql.ErrNoRows)
+	_, err = NewSyncRun(db).GetRunByID(9)
+	assert.Equal(t, ErrNotFound, err)
+	assert.Nil(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+}
@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"database/sql"
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"strings"
+	"time"
+)
+
+type WebhookRepository interface {
+	Insert(w model.Webhook) (int64, error)
+	GetAll() ([]model.Webhook, error)
+	GetByID(id int64) (model.Webhook, error)
+	Delete(id int64) error
+	ClaimDueDeliveries(claim string, at, until time.Time, limit int) ([]model.WebhookDelivery, error)
+	GetDeliveries(webhookID int64, limit int) ([]model.WebhookDelivery, error)
+	UpdateDelivery(d model.WebhookDelivery) error
+}
+
+type webhookRepo struct {
+	db *sql.DB
+}
+
+const (
+	webhookColumns  = "`id`,`url`,`secret`,`currencies`,`created_at`"
+	deliveryColumns = "`id`,`webhook_id`,`event`,`sync_run_id`,`payload`,`status`,`attempts`,`next_attempt_at`,`last_status_code`,`last_error`,`created_at`,`delivered_at`"
+)
+
+func NewWebhook(db *sql.DB) WebhookRepository {
+	return &webhookRepo{db: db}
+}
+
+func (r *webhookRepo) Insert(w model.Webhook) (int64, error) {
+	res, err := r.db.Exec("INSERT INTO webhooks(url, secret, currencies, created_at) VALUES (?, ?, ?, ?)",
+		w.URL, w.Secret, strings.Join(w.Currencies, ","), w.CreatedAt)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (r *webhookRepo) GetAll() ([]model.Webhook, error) {
+	webhooks := make([]model.Webhook, 0)
+	results, err := r.db.Query("SELECT " + webhookColumns + " FROM `webhooks` ORDER BY `id` ASC")
+	if err != nil {
+		return nil, err
+	}
+	defer results.Close()
+	for results.Next() {
+		w, err := scanWebhook(results)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, w)
+	}
+	return webhooks, results.Err()
+}
+
+func (r *webhookRepo) GetByID(id int64) (model.Webhook, error) {
+	w, err := scanWebhook(r.db.QueryRow("SELECT "+webhookColumns+" FROM `webhooks` WHERE `id` = ?", id))
+	if err == sql.ErrNoRows {
+		return model.Webhook{}, ErrNotFound
+	}
+	return w, err
+}
+
+// Delete removes the webhook. Its delivery log is kept; pending deliveries
+// fail on their next attempt.
+func (r *webhookRepo) Delete(id int64) error {
+	res, err := r.db.Exec("DELETE FROM webhooks WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// enqueueDeliveries inserts deliveries within tx.
+func enqueueDeliveries(tx *sql.Tx, deliveries []model.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	stmt, err := tx.Prepare("INSERT INTO webhook_deliveries(webhook_id, event, sync_run_id, payload, status, next_attempt_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	for _, d := range deliveries {
+		if _, err := stmt.Exec(d.WebhookID, d.Event, d.SyncRunID, d.Payload, d.Status, d.NextAttemptAt, d.CreatedAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ClaimDueDeliveries claims up to limit pending deliveries whose next attempt
+// is due at at, oldest first, and returns them. Claiming tags them with claim
+// and moves their next attempt to until in one update, so no other
+// dispatcher sees them as due before until; a delivery whose dispatcher
+// stopped is retried after that.
+func (r *webhookRepo) ClaimDueDeliveries(claim string, at, until time.Time, limit int) ([]model.WebhookDelivery, error) {
+	q := "UPDATE webhook_deliveries SET claim = ?, next_attempt_at = ? WHERE status = ? AND next_attempt_at <= ? ORDER BY id ASC LIMIT ?"
+	if _, err := r.db.Exec(q, claim, until, model.DeliveryPending, at, limit); err != nil {
+		return nil, err
+	}
+	q = "SELECT " + deliveryColumns + " FROM `webhook_deliveries` WHERE `claim` = ? AND `status` = ? ORDER BY `id` ASC"
+	return r.queryDeliveries(q, claim, model.DeliveryPending)
+}
+
+// GetDeliveries returns the latest limit deliveries of a webhook, newest
+// first.
+func (r *webhookRepo) GetDeliveries(webhookID int64, limit int) ([]model.WebhookDelivery, error) {
+	q := "SELECT " + deliveryColumns + " FROM `webhook_deliveries` WHERE `webhook_id` = ? ORDER BY `id` DESC LIMIT ?"
+	return r.queryDeliveries(q, webhookID, limit)
+}
+
+func (r *webhookRepo) UpdateDelivery(d model.WebhookDelivery) error {
+	q := "UPDATE webhook_deliveries SET status = ?, attempts = ?, next_attempt_at = ?, last_status_code = ?, last_error = ?, delivered_at = ? WHERE id = ?"
+	_, err := r.db.Exec(q, d.Status, d.Attempts, d.NextAttemptAt, d.LastStatusCode, d.LastError, nullTime(d.DeliveredAt), d.ID)
+	return err
+}
+
+func (r *webhookRepo) queryDeliveries(q string, args ...interface{}) ([]model.WebhookDelivery, error) {
+	deliveries := make([]model.WebhookDelivery, 0)
+	results, err := r.db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer results.Close()
+	for results.Next() {
+		var (
+			d           model.WebhookDelivery
+			deliveredAt sql.NullTime
+		)
+		if err := results.Scan(&d.ID, &d.WebhookID, &d.Event, &d.SyncRunID, &d.Payload, &d.Status, &d.Attempts,
+			&d.NextAttemptAt, &d.LastStatusCode, &d.LastError, &d.CreatedAt, &deliveredAt); err != nil {
+			return nil, err
+		}
+		d.NextAttemptAt = d.NextAttemptAt.UTC()
+		d.CreatedAt = d.CreatedAt.UTC()
+		if deliveredAt.Valid {
+			d.DeliveredAt = deliveredAt.Time.UTC()
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, results.Err()
+}
+
+func scanWebhook(s scanner) (model.Webhook, error) {
+	var (
+		w          model.Webhook
+		currencies string
+	)
+	if err := s.Scan(&w.ID, &w.URL, &w.Secret, &currencies, &w.CreatedAt); err != nil {
+		return model.Webhook{}, err
+	}
+	if currencies != "" {
+		w.Currencies = strings.Split(currencies, ",")
+	}
+	w.CreatedAt = w.CreatedAt.UTC()
+	return w, nil
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+var deliveryColumnNames = []string{"id", "webhook_id", "event", "sync_run_id", "payload", "status", "attempts",
+	"next_attempt_at", "last_status_code", "last_error", "created_at", "delivered_at"}
+
+func TestWebhookRepo_Insert(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "Error when opening a stub database connection")
+	defer db.Close()
+
+	now := time.Date(2021, 3, 30, 10, 0, 0, 0, time.UTC)
+	mock.ExpectExec("INSERT INTO webhooks\\(url, secret, currencies, created_at\\)").
+		WithArgs("https://example.com/hook", "s3cret", "USD,GBP", now).
+		WillReturnResult(sqlmock.NewResult(3, 1))
+	id, err := NewWebhook(db).Insert(model.Webhook{URL: "https://example.com/hook", Secret: "s3cret",
+		Currencies: []string{"USD", "GBP"}, CreatedAt: now})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), id)
+	assert.Nil(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+}
+
+func TestWebhookRepo_GetAll(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "Error when opening a stub database connection")
+	defer db.Close()
+
+	now := time.Date(2021, 3, 30, 10, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT (.+) FROM `webhooks` ORDER BY `id` ASC").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "url", "secret", "currencies", "created_at"}).
+			AddRow(1, "https://a.example", "a", "", now).
+			AddRow(2, "https://b.example", "b", "USD,GBP", now))
+	webhooks, err := NewWebhook(db).GetAll()
+	assert.Nil(t, err)
+	assert.Equal(t, []model.Webhook{
+		{ID: 1, URL: "https://a.example", Secret: "a", CreatedAt: now},
+		{ID: 2, URL: "https://b.example", Secret: "b", Currencies: []string{"USD", "GBP"}, CreatedAt: now},
+	}, webhooks)
+
+	expectedErr := errors.New("expected error")
+	mock.ExpectQuery("SELECT (.+) FROM `webhooks`").WillReturnError(expectedErr)
+	_, err = NewWebhook(db).GetAll()
+	assert.Equal(t, expectedErr, err)
+	assert.Nil(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+}
+
+func TestWebhookRepo_Delete(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "Error when opening a stub database connection")
+	defer db.Close()
+
+	mock.ExpectExec("DELETE FROM webhooks WHERE id = \\?").WithArgs(int64(3)).WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.Nil(t, NewWebhook(db).Delete(3))
+	mock.ExpectExec("DELETE FROM webhooks").WithArgs(int64(4)).WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.Equal(t, ErrNotFound, NewWebhook(db).Delete(4))
+	assert.Nil(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+}
+
+func TestWebhookRepo_ClaimDueDeliveries(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "Error when opening a stub database connection")
+	defer db.Close()
+
+	now := time.Date(2021, 3, 30, 10, 0, 0, 0, time.UTC)
+	until := now.Add(time.Minute)
+	mock.ExpectExec("UPDATE webhook_deliveries SET claim = \\?, next_attempt_at = \\? WHERE status = \\? AND next_attempt_at <= \\? ORDER BY id ASC LIMIT \\?").
+		WithArgs("c1", until, model.DeliveryPending, now, 50).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT (.+) FROM `webhook_deliveries` WHERE `claim` = \\? AND `status` = \\? ORDER BY `id` ASC").
+		WithArgs("c1", model.DeliveryPending).
+		WillReturnRows(sqlmock.NewRows(deliveryColumnNames).
+			AddRow(5, 1, "rates.updated", 9, "{}", model.DeliveryPending, 2, until, 500, "500 Internal Server Error", now, nil))
+	deliveries, err := NewWebhook(db).ClaimDueDeliveries("c1", now, until, 50)
+	assert.Nil(t, err)
+	assert.Equal(t, []model.WebhookDelivery{{ID: 5, WebhookID: 1, Event: "rates.updated", SyncRunID: 9, Payload: "{}",
+		Status: model.DeliveryPending, Attempts: 2, NextAttemptAt: until, LastStatusCode: 500,
+		LastError: "500 Internal Server Error", CreatedAt: now}}, deliveries)
+
+	expectedErr := errors.New("expected error")
+	mock.ExpectExec("UPDATE webhook_deliveries SET claim").WillReturnError(expectedErr)
+	_, err = NewWebhook(db).ClaimDueDeliveries("c2", now, until, 50)
+	assert.Equal(t, expectedErr, err)
+	assert.Nil(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+}
+
+func TestWebhookRepo_UpdateDelivery(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "Error when opening a stub database connection")
+	defer db.Close()
+
+	now := time.Date(2021, 3, 30, 10, 0, 0, 0, time.UTC)
+	mock.ExpectExec("UPDATE webhook_deliveries SET status = \\?, attempts = \\?, next_attempt_at = \\?, last_status_code = \\?, last_error = \\?, delivered_at = \\? WHERE id = \\?").
+		WithArgs(model.DeliveryDelivered, 1, now, 200, "", now, int64(5)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.Nil(t, NewWebhook(db).UpdateDelivery(model.WebhookDelivery{ID: 5, Status: model.DeliveryDelivered,
+		Attempts: 1, NextAttemptAt: now, LastStatusCode: 200, DeliveredAt: now}))
+	assert.Nil(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+}
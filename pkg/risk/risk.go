@@ -0,0 +1,52 @@
+// Package risk estimates the market risk of currency positions by historical
+// simulation.
+package risk
+
+import (
+	"math"
+	"sort"
+)
+
+// PnL revalues positions, given as their current value in base, under every
+// overlapping horizon-day move found in rates. rates holds one series per
+// position in units per base, aligned on the same dates and ordered by date.
+func PnL(values []float64, rates [][]float64, horizon int) []float64 {
+	if len(rates) == 0 || horizon < 1 {
+		return nil
+	}
+	n := len(rates[0]) - horizon
+	if n < 1 {
+		return nil
+	}
+	pnl := make([]float64, n)
+	for k := 0; k < n; k++ {
+		for i, v := range values {
+			pnl[k] += v * (rates[i][k]/rates[i][k+horizon] - 1)
+		}
+	}
+	return pnl
+}
+
+// VaR returns the value at risk and the expected shortfall of pnl at the
+// given confidence level, both as positive losses. The value at risk is the
+// smallest loss not exceeded in a confidence share of the scenarios and the
+// expected shortfall is the average loss of the scenarios at or beyond it.
+func VaR(pnl []float64, confidence float64) (float64, float64) {
+	if len(pnl) == 0 || confidence <= 0 || confidence >= 1 {
+		return math.NaN(), math.NaN()
+	}
+	losses := make([]float64, len(pnl))
+	for i, p := range pnl {
+		losses[i] = -p
+	}
+	sort.Float64s(losses)
+	k := int(math.Ceil(confidence*float64(len(losses)))) - 1
+	if k < 0 {
+		k = 0
+	}
+	var tail float64
+	for _, l := range losses[k:] {
+		tail += l
+	}
+	return losses[k], tail / float64(len(losses)-k)
+}
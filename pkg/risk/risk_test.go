@@ -0,0 +1,37 @@
+package risk
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+func TestPnL(t *testing.T) {
+	// 100 EUR worth of USD while EUR/USD goes 1.0 -> 1.25 -> 1.0.
+	rates := [][]float64{{1, 1.25, 1}}
+	assert.InDeltaSlice(t, []float64{-20, 25}, PnL([]float64{100}, rates, 1), 1e-9)
+	assert.InDeltaSlice(t, []float64{0}, PnL([]float64{100}, rates, 2), 1e-9)
+	assert.Nil(t, PnL([]float64{100}, rates, 3))
+	assert.Nil(t, PnL(nil, nil, 1))
+
+	two := [][]float64{{1, 2}, {1, 0.5}}
+	assert.InDeltaSlice(t, []float64{0}, PnL([]float64{100, 50}, two, 1), 1e-9)
+}
+
+func TestVaR(t *testing.T) {
+	pnl := []float64{5, -1, -10, 3, -4, 2, -7, 1, 0, -2}
+	v, es := VaR(pnl, 0.9)
+	assert.Equal(t, 7.0, v)
+	assert.Equal(t, 8.5, es)
+	v, es = VaR(pnl, 0.95)
+	assert.Equal(t, 10.0, v)
+	assert.Equal(t, 10.0, es)
+	v, _ = VaR(pnl, 0.6)
+	assert.Equal(t, 1.0, v)
+
+	v, es = VaR(nil, 0.95)
+	assert.True(t, math.IsNaN(v))
+	assert.True(t, math.IsNaN(es))
+	v, _ = VaR(pnl, 1)
+	assert.True(t, math.IsNaN(v))
+}
@@ -2,58 +2,58 @@ package server
 
 import (
 	"github.com/huyhvq/eurofxref/pkg/handler"
-	"github.com/huyhvq/eurofxref/pkg/model"
-	"github.com/huyhvq/eurofxref/pkg/repository"
-	"github.com/huyhvq/eurofxref/pkg/service/ecb"
 	"net/http"
 )
 
 type HttpServer interface {
 	Start() error
-	Initial(repository.RateRepository) error
 }
 
 type httpServer struct {
 	handler handler.HttpServerHandler
-	ecb     ecb.Service
 }
 
-func NewHttpServer(h handler.HttpServerHandler, ecb ecb.Service) HttpServer {
+func NewHttpServer(h handler.HttpServerHandler) HttpServer {
 	return &httpServer{
 		handler: h,
-		ecb:     ecb,
 	}
 }
 
-func (h *httpServer) Initial(r repository.RateRepository) error {
-	t, err := r.GetLatestDate()
-	if err != nil {
-		return err
-	}
-	rates, err := h.ecb.FetchRatesAfterDate(t)
-	if err != nil {
-		return err
-	}
-
-	rm := make([]model.Rate, 0, len(rates))
-
-	for _, rate := range rates {
-		rm = append(rm, model.Rate{
-			Time:     rate.Time.Format("2006-01-02"),
-			Currency: rate.Currency,
-			Rate:     rate.Rate,
-		})
-	}
-	if err := r.InsertMany(rm); err != nil {
-		return err
-	}
-	return nil
-}
-
 func (h *httpServer) Start() error {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/rates/latest", h.handler.GetLatestRates)
 	mux.HandleFunc("/rates/analyze", h.handler.GetRatesAnalyze)
+	mux.HandleFunc("/rates/fluctuation", h.handler.GetRatesFluctuation)
+	mux.HandleFunc("/rates/periods", h.handler.GetRatesPeriods)
+	mux.HandleFunc("/rates/indicators", h.handler.GetRatesIndicators)
+	mux.HandleFunc("/rates/correlation", h.handler.GetRatesCorrelation)
+	mux.HandleFunc("/rates/stream", h.handler.StreamRates)
+	mux.HandleFunc("/changes", h.handler.GetChanges)
+	mux.HandleFunc("/portfolio/var", h.handler.GetPortfolioVaR)
+	mux.HandleFunc("/convert", h.handler.Convert)
+	mux.HandleFunc("/convert/batch", h.handler.ConvertBatch)
+	mux.HandleFunc("/convert/csv", h.handler.ConvertCSV)
+	mux.HandleFunc("/quotes", h.handler.CreateQuote)
+	mux.HandleFunc("/quotes/", h.handler.Quote)
+	mux.HandleFunc("/currencies", h.handler.GetCurrencies)
+	mux.HandleFunc("/baskets", h.handler.GetBaskets)
+	mux.HandleFunc("/baskets/", h.handler.GetBasket)
 	mux.HandleFunc("/rates/", h.handler.GetRatesByDate)
+	mux.HandleFunc("/calendar", h.handler.GetCalendar)
+	mux.HandleFunc("/calendar/", h.handler.GetCalendarDay)
+	mux.HandleFunc("/admin/sync", h.handler.TriggerSync)
+	mux.HandleFunc("/admin/sync/runs", h.handler.GetSyncRuns)
+	mux.HandleFunc("/admin/sync/runs/", h.handler.GetSyncRun)
+	mux.HandleFunc("/admin/consistency", h.handler.GetConsistency)
+	mux.HandleFunc("/admin/repair", h.handler.Repair)
+	mux.HandleFunc("/admin/baskets", h.handler.AdminBaskets)
+	mux.HandleFunc("/admin/baskets/", h.handler.AdminBasket)
+	mux.HandleFunc("/admin/overrides", h.handler.AdminOverrides)
+	mux.HandleFunc("/admin/overrides/", h.handler.AdminOverride)
+	mux.HandleFunc("/admin/webhooks", h.handler.AdminWebhooks)
+	mux.HandleFunc("/admin/webhooks/", h.handler.AdminWebhook)
+	mux.HandleFunc("/admin/alerts", h.handler.AdminAlerts)
+	mux.HandleFunc("/admin/alerts/rules", h.handler.AdminAlertRules)
+	mux.HandleFunc("/admin/alerts/rules/", h.handler.AdminAlertRule)
 	return http.ListenAndServe(":8080", mux)
 }
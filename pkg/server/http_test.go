@@ -1,118 +1,151 @@
 package server
 
 import (
-	"errors"
-	"github.com/huyhvq/eurofxref/pkg/model"
-	"github.com/huyhvq/eurofxref/pkg/service/ecb"
 	"github.com/stretchr/testify/assert"
 	"net/http"
 	"testing"
-	"time"
 )
 
-type mockSrv struct {
+type mockHandler struct {
 }
 
-var (
-	fetchRatesAfterDateErr = errors.New("fetch rates after date error")
-	getLatestRatesErr      = errors.New("get latest rate error")
-	insertManyErr          = errors.New("insert many")
-	mt, _                  = time.ParseInLocation("2006-01-02", "2021-03-04", time.UTC)
-	ft, _                  = time.ParseInLocation("2006-01-02", "2021-03-03", time.UTC)
-)
+func (m mockHandler) GetLatestRates(w http.ResponseWriter, r *http.Request) {
+	panic("implement me")
+}
 
-func (m mockSrv) FetchRatesAfterDate(date time.Time) ([]ecb.Rate, error) {
-	if date == ft {
-		return []ecb.Rate{{
-			Time:     date,
-			Currency: "USD",
-			Rate:     1,
-		}}, nil
-	}
-	if date == mt {
-		return nil, fetchRatesAfterDateErr
-	}
-	return []ecb.Rate{{
-		Time:     date,
-		Currency: "USD",
-		Rate:     1,
-	}, {
-		Time:     date,
-		Currency: "USD",
-		Rate:     1,
-	}}, nil
+func (m mockHandler) GetRatesByDate(w http.ResponseWriter, r *http.Request) {
+	panic("implement me")
 }
 
-type mockHandler struct {
+func (m mockHandler) GetRatesAnalyze(w http.ResponseWriter, r *http.Request) {
+	panic("implement me")
 }
 
-func (m mockHandler) GetLatestRates(w http.ResponseWriter, r *http.Request) {
+func (m mockHandler) GetRatesFluctuation(w http.ResponseWriter, r *http.Request) {
 	panic("implement me")
 }
 
-func (m mockHandler) GetRatesByDate(w http.ResponseWriter, r *http.Request) {
+func (m mockHandler) GetRatesPeriods(w http.ResponseWriter, r *http.Request) {
 	panic("implement me")
 }
 
-func (m mockHandler) GetRatesAnalyze(w http.ResponseWriter, r *http.Request) {
+func (m mockHandler) GetRatesIndicators(w http.ResponseWriter, r *http.Request) {
+	panic("implement me")
+}
+
+func (m mockHandler) GetRatesCorrelation(w http.ResponseWriter, r *http.Request) {
 	panic("implement me")
 }
 
-type mockRepo struct {
-	Date time.Time
+func (m mockHandler) GetPortfolioVaR(w http.ResponseWriter, r *http.Request) {
+	panic("implement me")
 }
 
-func (m mockRepo) InsertMany(rates []model.Rate) error {
-	if len(rates) == 2 {
-		return nil
-	}
-	return insertManyErr
+func (m mockHandler) Convert(w http.ResponseWriter, r *http.Request) {
+	panic("implement me")
 }
 
-func (m mockRepo) GetLatestDate() (time.Time, error) {
-	t := time.Time{}
-	if m.Date == t {
-		return m.Date, getLatestRatesErr
-	}
-	return m.Date, nil
+func (m mockHandler) ConvertBatch(w http.ResponseWriter, r *http.Request) {
+	panic("implement me")
 }
 
-func (m mockRepo) GetLatestRates() ([]model.Rate, error) {
+func (m mockHandler) ConvertCSV(w http.ResponseWriter, r *http.Request) {
 	panic("implement me")
 }
 
-func (m mockRepo) GetRatesAnalyze() ([]model.RateAnalyze, error) {
+func (m mockHandler) GetCurrencies(w http.ResponseWriter, r *http.Request) {
 	panic("implement me")
 }
 
-func (m mockRepo) GetRatesByDate(date time.Time) ([]model.Rate, error) {
+func (m mockHandler) GetBaskets(w http.ResponseWriter, r *http.Request) {
 	panic("implement me")
 }
 
-func TestNewHttpServer(t *testing.T) {
-	h := NewHttpServer(mockHandler{}, mockSrv{})
-	assert.NotNil(t, h)
+func (m mockHandler) GetBasket(w http.ResponseWriter, r *http.Request) {
+	panic("implement me")
+}
+
+func (m mockHandler) AdminBaskets(w http.ResponseWriter, r *http.Request) {
+	panic("implement me")
+}
+
+func (m mockHandler) AdminBasket(w http.ResponseWriter, r *http.Request) {
+	panic("implement me")
+}
+
+func (m mockHandler) AdminOverrides(w http.ResponseWriter, r *http.Request) {
+	panic("implement me")
+}
+
+func (m mockHandler) AdminOverride(w http.ResponseWriter, r *http.Request) {
+	panic("implement me")
+}
+
+func (m mockHandler) CreateQuote(w http.ResponseWriter, r *http.Request) {
+	panic("implement me")
+}
+
+func (m mockHandler) Quote(w http.ResponseWriter, r *http.Request) {
+	panic("implement me")
+}
+
+func (m mockHandler) AdminWebhooks(w http.ResponseWriter, r *http.Request) {
+	panic("implement me")
+}
+
+func (m mockHandler) AdminWebhook(w http.ResponseWriter, r *http.Request) {
+	panic("implement me")
+}
+
+func (m mockHandler) AdminAlertRules(w http.ResponseWriter, r *http.Request) {
+	panic("implement me")
+}
+
+func (m mockHandler) AdminAlertRule(w http.ResponseWriter, r *http.Request) {
+	panic("implement me")
+}
+
+func (m mockHandler) AdminAlerts(w http.ResponseWriter, r *http.Request) {
+	panic("implement me")
+}
+
+func (m mockHandler) StreamRates(w http.ResponseWriter, r *http.Request) {
+	panic("implement me")
+}
+
+func (m mockHandler) GetChanges(w http.ResponseWriter, r *http.Request) {
+	panic("implement me")
+}
+
+func (m mockHandler) TriggerSync(w http.ResponseWriter, r *http.Request) {
+	panic("implement me")
+}
+
+func (m mockHandler) GetSyncRuns(w http.ResponseWriter, r *http.Request) {
+	panic("implement me")
 }
 
-func TestHttpServer_Initial(t *testing.T) {
-	t.Run("Initial successful", func(t *testing.T) {
-		mtf, _ := time.ParseInLocation("2006-01-02", "2021-03-05", time.UTC)
-		err := NewHttpServer(mockHandler{}, mockSrv{}).Initial(mockRepo{Date: mtf})
-		assert.Nil(t, err)
-	})
-	t.Run("Initial failed on GetLatestDate", func(t *testing.T) {
-		err := NewHttpServer(mockHandler{}, mockSrv{}).Initial(mockRepo{})
-		assert.NotNil(t, err)
-		assert.Equal(t, getLatestRatesErr, err)
-	})
-	t.Run("Initial failed on FetchRatesAfterDate", func(t *testing.T) {
-		err := NewHttpServer(mockHandler{}, mockSrv{}).Initial(mockRepo{Date: mt})
-		assert.NotNil(t, err)
-		assert.Equal(t, fetchRatesAfterDateErr, err)
-	})
-	t.Run("Initial failed on InsertMany", func(t *testing.T) {
-		err := NewHttpServer(mockHandler{}, mockSrv{}).Initial(mockRepo{Date: ft})
-		assert.NotNil(t, err)
-		assert.Equal(t, insertManyErr, err)
-	})
+func (m mockHandler) GetSyncRun(w http.ResponseWriter, r *http.Request) {
+	panic("implement me")
+}
+
+func (m mockHandler) GetConsistency(w http.ResponseWriter, r *http.Request) {
+	panic("implement me")
+}
+
+func (m mockHandler) Repair(w http.ResponseWriter, r *http.Request) {
+	panic("implement me")
+}
+
+func (m mockHandler) GetCalendar(w http.ResponseWriter, r *http.Request) {
+	panic("implement me")
+}
+
+func (m mockHandler) GetCalendarDay(w http.ResponseWriter, r *http.Request) {
+	panic("implement me")
+}
+
+func TestNewHttpServer(t *testing.T) {
+	h := NewHttpServer(mockHandler{})
+	assert.NotNil(t, h)
 }
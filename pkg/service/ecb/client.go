@@ -1,6 +1,8 @@
 package ecb
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/xml"
 	"errors"
 	"io/ioutil"
@@ -8,12 +10,17 @@ import (
 	"time"
 )
 
+const Provider = "ecb"
+
 type Service interface {
 	FetchRatesAfterDate(date time.Time) ([]Rate, error)
+	Fetch(date time.Time) (*Feed, error)
+	FetchHistory(date time.Time) (*Feed, error)
 }
 
 type Config struct {
-	Endpoint string
+	Endpoint        string
+	HistoryEndpoint string
 }
 
 type ecbService struct {
@@ -36,12 +43,13 @@ func NewService(cfg *Config) Service {
 	}
 }
 
-func (s ecbService) fetchAllRates() (*HistoryResponse, error) {
-	resp, err := s.client.Get(s.cfg.Endpoint)
+func (s ecbService) fetchAllRates(feed *Feed) (*HistoryResponse, error) {
+	resp, err := s.client.Get(feed.Endpoint)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
+	feed.StatusCode = resp.StatusCode
 	if resp.StatusCode != http.StatusOK {
 		return nil, errUnableToConnect
 	}
@@ -49,6 +57,8 @@ func (s ecbService) fetchAllRates() (*HistoryResponse, error) {
 	if err != nil {
 		return nil, errCantReadBody
 	}
+	sum := sha256.Sum256(data)
+	feed.Hash = hex.EncodeToString(sum[:])
 	var h HistoryResponse
 	if err := xml.Unmarshal(data, &h); err != nil {
 		return nil, err
@@ -56,16 +66,30 @@ func (s ecbService) fetchAllRates() (*HistoryResponse, error) {
 	return &h, nil
 }
 
-func (s ecbService) FetchRatesAfterDate(date time.Time) ([]Rate, error) {
-	totalRates, err := s.fetchAllRates()
+// Fetch downloads the feed and returns the rates published after date. The
+// returned Feed is never nil so callers can record the endpoint, HTTP status
+// and hash of a failed download.
+func (s ecbService) Fetch(date time.Time) (*Feed, error) {
+	return s.fetch(s.cfg.Endpoint, date)
+}
+
+// FetchHistory is Fetch against the full history feed, used to backfill
+// dates that have dropped out of the regular feed.
+func (s ecbService) FetchHistory(date time.Time) (*Feed, error) {
+	return s.fetch(s.cfg.HistoryEndpoint, date)
+}
+
+func (s ecbService) fetch(endpoint string, date time.Time) (*Feed, error) {
+	feed := &Feed{Endpoint: endpoint}
+	totalRates, err := s.fetchAllRates(feed)
 	if err != nil {
-		return nil, err
+		return feed, err
 	}
 	rates := make([]Rate, 0, len(totalRates.Cube))
 	for _, rs := range totalRates.Cube {
 		t, err := time.ParseInLocation("2006-01-02", rs.Time, time.UTC)
 		if err != nil {
-			return nil, err
+			return feed, err
 		}
 		if t.After(date) {
 			for _, r := range rs.Cube {
@@ -77,5 +101,14 @@ func (s ecbService) FetchRatesAfterDate(date time.Time) ([]Rate, error) {
 			}
 		}
 	}
-	return rates, nil
+	feed.Rates = rates
+	return feed, nil
+}
+
+func (s ecbService) FetchRatesAfterDate(date time.Time) ([]Rate, error) {
+	feed, err := s.Fetch(date)
+	if err != nil {
+		return nil, err
+	}
+	return feed.Rates, nil
 }
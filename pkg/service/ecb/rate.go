@@ -21,3 +21,11 @@ type HistoryCubeResponse struct {
 	Currency string  `xml:"currency,attr"`
 	Rate     float64 `xml:"rate,attr"`
 }
+
+// Feed describes a single download of the reference rates feed.
+type Feed struct {
+	Endpoint   string
+	StatusCode int
+	Hash       string
+	Rates      []Rate
+}
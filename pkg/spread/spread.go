@@ -0,0 +1,159 @@
+package spread
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+var (
+	ErrInvalidRule    = errors.New("invalid spread rule")
+	ErrUnknownProfile = errors.New("unknown client profile")
+)
+
+const wildcard = "*"
+
+// Rule marks up the mid rate of the pairs it matches by Bps basis points or
+// Percent percent on each side: bid = mid*(1-m), ask = mid*(1+m). Pair is
+// BASE/QUOTE, either side may be "*", and "*" alone matches every pair; a
+// rule also matches the inverse of its pair. An empty Profile is the default
+// profile every client falls back to. With Precision set, the bid is rounded
+// down and the ask up to that many decimals, so rounding never narrows the
+// spread.
+type Rule struct {
+	Profile   string  `mapstructure:"profile"`
+	Pair      string  `mapstructure:"pair"`
+	Bps       float64 `mapstructure:"bps"`
+	Percent   float64 `mapstructure:"percent"`
+	Precision *int    `mapstructure:"precision"`
+
+	base, quote string
+}
+
+// Quote is a mid rate with the bid and ask of a profile.
+type Quote struct {
+	Bid    float64
+	Mid    float64
+	Ask    float64
+	Markup float64 // basis points on each side
+}
+
+// Rules holds the spread rules grouped by profile.
+type Rules map[string][]Rule
+
+// New validates rules and groups them by profile.
+func New(rules []Rule) (Rules, error) {
+	rs := make(Rules)
+	for _, r := range rules {
+		r.Profile = strings.TrimSpace(r.Profile)
+		pair := strings.ToUpper(strings.TrimSpace(r.Pair))
+		if pair == "" || pair == wildcard {
+			pair = wildcard + "/" + wildcard
+		}
+		parts := strings.Split(pair, "/")
+		if len(parts) != 2 || !validSide(parts[0]) || !validSide(parts[1]) {
+			return nil, fmt.Errorf("%w: pair %q", ErrInvalidRule, r.Pair)
+		}
+		if r.Bps < 0 || r.Percent < 0 || (r.Bps != 0 && r.Percent != 0) {
+			return nil, fmt.Errorf("%w: pair %q needs either bps or percent", ErrInvalidRule, r.Pair)
+		}
+		if r.markup() >= 1 || (r.Precision != nil && (*r.Precision < 0 || *r.Precision > 12)) {
+			return nil, fmt.Errorf("%w: pair %q", ErrInvalidRule, r.Pair)
+		}
+		r.Pair, r.base, r.quote = pair, parts[0], parts[1]
+		rs[r.Profile] = append(rs[r.Profile], r)
+	}
+	return rs, nil
+}
+
+func validSide(s string) bool {
+	return s == wildcard || len(s) == 3
+}
+
+// markup is the fraction of mid added on each side.
+func (r Rule) markup() float64 {
+	if r.Percent != 0 {
+		return r.Percent / 100
+	}
+	return r.Bps / 10000
+}
+
+// score ranks how specifically r matches base/quote: 0 is no match, then the
+// catch-all, a rule naming one side and a rule naming both.
+func (r Rule) score(base, quote string) int {
+	best := 0
+	for _, p := range [][2]string{{base, quote}, {quote, base}} {
+		s := 1
+		for i, side := range []string{r.base, r.quote} {
+			switch side {
+			case wildcard:
+			case p[i]:
+				s += 2 - i
+			default:
+				s = 0
+			}
+			if s == 0 {
+				break
+			}
+		}
+		if s > best {
+			best = s
+		}
+	}
+	return best
+}
+
+// Lookup returns the most specific rule of profile matching base/quote,
+// falling back to the default profile. ok is false when no rule matches.
+func (rs Rules) Lookup(profile, base, quote string) (Rule, bool, error) {
+	profile = strings.TrimSpace(profile)
+	if !rs.HasProfile(profile) {
+		return Rule{}, false, ErrUnknownProfile
+	}
+	for _, p := range []string{profile, ""} {
+		var (
+			best  Rule
+			score int
+		)
+		for _, r := range rs[p] {
+			if s := r.score(base, quote); s > score {
+				best, score = r, s
+			}
+		}
+		if score > 0 {
+			return best, true, nil
+		}
+	}
+	return Rule{}, false, nil
+}
+
+// Quote applies the rule of profile for base/quote to mid. Without a
+// matching rule bid and ask equal mid.
+func (rs Rules) Quote(profile, base, quote string, mid float64) (Quote, error) {
+	r, ok, err := rs.Lookup(profile, base, quote)
+	if err != nil || !ok {
+		return Quote{Bid: mid, Mid: mid, Ask: mid}, err
+	}
+	return r.Apply(mid), nil
+}
+
+// Apply marks up mid by r.
+func (r Rule) Apply(mid float64) Quote {
+	m := r.markup()
+	q := Quote{Bid: mid * (1 - m), Mid: mid, Ask: mid * (1 + m), Markup: m * 10000}
+	if r.Precision != nil {
+		p := math.Pow(10, float64(*r.Precision))
+		// Strip float noise first so an exact value is not pushed a unit out.
+		q.Bid = math.Floor(math.Round(q.Bid*p*1e6)/1e6) / p
+		q.Ask = math.Ceil(math.Round(q.Ask*p*1e6)/1e6) / p
+	}
+	return q
+}
+
+// HasProfile reports whether profile is the default profile or has rules.
+func (rs Rules) HasProfile(profile string) bool {
+	profile = strings.TrimSpace(profile)
+	_, ok := rs[profile]
+	return profile == "" || ok
+}
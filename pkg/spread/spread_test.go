@@ -0,0 +1,100 @@
+package spread
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func TestNew(t *testing.T) {
+	rs, err := New([]Rule{
+		{Pair: "*", Bps: 50},
+		{Pair: "eur/usd", Percent: 0.1},
+		{Profile: "vip", Pair: "USD/*", Bps: 10},
+	})
+	assert.Nil(t, err)
+	assert.Len(t, rs[""], 2)
+	assert.Equal(t, "*/*", rs[""][0].Pair)
+	assert.Equal(t, "EUR/USD", rs[""][1].Pair)
+	assert.Len(t, rs["vip"], 1)
+
+	for _, invalid := range []Rule{
+		{Pair: "EURUSD", Bps: 10},
+		{Pair: "EU/USD", Bps: 10},
+		{Pair: "EUR/USD", Bps: 10, Percent: 0.1},
+		{Pair: "EUR/USD", Bps: -1},
+		{Pair: "EUR/USD", Percent: 100},
+		{Pair: "EUR/USD", Bps: 10, Precision: intPtr(-1)},
+	} {
+		_, err := New([]Rule{invalid})
+		assert.True(t, errors.Is(err, ErrInvalidRule), invalid.Pair)
+	}
+}
+
+func TestRules_Lookup(t *testing.T) {
+	rs, err := New([]Rule{
+		{Pair: "*", Bps: 50},
+		{Pair: "*/JPY", Bps: 40},
+		{Pair: "USD/*", Bps: 30},
+		{Pair: "EUR/USD", Bps: 10},
+		{Profile: "vip", Pair: "GBP/*", Bps: 5},
+	})
+	assert.Nil(t, err)
+
+	for _, c := range []struct {
+		profile, base, quote string
+		bps                  float64
+	}{
+		{"", "EUR", "USD", 10},
+		{"", "USD", "EUR", 10},
+		{"", "USD", "JPY", 30},
+		{"", "GBP", "JPY", 40},
+		{"", "GBP", "CHF", 50},
+		{"vip", "CHF", "GBP", 5},
+		{"vip", "EUR", "USD", 10},
+	} {
+		r, ok, err := rs.Lookup(c.profile, c.base, c.quote)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, c.bps, r.Bps, c.base+"/"+c.quote)
+	}
+
+	_, _, err = rs.Lookup("unknown", "EUR", "USD")
+	assert.Equal(t, ErrUnknownProfile, err)
+
+	rs, _ = New([]Rule{{Pair: "EUR/USD", Bps: 10}})
+	_, ok, err := rs.Lookup("", "EUR", "GBP")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+}
+
+func TestRules_Quote(t *testing.T) {
+	rs, err := New([]Rule{
+		{Pair: "EUR/USD", Bps: 50, Precision: intPtr(4)},
+		{Pair: "EUR/GBP", Percent: 1},
+	})
+	assert.Nil(t, err)
+
+	q, err := rs.Quote("", "EUR", "USD", 1.18)
+	assert.Nil(t, err)
+	assert.Equal(t, Quote{Bid: 1.1741, Mid: 1.18, Ask: 1.1859, Markup: 50}, q)
+
+	q, err = rs.Quote("", "EUR", "USD", 1.18333)
+	assert.Nil(t, err)
+	assert.Equal(t, 1.1774, q.Bid)
+	assert.Equal(t, 1.1893, q.Ask)
+
+	q, err = rs.Quote("", "EUR", "GBP", 0.86)
+	assert.Nil(t, err)
+	assert.InDelta(t, 0.8514, q.Bid, 1e-12)
+	assert.InDelta(t, 0.8686, q.Ask, 1e-12)
+	assert.Equal(t, float64(100), q.Markup)
+
+	q, err = rs.Quote("", "EUR", "JPY", 130)
+	assert.Nil(t, err)
+	assert.Equal(t, Quote{Bid: 130, Mid: 130, Ask: 130}, q)
+}
@@ -0,0 +1,31 @@
+package stats
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+func TestMean(t *testing.T) {
+	assert.Equal(t, 2.5, Mean([]float64{1, 2, 3, 4}))
+	assert.True(t, math.IsNaN(Mean(nil)))
+}
+
+func TestPearson(t *testing.T) {
+	assert.InDelta(t, 1, Pearson([]float64{1, 2, 3}, []float64{2, 4, 6}), 1e-12)
+	assert.InDelta(t, -1, Pearson([]float64{1, 2, 3}, []float64{3, 2, 1}), 1e-12)
+	assert.InDelta(t, 0.8, Pearson([]float64{1, 2, 3, 4, 5}, []float64{2, 1, 4, 3, 5}), 1e-12)
+	assert.True(t, math.IsNaN(Pearson([]float64{1, 1, 1}, []float64{1, 2, 3})))
+	assert.True(t, math.IsNaN(Pearson([]float64{1}, []float64{1})))
+	assert.True(t, math.IsNaN(Pearson([]float64{1, 2}, []float64{1})))
+}
+
+func TestSpearman(t *testing.T) {
+	assert.InDelta(t, 1, Spearman([]float64{1, 2, 3, 4}, []float64{1, 8, 27, 64}), 1e-12)
+	assert.True(t, math.IsNaN(Spearman([]float64{1, 2}, []float64{1})))
+}
+
+func TestRanks(t *testing.T) {
+	assert.Equal(t, []float64{3, 1, 4, 2}, Ranks([]float64{30, 10, 40, 20}))
+	assert.Equal(t, []float64{1, 2.5, 2.5, 4}, Ranks([]float64{1, 2, 2, 3}))
+}
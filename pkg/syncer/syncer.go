@@ -0,0 +1,257 @@
+package syncer
+
+import (
+	"github.com/huyhvq/eurofxref/pkg/consistency"
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"github.com/huyhvq/eurofxref/pkg/repository"
+	"github.com/huyhvq/eurofxref/pkg/service/ecb"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// epsilon is half a unit of the last decimal stored by the rates column, so a
+// value that the database would round to the stored one counts as unchanged.
+const epsilon = 5e-6
+
+const (
+	TriggerStartup = "startup"
+	TriggerManual  = "manual"
+	TriggerCLI     = "cli"
+	TriggerRepair  = "auto-repair"
+)
+
+// Event describes a successful run that stored new or revised rates.
+// NewDates are the publication dates, in order, after the latest date stored
+// before the run; listeners announcing fresh publications should only look at
+// these. BackfilledDates are the earlier dates that had no rates stored before
+// the run, such as holes filled by a repair.
+type Event struct {
+	Run             model.SyncRun
+	Revisions       []model.RateRevision
+	NewDates        []string
+	BackfilledDates []string
+}
+
+// Outbox returns the webhook deliveries announcing the revisions of e. They
+// are stored in the transaction that stores the revisions; e.Run is the run
+// in progress.
+type Outbox func(e Event) ([]model.WebhookDelivery, error)
+
+// Listener is called after every successful run that changed rates, in the
+// order the runs finish. It runs while the next sync waits, so it should
+// hand slow work off rather than do it inline.
+type Listener func(Event)
+
+// Syncer pulls new publications from the provider into the rates table and
+// records every attempt in the sync run history.
+type Syncer interface {
+	Sync(triggeredBy string) (model.SyncRun, error)
+	Repair(triggeredBy string, start, end time.Time) (model.SyncRun, error)
+	Check(start, end time.Time) (consistency.Report, error)
+}
+
+type syncer struct {
+	mu        sync.Mutex
+	rates     repository.RateRepository
+	runs      repository.SyncRunRepository
+	ecb       ecb.Service
+	outbox    Outbox
+	listeners []Listener
+}
+
+// New returns a Syncer. outbox may be nil when nothing is announced.
+func New(rates repository.RateRepository, runs repository.SyncRunRepository, ecb ecb.Service, outbox Outbox, listeners ...Listener) Syncer {
+	return &syncer{
+		rates:     rates,
+		runs:      runs,
+		ecb:       ecb,
+		outbox:    outbox,
+		listeners: listeners,
+	}
+}
+
+// Sync runs one synchronisation against the regular feed. Concurrent calls
+// are serialised so a manual trigger never races the startup sync.
+func (s *syncer) Sync(triggeredBy string) (model.SyncRun, error) {
+	return s.record(triggeredBy, func(run *model.SyncRun) (Event, error) {
+		return s.sync(run, s.ecb.Fetch, time.Time{}, time.Time{})
+	})
+}
+
+// Repair backfills [start, end] from the full history feed. Only rates that
+// are missing or differ from the stored ones are written.
+func (s *syncer) Repair(triggeredBy string, start, end time.Time) (model.SyncRun, error) {
+	return s.record(triggeredBy, func(run *model.SyncRun) (Event, error) {
+		return s.sync(run, s.ecb.FetchHistory, start, end)
+	})
+}
+
+// Check reports missing and partially stored dates in [start, end]. A zero
+// start or end defaults to the first or last stored date.
+func (s *syncer) Check(start, end time.Time) (consistency.Report, error) {
+	qEnd := end
+	if qEnd.IsZero() {
+		qEnd = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+	}
+	counts, err := s.rates.GetDateCounts(start, qEnd)
+	if err != nil {
+		return consistency.Report{}, err
+	}
+	if len(counts) > 0 {
+		if start.IsZero() {
+			start, _ = time.ParseInLocation("2006-01-02", counts[0].Time, time.UTC)
+		}
+		if end.IsZero() {
+			end, _ = time.ParseInLocation("2006-01-02", counts[len(counts)-1].Time, time.UTC)
+		}
+	}
+	if end.IsZero() || start.After(end) {
+		end = start
+	}
+	return consistency.Check(counts, start, end), nil
+}
+
+func (s *syncer) record(triggeredBy string, fn func(run *model.SyncRun) (Event, error)) (model.SyncRun, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	run := model.SyncRun{
+		Provider:    ecb.Provider,
+		TriggeredBy: triggeredBy,
+		Status:      model.SyncRunning,
+		StartedAt:   time.Now().UTC(),
+	}
+	id, err := s.runs.Insert(run)
+	if err != nil {
+		return run, err
+	}
+	run.ID = id
+
+	event, syncErr := fn(&run)
+	run.Status = model.SyncSuccess
+	if syncErr != nil {
+		run.Status = model.SyncFailed
+		run.Error = syncErr.Error()
+	}
+	run.FinishedAt = time.Now().UTC()
+	if err := s.runs.Finish(run); err != nil && syncErr == nil {
+		return run, err
+	}
+	if syncErr == nil && len(event.Revisions) > 0 {
+		event.Run = run
+		for _, l := range s.listeners {
+			l(event)
+		}
+	}
+	return run, syncErr
+}
+
+// sync diffs the feed against the stored rates so that new publications,
+// corrections of already stored dates and holes are all picked up. Only new or
+// changed values are written, each as a new revision. Non-zero start and end
+// restrict the diff to that range.
+func (s *syncer) sync(run *model.SyncRun, fetch func(time.Time) (*ecb.Feed, error), start, end time.Time) (Event, error) {
+	feed, err := fetch(time.Time{})
+	if feed != nil {
+		run.Endpoint = feed.Endpoint
+		run.HTTPStatus = feed.StatusCode
+		run.FeedHash = feed.Hash
+	}
+	if err != nil {
+		return Event{}, err
+	}
+	fetchedAt := time.Now().UTC()
+
+	var first, last time.Time
+	dates := make(map[string]struct{})
+	rates := make([]ecb.Rate, 0, len(feed.Rates))
+	for _, rate := range feed.Rates {
+		if (!start.IsZero() && rate.Time.Before(start)) || (!end.IsZero() && rate.Time.After(end)) {
+			continue
+		}
+		rates = append(rates, rate)
+		dates[rate.Time.Format("2006-01-02")] = struct{}{}
+		if first.IsZero() || rate.Time.Before(first) {
+			first = rate.Time
+		}
+		if rate.Time.After(last) {
+			last = rate.Time
+		}
+	}
+	run.DatesFetched = len(dates)
+	if len(dates) == 0 {
+		return Event{}, nil
+	}
+	run.FirstDate = first.Format("2006-01-02")
+	run.LastDate = last.Format("2006-01-02")
+
+	stored, err := s.rates.GetRatesBetween(first, last)
+	if err != nil {
+		return Event{}, err
+	}
+	latest, err := s.rates.GetLatestDate()
+	if err != nil {
+		return Event{}, err
+	}
+	current := make(map[string]float64, len(stored))
+	storedDates := make(map[string]struct{})
+	for _, rate := range stored {
+		current[rate.Time+rate.Currency] = rate.Rate
+		storedDates[rate.Time] = struct{}{}
+	}
+
+	var inserted, revised int
+	revisions := make([]model.RateRevision, 0)
+	for _, rate := range rates {
+		d := rate.Time.Format("2006-01-02")
+		v, ok := current[d+rate.Currency]
+		if ok && math.Abs(v-rate.Rate) < epsilon {
+			continue
+		}
+		if ok {
+			revised++
+		} else {
+			inserted++
+		}
+		current[d+rate.Currency] = rate.Rate
+		revisions = append(revisions, model.RateRevision{
+			Time:      d,
+			Currency:  rate.Currency,
+			Rate:      rate.Rate,
+			Source:    ecb.Provider,
+			FeedHash:  feed.Hash,
+			FetchedAt: fetchedAt,
+		})
+	}
+	if len(revisions) == 0 {
+		return Event{}, nil
+	}
+	event := Event{Run: *run, Revisions: revisions}
+	for _, r := range revisions {
+		if _, ok := storedDates[r.Time]; ok {
+			continue
+		}
+		storedDates[r.Time] = struct{}{}
+		if latest.IsZero() || r.Time > latest.Format("2006-01-02") {
+			event.NewDates = append(event.NewDates, r.Time)
+		} else {
+			event.BackfilledDates = append(event.BackfilledDates, r.Time)
+		}
+	}
+	sort.Strings(event.NewDates)
+	sort.Strings(event.BackfilledDates)
+	var deliveries []model.WebhookDelivery
+	if s.outbox != nil {
+		if deliveries, err = s.outbox(event); err != nil {
+			return Event{}, err
+		}
+	}
+	if err := s.rates.SaveRevisions(revisions, deliveries); err != nil {
+		return Event{}, err
+	}
+	run.RowsInserted = inserted
+	run.RowsRevised = revised
+	return event, nil
+}
@@ -0,0 +1,349 @@
+package syncer
+
+import (
+	"errors"
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"github.com/huyhvq/eurofxref/pkg/period"
+	"github.com/huyhvq/eurofxref/pkg/service/ecb"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+var (
+	fetchErr         = errors.New("fetch error")
+	getRatesErr      = errors.New("get rates between error")
+	saveRevisionsErr = errors.New("save revisions error")
+	insertRunErr     = errors.New("insert run")
+	d1, _            = time.ParseInLocation("2006-01-02", "2021-03-04", time.UTC)
+	d2, _            = time.ParseInLocation("2006-01-02", "2021-03-05", time.UTC)
+)
+
+type mockSrv struct {
+	rates   []ecb.Rate
+	history []ecb.Rate
+	err     error
+}
+
+func (m mockSrv) FetchRatesAfterDate(date time.Time) ([]ecb.Rate, error) {
+	panic("implement me")
+}
+
+func (m mockSrv) Fetch(date time.Time) (*ecb.Feed, error) {
+	feed := &ecb.Feed{Endpoint: "mock", StatusCode: 200, Hash: "hash"}
+	if m.err != nil {
+		feed.StatusCode = 500
+		return feed, m.err
+	}
+	feed.Rates = m.rates
+	return feed, nil
+}
+
+type mockRepo struct {
+	stored     []model.Rate
+	latest     string
+	counts     []model.DateCount
+	countsErr  error
+	betweenErr error
+	saveErr    error
+	saved      []model.RateRevision
+	queued     []model.WebhookDelivery
+}
+
+func (m *mockRepo) GetLatestDate() (time.Time, error) {
+	latest := m.latest
+	for _, r := range m.stored {
+		if r.Time > latest {
+			latest = r.Time
+		}
+	}
+	if latest == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse("2006-01-02", latest)
+}
+
+func (m *mockRepo) GetDateOnOrBefore(date time.Time) (time.Time, error) {
+	panic("implement me")
+}
+
+func (m *mockRepo) GetLatestRates() ([]model.Rate, error) {
+	panic("implement me")
+}
+
+func (m *mockRepo) GetRatesAnalyze() ([]model.RateAnalyze, error) {
+	panic("implement me")
+}
+
+func (m *mockRepo) GetRatesByPeriod(g period.Granularity, start, end time.Time) ([]model.RatePeriod, error) {
+	panic("implement me")
+}
+
+func (m *mockRepo) GetRatesByDate(date time.Time) ([]model.Rate, error) {
+	panic("implement me")
+}
+
+func (m *mockRepo) GetRatesByDateAsOf(date, knownAt time.Time) ([]model.Rate, error) {
+	panic("implement me")
+}
+
+func (m *mockRepo) GetRatesBetween(start, end time.Time) ([]model.Rate, error) {
+	return m.stored, m.betweenErr
+}
+
+func (m *mockRepo) SaveRevisions(revisions []model.RateRevision, deliveries []model.WebhookDelivery) error {
+	if m.saveErr != nil {
+		return m.saveErr
+	}
+	m.saved = append(m.saved, revisions...)
+	m.queued = append(m.queued, deliveries...)
+	return nil
+}
+
+func (m mockSrv) FetchHistory(date time.Time) (*ecb.Feed, error) {
+	return &ecb.Feed{Endpoint: "history", StatusCode: 200, Hash: "history-hash", Rates: m.history}, nil
+}
+
+func (m *mockRepo) GetDateCounts(start, end time.Time) ([]model.DateCount, error) {
+	return m.counts, m.countsErr
+}
+
+func (m *mockRepo) GetCurrencyRanges() ([]model.CurrencyRange, error) {
+	panic("implement me")
+}
+
+func (m *mockRepo) GetChanges(afterSeq int64, limit int) ([]model.RateChange, error) {
+	panic("implement me")
+}
+
+func (m *mockRepo) RebuildAggregates() error {
+	panic("implement me")
+}
+
+func (m *mockRepo) FillAggregates() error {
+	panic("implement me")
+}
+
+type mockRunRepo struct {
+	insertErr error
+	finished  []model.SyncRun
+}
+
+func (m *mockRunRepo) Insert(run model.SyncRun) (int64, error) {
+	if m.insertErr != nil {
+		return 0, m.insertErr
+	}
+	return 7, nil
+}
+
+func (m *mockRunRepo) Finish(run model.SyncRun) error {
+	m.finished = append(m.finished, run)
+	return nil
+}
+
+func (m *mockRunRepo) GetRuns(limit int) ([]model.SyncRun, error) {
+	panic("implement me")
+}
+
+func (m *mockRunRepo) GetRunByID(id int64) (model.SyncRun, error) {
+	panic("implement me")
+}
+
+var feedRates = []ecb.Rate{
+	{Time: d1, Currency: "USD", Rate: 1.1987},
+	{Time: d1, Currency: "JPY", Rate: 129.91},
+	{Time: d2, Currency: "USD", Rate: 1.1915},
+}
+
+func TestNew(t *testing.T) {
+	s := New(&mockRepo{}, &mockRunRepo{}, mockSrv{}, nil)
+	assert.NotNil(t, s)
+}
+
+func TestSyncer_Sync(t *testing.T) {
+	t.Run("Sync inserts new and revises changed rates", func(t *testing.T) {
+		repo := &mockRepo{stored: []model.Rate{
+			{Time: "2021-03-04", Currency: "USD", Rate: 1.1987},
+			{Time: "2021-03-04", Currency: "JPY", Rate: 129.9},
+		}}
+		runs := &mockRunRepo{}
+		run, err := New(repo, runs, mockSrv{rates: feedRates}, nil).Sync(TriggerManual)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(7), run.ID)
+		assert.Equal(t, model.SyncSuccess, run.Status)
+		assert.Equal(t, TriggerManual, run.TriggeredBy)
+		assert.Equal(t, ecb.Provider, run.Provider)
+		assert.Equal(t, "mock", run.Endpoint)
+		assert.Equal(t, 200, run.HTTPStatus)
+		assert.Equal(t, "hash", run.FeedHash)
+		assert.Equal(t, 2, run.DatesFetched)
+		assert.Equal(t, "2021-03-04", run.FirstDate)
+		assert.Equal(t, "2021-03-05", run.LastDate)
+		assert.Equal(t, 1, run.RowsInserted)
+		assert.Equal(t, 1, run.RowsRevised)
+		assert.False(t, run.FinishedAt.IsZero())
+		assert.Equal(t, []model.SyncRun{run}, runs.finished)
+
+		assert.Equal(t, 2, len(repo.saved))
+		assert.Equal(t, "JPY", repo.saved[0].Currency)
+		assert.Equal(t, 129.91, repo.saved[0].Rate)
+		assert.Equal(t, "2021-03-05", repo.saved[1].Time)
+		assert.Equal(t, ecb.Provider, repo.saved[1].Source)
+		assert.Equal(t, "hash", repo.saved[1].FeedHash)
+		assert.False(t, repo.saved[1].FetchedAt.IsZero())
+	})
+	t.Run("Sync notifies listeners of changed rates", func(t *testing.T) {
+		var events []Event
+		listener := func(e Event) { events = append(events, e) }
+		repo := &mockRepo{}
+		run, err := New(repo, &mockRunRepo{}, mockSrv{rates: feedRates}, nil, listener).Sync(TriggerManual)
+		assert.Nil(t, err)
+		assert.Equal(t, []Event{{Run: run, Revisions: repo.saved, NewDates: []string{"2021-03-04", "2021-03-05"}}}, events)
+
+		repo = &mockRepo{stored: []model.Rate{
+			{Time: "2021-03-04", Currency: "USD", Rate: 1.1987},
+			{Time: "2021-03-04", Currency: "JPY", Rate: 129.91},
+			{Time: "2021-03-05", Currency: "USD", Rate: 1.1915},
+		}}
+		_, err = New(repo, &mockRunRepo{}, mockSrv{rates: feedRates}, nil, listener).Sync(TriggerManual)
+		assert.Nil(t, err)
+		_, err = New(&mockRepo{saveErr: saveRevisionsErr}, &mockRunRepo{}, mockSrv{rates: feedRates}, nil, listener).Sync(TriggerManual)
+		assert.Equal(t, saveRevisionsErr, err)
+		assert.Len(t, events, 1)
+
+		repo = &mockRepo{stored: []model.Rate{{Time: "2021-03-04", Currency: "USD", Rate: 1.1987}}}
+		_, err = New(repo, &mockRunRepo{}, mockSrv{rates: feedRates}, nil, listener).Sync(TriggerManual)
+		assert.Nil(t, err)
+		assert.Len(t, events, 2)
+		assert.Equal(t, []string{"2021-03-05"}, events[1].NewDates)
+		assert.Nil(t, events[1].BackfilledDates)
+	})
+	t.Run("Sync reports dates before the latest stored one as backfilled", func(t *testing.T) {
+		var events []Event
+		listener := func(e Event) { events = append(events, e) }
+		repo := &mockRepo{latest: "2021-03-08"}
+		_, err := New(repo, &mockRunRepo{}, mockSrv{rates: feedRates}, nil, listener).Sync(TriggerRepair)
+		assert.Nil(t, err)
+		assert.Len(t, events, 1)
+		assert.Nil(t, events[0].NewDates)
+		assert.Equal(t, []string{"2021-03-04", "2021-03-05"}, events[0].BackfilledDates)
+
+		events = nil
+		repo = &mockRepo{latest: "2021-03-04"}
+		_, err = New(repo, &mockRunRepo{}, mockSrv{rates: feedRates}, nil, listener).Sync(TriggerManual)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"2021-03-05"}, events[0].NewDates)
+		assert.Equal(t, []string{"2021-03-04"}, events[0].BackfilledDates)
+	})
+	t.Run("Sync stores the outbox with the revisions", func(t *testing.T) {
+		var seen Event
+		outbox := func(e Event) ([]model.WebhookDelivery, error) {
+			seen = e
+			return []model.WebhookDelivery{{WebhookID: 1, SyncRunID: e.Run.ID}}, nil
+		}
+		repo := &mockRepo{}
+		run, err := New(repo, &mockRunRepo{}, mockSrv{rates: feedRates}, outbox).Sync(TriggerManual)
+		assert.Nil(t, err)
+		assert.Equal(t, run.ID, seen.Run.ID)
+		assert.Equal(t, model.SyncRunning, seen.Run.Status)
+		assert.Equal(t, repo.saved, seen.Revisions)
+		assert.Equal(t, []string{"2021-03-04", "2021-03-05"}, seen.NewDates)
+		assert.Equal(t, []model.WebhookDelivery{{WebhookID: 1, SyncRunID: run.ID}}, repo.queued)
+
+		outboxErr := errors.New("outbox error")
+		repo = &mockRepo{}
+		run, err = New(repo, &mockRunRepo{}, mockSrv{rates: feedRates}, func(Event) ([]model.WebhookDelivery, error) {
+			return nil, outboxErr
+		}).Sync(TriggerManual)
+		assert.Equal(t, outboxErr, err)
+		assert.Equal(t, model.SyncFailed, run.Status)
+		assert.Empty(t, repo.saved)
+	})
+	t.Run("Sync skips unchanged feed", func(t *testing.T) {
+		repo := &mockRepo{stored: []model.Rate{
+			{Time: "2021-03-04", Currency: "USD", Rate: 1.1987},
+			{Time: "2021-03-04", Currency: "JPY", Rate: 129.91},
+			{Time: "2021-03-05", Currency: "USD", Rate: 1.1915},
+		}}
+		run, err := New(repo, &mockRunRepo{}, mockSrv{rates: feedRates}, nil).Sync(TriggerStartup)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, run.RowsInserted)
+		assert.Equal(t, 0, run.RowsRevised)
+		assert.Nil(t, repo.saved)
+	})
+	t.Run("Sync with empty feed", func(t *testing.T) {
+		run, err := New(&mockRepo{}, &mockRunRepo{}, mockSrv{}, nil).Sync(TriggerStartup)
+		assert.Nil(t, err)
+		assert.Equal(t, model.SyncSuccess, run.Status)
+		assert.Equal(t, 0, run.DatesFetched)
+	})
+	t.Run("Sync failed on Fetch", func(t *testing.T) {
+		runs := &mockRunRepo{}
+		run, err := New(&mockRepo{}, runs, mockSrv{err: fetchErr}, nil).Sync(TriggerStartup)
+		assert.Equal(t, fetchErr, err)
+		assert.Equal(t, model.SyncFailed, run.Status)
+		assert.Equal(t, fetchErr.Error(), run.Error)
+		assert.Equal(t, 500, run.HTTPStatus)
+		assert.Equal(t, 1, len(runs.finished))
+	})
+	t.Run("Sync failed on GetRatesBetween", func(t *testing.T) {
+		run, err := New(&mockRepo{betweenErr: getRatesErr}, &mockRunRepo{}, mockSrv{rates: feedRates}, nil).Sync(TriggerStartup)
+		assert.Equal(t, getRatesErr, err)
+		assert.Equal(t, model.SyncFailed, run.Status)
+	})
+	t.Run("Sync failed on SaveRevisions", func(t *testing.T) {
+		run, err := New(&mockRepo{saveErr: saveRevisionsErr}, &mockRunRepo{}, mockSrv{rates: feedRates}, nil).Sync(TriggerStartup)
+		assert.Equal(t, saveRevisionsErr, err)
+		assert.Equal(t, 0, run.RowsInserted)
+	})
+	t.Run("Sync failed on recording run", func(t *testing.T) {
+		run, err := New(&mockRepo{}, &mockRunRepo{insertErr: insertRunErr}, mockSrv{}, nil).Sync(TriggerStartup)
+		assert.Equal(t, insertRunErr, err)
+		assert.Equal(t, int64(0), run.ID)
+	})
+}
+
+func TestSyncer_Repair(t *testing.T) {
+	history := append([]ecb.Rate{
+		{Time: d1.AddDate(0, 0, -1), Currency: "USD", Rate: 1.2},
+		{Time: d1.AddDate(0, 0, -1), Currency: "JPY", Rate: 130},
+	}, feedRates...)
+	repo := &mockRepo{stored: []model.Rate{
+		{Time: "2021-03-04", Currency: "USD", Rate: 1.1987},
+	}}
+	run, err := New(repo, &mockRunRepo{}, mockSrv{history: history}, nil).Repair(TriggerCLI, d1, d1)
+	assert.Nil(t, err)
+	assert.Equal(t, model.SyncSuccess, run.Status)
+	assert.Equal(t, "history", run.Endpoint)
+	assert.Equal(t, 1, run.DatesFetched)
+	assert.Equal(t, "2021-03-04", run.FirstDate)
+	assert.Equal(t, "2021-03-04", run.LastDate)
+	assert.Equal(t, 1, run.RowsInserted)
+	assert.Equal(t, []model.RateRevision{{
+		Time:      "2021-03-04",
+		Currency:  "JPY",
+		Rate:      129.91,
+		Source:    ecb.Provider,
+		FeedHash:  "history-hash",
+		FetchedAt: repo.saved[0].FetchedAt,
+	}}, repo.saved)
+}
+
+func TestSyncer_Check(t *testing.T) {
+	repo := &mockRepo{counts: []model.DateCount{
+		{Time: "2021-03-04", Count: 32},
+		{Time: "2021-03-08", Count: 32},
+	}}
+	report, err := New(repo, &mockRunRepo{}, mockSrv{}, nil).Check(time.Time{}, time.Time{})
+	assert.Nil(t, err)
+	assert.Equal(t, "2021-03-04", report.Start)
+	assert.Equal(t, "2021-03-08", report.End)
+	assert.Equal(t, []string{"2021-03-05"}, report.MissingDates)
+
+	report, err = New(&mockRepo{}, &mockRunRepo{}, mockSrv{}, nil).Check(d1, d2)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"2021-03-04", "2021-03-05"}, report.MissingDates)
+
+	_, err = New(&mockRepo{countsErr: getRatesErr}, &mockRunRepo{}, mockSrv{}, nil).Check(d1, d2)
+	assert.Equal(t, getRatesErr, err)
+}
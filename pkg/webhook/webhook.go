@@ -0,0 +1,246 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/huyhvq/eurofxref/pkg/fx"
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"github.com/huyhvq/eurofxref/pkg/repository"
+	"github.com/huyhvq/eurofxref/pkg/syncer"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	EventRatesUpdated = "rates.updated"
+
+	EventHeader     = "X-Eurofxref-Event"
+	DeliveryHeader  = "X-Eurofxref-Delivery"
+	SignatureHeader = "X-Eurofxref-Signature"
+
+	// MaxAttempts is the number of attempts after which a delivery fails.
+	MaxAttempts = 8
+
+	batchSize   = 100
+	maxErrorLen = 1024
+
+	// claimLease is how long claimed deliveries stay hidden from other
+	// dispatchers; it outlasts a batch posted with the default client.
+	claimLease = 30 * time.Minute
+)
+
+// Payload is the body posted to a webhook. Rates holds the new and revised
+// rates of the sync by date and currency.
+type Payload struct {
+	Event       string                        `json:"event"`
+	SyncRunID   int64                         `json:"sync_run_id"`
+	TriggeredBy string                        `json:"triggered_by"`
+	Base        string                        `json:"base"`
+	Rates       map[string]map[string]float64 `json:"rates"`
+	CreatedAt   time.Time                     `json:"created_at"`
+}
+
+// Sign returns the signature header value of body: the hex HMAC-SHA256 of
+// body keyed with secret, prefixed with "sha256=".
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewPayload returns the payload of e restricted to currencies, all when
+// empty. ok is false when none of them changed.
+func NewPayload(e syncer.Event, currencies []string, at time.Time) (Payload, bool) {
+	filter := make(map[string]struct{}, len(currencies))
+	for _, c := range currencies {
+		filter[c] = struct{}{}
+	}
+	p := Payload{
+		Event:       EventRatesUpdated,
+		SyncRunID:   e.Run.ID,
+		TriggeredBy: e.Run.TriggeredBy,
+		Base:        fx.Base,
+		Rates:       make(map[string]map[string]float64),
+		CreatedAt:   at,
+	}
+	for _, r := range e.Revisions {
+		if _, ok := filter[r.Currency]; len(filter) > 0 && !ok {
+			continue
+		}
+		if p.Rates[r.Time] == nil {
+			p.Rates[r.Time] = make(map[string]float64)
+		}
+		p.Rates[r.Time][r.Currency] = r.Rate
+	}
+	return p, len(p.Rates) > 0
+}
+
+// Outbox returns the sync outbox that queues a delivery of the changed rates
+// to every webhook interested in them, stored with the rates themselves.
+// Deliveries are sent by a Dispatcher.
+func Outbox(repo repository.WebhookRepository) syncer.Outbox {
+	return func(e syncer.Event) ([]model.WebhookDelivery, error) {
+		webhooks, err := repo.GetAll()
+		if err != nil {
+			return nil, err
+		}
+		now := time.Now().UTC()
+		deliveries := make([]model.WebhookDelivery, 0, len(webhooks))
+		for _, w := range webhooks {
+			p, ok := NewPayload(e, w.Currencies, now)
+			if !ok {
+				continue
+			}
+			body, err := json.Marshal(p)
+			if err != nil {
+				return nil, err
+			}
+			deliveries = append(deliveries, model.WebhookDelivery{
+				WebhookID:     w.ID,
+				Event:         p.Event,
+				SyncRunID:     e.Run.ID,
+				Payload:       string(body),
+				Status:        model.DeliveryPending,
+				NextAttemptAt: now,
+				CreatedAt:     now,
+			})
+		}
+		return deliveries, nil
+	}
+}
+
+// Backoff is the delay before the attempt following the given number of
+// failed attempts: 30s doubling up to 6h.
+func Backoff(attempts int) time.Duration {
+	d := 30 * time.Second
+	for i := 1; i < attempts && d < 6*time.Hour; i++ {
+		d *= 2
+	}
+	if d > 6*time.Hour {
+		d = 6 * time.Hour
+	}
+	return d
+}
+
+// Dispatcher posts the queued deliveries that are due.
+type Dispatcher struct {
+	repo   repository.WebhookRepository
+	client *http.Client
+	now    func() time.Time
+}
+
+func NewDispatcher(repo repository.WebhookRepository, client *http.Client) *Dispatcher {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Dispatcher{repo: repo, client: client, now: func() time.Time { return time.Now().UTC() }}
+}
+
+// Run dispatches due deliveries every interval until stop is closed.
+func (d *Dispatcher) Run(interval time.Duration, stop <-chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		if err := d.Dispatch(); err != nil {
+			log.Println("webhook: dispatch failed:", err)
+		}
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// Dispatch sends every delivery due now, one batch at a time, and records
+// the outcome of each attempt. Every batch is claimed first, so dispatchers
+// of several instances never send the same delivery concurrently.
+func (d *Dispatcher) Dispatch() error {
+	for {
+		claim, err := newClaim()
+		if err != nil {
+			return err
+		}
+		now := d.now()
+		due, err := d.repo.ClaimDueDeliveries(claim, now, now.Add(claimLease), batchSize)
+		if err != nil || len(due) == 0 {
+			return err
+		}
+		webhooks, err := d.repo.GetAll()
+		if err != nil {
+			return err
+		}
+		byID := make(map[int64]model.Webhook, len(webhooks))
+		for _, w := range webhooks {
+			byID[w.ID] = w
+		}
+		for _, dl := range due {
+			w, ok := byID[dl.WebhookID]
+			if ok {
+				dl.LastStatusCode, err = d.post(w, dl)
+			} else {
+				dl.LastStatusCode, err = 0, fmt.Errorf("webhook deleted")
+			}
+			dl.Attempts++
+			dl.LastError = ""
+			switch {
+			case err == nil:
+				dl.Status = model.DeliveryDelivered
+				dl.DeliveredAt = d.now()
+			case !ok || dl.Attempts >= MaxAttempts:
+				dl.Status = model.DeliveryFailed
+			default:
+				dl.NextAttemptAt = d.now().Add(Backoff(dl.Attempts))
+			}
+			if err != nil {
+				dl.LastError = err.Error()
+				if len(dl.LastError) > maxErrorLen {
+					dl.LastError = dl.LastError[:maxErrorLen]
+				}
+			}
+			if err := d.repo.UpdateDelivery(dl); err != nil {
+				return err
+			}
+		}
+		if len(due) < batchSize {
+			return nil
+		}
+	}
+}
+
+// newClaim returns 32 random hex characters.
+func newClaim() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// post sends dl to w. Any response other than 2xx is an error.
+func (d *Dispatcher) post(w model.Webhook, dl model.WebhookDelivery) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewBufferString(dl.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, dl.Event)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(dl.ID, 10))
+	req.Header.Set(SignatureHeader, Sign(w.Secret, []byte(dl.Payload)))
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,157 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/huyhvq/eurofxref/pkg/model"
+	"github.com/huyhvq/eurofxref/pkg/syncer"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type mockRepo struct {
+	webhooks []model.Webhook
+	due      []model.WebhookDelivery
+	claims   []string
+	until    time.Time
+	updated  []model.WebhookDelivery
+	err      error
+}
+
+func (m *mockRepo) Insert(w model.Webhook) (int64, error)   { return 0, nil }
+func (m *mockRepo) GetAll() ([]model.Webhook, error)        { return m.webhooks, m.err }
+func (m *mockRepo) GetByID(id int64) (model.Webhook, error) { return model.Webhook{}, nil }
+func (m *mockRepo) Delete(id int64) error                   { return nil }
+func (m *mockRepo) UpdateDelivery(d model.WebhookDelivery) error {
+	m.updated = append(m.updated, d)
+	return nil
+}
+func (m *mockRepo) GetDeliveries(int64, int) ([]model.WebhookDelivery, error) { return nil, nil }
+
+func (m *mockRepo) ClaimDueDeliveries(claim string, at, until time.Time, limit int) ([]model.WebhookDelivery, error) {
+	m.claims, m.until = append(m.claims, claim), until
+	due := m.due
+	m.due = nil
+	return due, nil
+}
+
+var event = syncer.Event{
+	Run: model.SyncRun{ID: 9, TriggeredBy: syncer.TriggerManual},
+	Revisions: []model.RateRevision{
+		{Time: "2021-03-30", Currency: "USD", Rate: 1.1763},
+		{Time: "2021-03-30", Currency: "GBP", Rate: 0.8551},
+	},
+}
+
+func TestSign(t *testing.T) {
+	assert.Equal(t, "sha256=f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8",
+		Sign("key", []byte("The quick brown fox jumps over the lazy dog")))
+}
+
+func TestNewPayload(t *testing.T) {
+	now := time.Date(2021, 3, 30, 16, 0, 0, 0, time.UTC)
+	p, ok := NewPayload(event, nil, now)
+	assert.True(t, ok)
+	assert.Equal(t, Payload{
+		Event:       EventRatesUpdated,
+		SyncRunID:   9,
+		TriggeredBy: syncer.TriggerManual,
+		Base:        "EUR",
+		Rates:       map[string]map[string]float64{"2021-03-30": {"USD": 1.1763, "GBP": 0.8551}},
+		CreatedAt:   now,
+	}, p)
+
+	p, ok = NewPayload(event, []string{"GBP"}, now)
+	assert.True(t, ok)
+	assert.Equal(t, map[string]map[string]float64{"2021-03-30": {"GBP": 0.8551}}, p.Rates)
+
+	_, ok = NewPayload(event, []string{"JPY"}, now)
+	assert.False(t, ok)
+}
+
+func TestOutbox(t *testing.T) {
+	repo := &mockRepo{webhooks: []model.Webhook{
+		{ID: 1},
+		{ID: 2, Currencies: []string{"JPY"}},
+		{ID: 3, Currencies: []string{"USD"}},
+	}}
+	queued, err := Outbox(repo)(event)
+	assert.Nil(t, err)
+	assert.Len(t, queued, 2)
+	assert.Equal(t, int64(1), queued[0].WebhookID)
+	assert.Equal(t, int64(3), queued[1].WebhookID)
+	assert.Equal(t, int64(9), queued[1].SyncRunID)
+	assert.Equal(t, model.DeliveryPending, queued[1].Status)
+
+	var p Payload
+	assert.Nil(t, json.Unmarshal([]byte(queued[1].Payload), &p))
+	assert.Equal(t, map[string]map[string]float64{"2021-03-30": {"USD": 1.1763}}, p.Rates)
+
+	repo.err = errors.New("db down")
+	_, err = Outbox(repo)(event)
+	assert.Equal(t, repo.err, err)
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 30*time.Second, Backoff(1))
+	assert.Equal(t, time.Minute, Backoff(2))
+	assert.Equal(t, 8*time.Minute, Backoff(5))
+	assert.Equal(t, 6*time.Hour, Backoff(20))
+}
+
+func TestDispatcher_Dispatch(t *testing.T) {
+	var (
+		body   string
+		header http.Header
+	)
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		body, header = string(b), r.Header
+	}))
+	defer ok.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	now := time.Date(2021, 3, 30, 16, 0, 0, 0, time.UTC)
+	repo := &mockRepo{
+		webhooks: []model.Webhook{{ID: 1, URL: ok.URL, Secret: "s"}, {ID: 2, URL: failing.URL}},
+		due: []model.WebhookDelivery{
+			{ID: 10, WebhookID: 1, Event: EventRatesUpdated, Payload: `{"a":1}`, Status: model.DeliveryPending},
+			{ID: 11, WebhookID: 2, Payload: "{}", Status: model.DeliveryPending, Attempts: 1},
+			{ID: 12, WebhookID: 2, Payload: "{}", Status: model.DeliveryPending, Attempts: MaxAttempts - 1},
+			{ID: 13, WebhookID: 3, Payload: "{}", Status: model.DeliveryPending},
+		},
+	}
+	d := NewDispatcher(repo, nil)
+	d.now = func() time.Time { return now }
+	assert.Nil(t, d.Dispatch())
+	assert.Len(t, repo.claims, 1)
+	assert.Len(t, repo.claims[0], 32)
+	assert.Equal(t, now.Add(claimLease), repo.until)
+
+	assert.Equal(t, `{"a":1}`, body)
+	assert.Equal(t, Sign("s", []byte(`{"a":1}`)), header.Get(SignatureHeader))
+	assert.Equal(t, "10", header.Get(DeliveryHeader))
+	assert.Equal(t, EventRatesUpdated, header.Get(EventHeader))
+
+	assert.Len(t, repo.updated, 4)
+	assert.Equal(t, model.DeliveryDelivered, repo.updated[0].Status)
+	assert.Equal(t, now, repo.updated[0].DeliveredAt)
+	assert.Equal(t, 200, repo.updated[0].LastStatusCode)
+
+	assert.Equal(t, model.DeliveryPending, repo.updated[1].Status)
+	assert.Equal(t, 2, repo.updated[1].Attempts)
+	assert.Equal(t, now.Add(time.Minute), repo.updated[1].NextAttemptAt)
+	assert.Equal(t, 503, repo.updated[1].LastStatusCode)
+	assert.Equal(t, "unexpected status 503 Service Unavailable", repo.updated[1].LastError)
+
+	assert.Equal(t, model.DeliveryFailed, repo.updated[2].Status)
+	assert.Equal(t, model.DeliveryFailed, repo.updated[3].Status)
+	assert.Equal(t, "webhook deleted", repo.updated[3].LastError)
+}